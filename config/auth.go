@@ -0,0 +1,43 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+type AuthConfig struct {
+	JWTSecret       []byte
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Used only to create the first staff account on an empty database.
+	AdminUsername string
+	AdminPassword string
+}
+
+func LoadAuthConfig() AuthConfig {
+	secret := os.Getenv("JWT_SECRET")
+	if len(secret) < 32 {
+		log.Fatal("⚠️ JWT_SECRET must be set to at least 32 characters")
+	}
+	return AuthConfig{
+		JWTSecret:       []byte(secret),
+		AccessTokenTTL:  durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: durationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		AdminUsername:   os.Getenv("ADMIN_USERNAME"),
+		AdminPassword:   os.Getenv("ADMIN_PASSWORD"),
+	}
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("⚠️ Invalid duration for %s: %v", key, err)
+	}
+	return d
+}
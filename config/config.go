@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -21,8 +22,11 @@ func ConnectDB() {
 	// 		Colorful:                  true,        // Enable color
 	// 	},
 	// )
+	// Times are stored in UTC; each property converts them to its own timezone.
+	// Databases written before this used loc=Local and are converted once on
+	// startup, see LegacyTimezone.
 	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASS"),
 		os.Getenv("DB_HOST"),
@@ -38,3 +42,20 @@ func ConnectDB() {
 	DB = db
 	fmt.Println("✅ Connected to MySQL database")
 }
+
+// LegacyTimezone is the zone of the times in a database written while the
+// connection used loc=Local, which is the application server's zone. It is
+// DB_LEGACY_TIMEZONE, a MySQL time zone name or an offset such as +02:00, or
+// +00:00 on a server running in UTC, and empty when neither is known.
+func LegacyTimezone() string {
+	if zone := os.Getenv("DB_LEGACY_TIMEZONE"); zone != "" {
+		return zone
+	}
+	year := time.Now().Year()
+	for _, month := range []time.Month{time.January, time.July} {
+		if _, offset := time.Date(year, month, 1, 0, 0, 0, 0, time.Local).Zone(); offset != 0 {
+			return ""
+		}
+	}
+	return "+00:00"
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"encoding/base64"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type EncryptionConfig struct {
+	// Keys holds every AES-256 key that may still be needed to decrypt stored
+	// values, by key id. New values are always written with ActiveKeyID.
+	Keys        map[uint32][]byte
+	ActiveKeyID uint32
+
+	// BlindIndexKey is the HMAC key used for searchable hashes. Changing it
+	// invalidates every stored index.
+	BlindIndexKey []byte
+}
+
+// LoadEncryptionConfig reads PII_ENCRYPTION_KEYS ("1:<base64>,2:<base64>"),
+// PII_ACTIVE_KEY_ID and PII_BLIND_INDEX_KEY (base64).
+func LoadEncryptionConfig() EncryptionConfig {
+	keys := make(map[uint32][]byte)
+	for _, entry := range strings.Split(os.Getenv("PII_ENCRYPTION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			log.Fatalf("⚠️ Invalid PII_ENCRYPTION_KEYS entry %q, expected <id>:<base64 key>", entry)
+		}
+		keyID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			log.Fatalf("⚠️ Invalid key id in PII_ENCRYPTION_KEYS: %v", err)
+		}
+		keys[uint32(keyID)] = decodeKeyEnv("PII_ENCRYPTION_KEYS", encoded)
+	}
+	activeID, err := strconv.ParseUint(os.Getenv("PII_ACTIVE_KEY_ID"), 10, 32)
+	if err != nil {
+		log.Fatal("⚠️ PII_ACTIVE_KEY_ID must be set to one of the PII_ENCRYPTION_KEYS ids")
+	}
+	return EncryptionConfig{
+		Keys:          keys,
+		ActiveKeyID:   uint32(activeID),
+		BlindIndexKey: decodeKeyEnv("PII_BLIND_INDEX_KEY", os.Getenv("PII_BLIND_INDEX_KEY")),
+	}
+}
+
+func decodeKeyEnv(name, value string) []byte {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(key) != 32 {
+		log.Fatalf("⚠️ %s must contain base64-encoded 32-byte keys", name)
+	}
+	return key
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"log"
+	"os"
+)
+
+// PaymentConfig selects the card payment gateway.
+type PaymentConfig struct {
+	// Provider names the gateway; only "fake", the in-process gateway for
+	// development and tests, is built in.
+	Provider string
+
+	// WebhookSecret signs the asynchronous payment results the gateway posts back.
+	WebhookSecret []byte
+}
+
+func LoadPaymentConfig() PaymentConfig {
+	provider := os.Getenv("PAYMENT_PROVIDER")
+	if provider == "" {
+		provider = "fake"
+	}
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if len(secret) < 16 {
+		log.Fatal("⚠️ PAYMENT_WEBHOOK_SECRET must be set to at least 16 characters")
+	}
+	return PaymentConfig{Provider: provider, WebhookSecret: []byte(secret)}
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditServices services.AuditServices
+}
+
+func NewAuditHandler(s services.AuditServices) *AuditHandler {
+	return &AuditHandler{auditServices: s}
+}
+
+// GetAuditTrail lists the history of one entity, e.g. ?entity_type=booking&entity_id=01H...
+func (h *AuditHandler) GetAuditTrail(c *gin.Context) {
+	entityType := c.Query("entity_type")
+	entityID := c.Query("entity_id")
+	if entityType == "" || entityID == "" {
+		c.JSON(http.StatusBadRequest, response.Response{"400", "entity_type and entity_id are required", nil})
+		return
+	}
+	res, err := h.auditServices.ListForEntity(entityType, entityID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"hms-backend/middleware"
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	authServices services.AuthServices
+}
+
+func NewAuthHandler(s services.AuthServices) *AuthHandler {
+	return &AuthHandler{authServices: s}
+}
+
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req request.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.authServices.Login(&req)
+	if errors.Is(err, services.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, response.Response{"401", err.Error(), nil})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req request.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.authServices.Refresh(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, response.Response{"401", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	principal := middleware.CurrentPrincipal(c)
+	if principal == nil || principal.Session == nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", "Only staff sessions can log out", nil})
+		return
+	}
+	if err := h.authServices.Logout(principal.Session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful logout", nil})
+}
+
+func (h *AuthHandler) CreateStaff(c *gin.Context) {
+	var req request.CreateStaffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.authServices.CreateStaff(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Successful", res})
+}
+
+func (h *AuthHandler) ChangeStaffRole(c *gin.Context) {
+	var req request.ChangeStaffRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.authServices.ChangeStaffRole(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	var req request.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.authServices.CreateAPIKey(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Successful, store the key now, it will not be shown again", res})
+}
+
+func (h *AuthHandler) GetAllAPIKeys(c *gin.Context) {
+	res, err := h.authServices.ListAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	var req request.RevokeAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	if err := h.authServices.RevokeAPIKey(req.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful revoke api key", nil})
+}
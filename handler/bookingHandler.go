@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
+	"hms-backend/middleware"
 	"hms-backend/request"
 	"hms-backend/response"
 	"hms-backend/services"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,11 +27,13 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		return
 	}
 
-	res, err := h.bookingService.CreateBooking(&req)
+	res, err := h.bookingService.CreateBooking(middleware.CurrentPrincipal(c), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
+	maskPII(c, res)
+	hideFlagWarnings(c, res)
 	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
 }
 
@@ -44,34 +48,36 @@ func (h *BookingHandler) GetBookingByReference(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
+	maskPII(c, res)
 	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
 }
 
 func (h *BookingHandler) GetBookingByDateRange(c *gin.Context) {
 	checkInQuery := c.Query("check_in")
 	checkOutQuery := c.Query("check_out")
-	layout := "2006-01-02"
 	if checkInQuery == "" || checkOutQuery == "" {
 		c.JSON(http.StatusBadRequest, response.Response{"400", "check_in and check_out dates are required", nil})
 		return
 	}
-	checkInStr, err := time.Parse(layout, checkInQuery)
+	checkInStr, err := request.ParseDate(checkInQuery)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{"400", "invalid date check_in format", nil})
 		return
 	}
 
-	checkOutStr, err := time.Parse(layout, checkOutQuery)
+	checkOutStr, err := request.ParseDate(checkOutQuery)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{"400", "invalid date check_out format", nil})
 		return
 	}
 
-	bookings, err := h.bookingService.ListBookingsForDateRange(checkInStr, checkOutStr)
+	propertyID, _ := strconv.ParseUint(c.Query("property_id"), 10, 32)
+	bookings, err := h.bookingService.ListBookingsForDateRange(uint(propertyID), checkInStr, checkOutStr)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
+	maskPII(c, bookings...)
 	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", bookings})
 }
 
@@ -81,11 +87,18 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
 		return
 	}
-	res, err := h.bookingService.CancelBooking(req)
+	res, err := h.bookingService.CancelBooking(middleware.CurrentPrincipal(c), req)
+	if errors.Is(err, services.ErrRefundFailed) {
+		// The booking is cancelled; the rest must be refunded by hand.
+		maskPII(c, res)
+		c.JSON(http.StatusBadGateway, response.Response{"502", err.Error(), res})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
+	maskPII(c, res)
 	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
 }
 
@@ -95,11 +108,13 @@ func (h *BookingHandler) CheckIn(c *gin.Context) {
 		c.JSON(http.StatusNotFound, response.Response{"404", "Bad Request", nil})
 		return
 	}
-	res, err := h.bookingService.CheckInGuest(req.BookingReference)
+	res, err := h.bookingService.CheckInGuest(middleware.CurrentPrincipal(c), req.BookingReference)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
+	maskPII(c, res)
+	hideFlagWarnings(c, res)
 	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
 }
 func (h *BookingHandler) Checkout(c *gin.Context) {
@@ -108,7 +123,95 @@ func (h *BookingHandler) Checkout(c *gin.Context) {
 		c.JSON(http.StatusNotFound, response.Response{"404", "Bad Request", nil})
 		return
 	}
-	res, err := h.bookingService.CheckOutGuest(req.BookingReference)
+	res, err := h.bookingService.CheckOutGuest(middleware.CurrentPrincipal(c), req.BookingReference)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *BookingHandler) AddOccupant(c *gin.Context) {
+	var req request.AddOccupantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.bookingService.AddOccupant(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *BookingHandler) RemoveOccupant(c *gin.Context) {
+	ref := c.Param("id")
+	occupantID, err := strconv.Atoi(c.Param("occupant_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.bookingService.RemoveOccupant(middleware.CurrentPrincipal(c), ref, uint(occupantID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *BookingHandler) UpdateSpecialRequests(c *gin.Context) {
+	var req request.UpdateSpecialRequestsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.bookingService.UpdateSpecialRequests(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+// GetArrivals handles GET /api/booking/arrivals?property_id=&date=, date defaults to the business date.
+func (h *BookingHandler) GetArrivals(c *gin.Context) {
+	propertyID, err := strconv.ParseUint(c.Query("property_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", "property_id is required", nil})
+		return
+	}
+	res, err := h.bookingService.GetArrivals(uint(propertyID), c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	maskPII(c, res...)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *BookingHandler) RecordDeposit(c *gin.Context) {
+	var req request.DepositPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.bookingService.RecordDeposit(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+// ExpireDeposits runs the overdue deposit check now, e.g. during the night audit.
+func (h *BookingHandler) ExpireDeposits(c *gin.Context) {
+	res, err := h.bookingService.ExpireDeposits(middleware.CurrentPrincipal(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
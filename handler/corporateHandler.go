@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"hms-backend/middleware"
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CorporateHandler struct {
+	corporateServices services.CorporateServices
+}
+
+func NewCorporateHandler(s services.CorporateServices) *CorporateHandler {
+	return &CorporateHandler{corporateServices: s}
+}
+
+func (h *CorporateHandler) Create(c *gin.Context) {
+	var req request.CorporateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.corporateServices.Create(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *CorporateHandler) Update(c *gin.Context) {
+	var req request.UpdateCorporateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.corporateServices.Update(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *CorporateHandler) GetAll(c *gin.Context) {
+	res, err := h.corporateServices.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *CorporateHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.corporateServices.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *CorporateHandler) AddGuest(c *gin.Context) {
+	var req request.CorporateGuestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.corporateServices.AddGuest(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *CorporateHandler) RemoveGuest(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	guestID, err := strconv.Atoi(c.Param("guest_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.corporateServices.RemoveGuest(middleware.CurrentPrincipal(c), uint(id), uint(guestID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
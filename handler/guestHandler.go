@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"hms-backend/middleware"
 	"hms-backend/request"
 	"hms-backend/response"
 	"hms-backend/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,11 +26,12 @@ func (h *GuestHandler) CreateNewGuest(c *gin.Context) {
 		return
 	}
 
-	res, err := h.guestServices.Create(&req)
+	res, err := h.guestServices.Create(middleware.CurrentPrincipal(c), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
+	maskPII(c, res)
 	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
 }
 
@@ -40,6 +43,7 @@ func (h *GuestHandler) GetGuestByCredentialID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
 		return
 	}
+	maskPII(c, res)
 	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
 }
 
@@ -50,21 +54,157 @@ func (h *GuestHandler) Update(c *gin.Context) {
 		return
 	}
 
-	res, err := h.guestServices.Update(&req)
+	res, err := h.guestServices.Update(middleware.CurrentPrincipal(c), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
+	maskPII(c, res)
 	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
 }
 
 func (h *GuestHandler) Delete(c *gin.Context) {
 	credType := c.Query("credential_type")
 	credID := c.Query("credential_number")
-	err := h.guestServices.Delete(credType, credID)
+	err := h.guestServices.Delete(middleware.CurrentPrincipal(c), credType, credID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
 		return
 	}
 	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", nil})
 }
+
+// Export returns every record held about the guest, unmasked.
+func (h *GuestHandler) Export(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.Export(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *GuestHandler) Erase(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	if err := h.guestServices.Erase(middleware.CurrentPrincipal(c), uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", nil})
+}
+
+func (h *GuestHandler) FindDuplicates(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.FindDuplicates(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	maskPII(c, res...)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *GuestHandler) Merge(c *gin.Context) {
+	var req request.MergeGuestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.Merge(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+// Search handles GET /api/guest?q=&sort=&page=&page_size=
+func (h *GuestHandler) Search(c *gin.Context) {
+	var params request.GuestSearchParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.Search(&params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *GuestHandler) History(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.History(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	maskPII(c, res)
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *GuestHandler) AddFlag(c *gin.Context) {
+	var req request.CreateGuestFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.AddFlag(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *GuestHandler) GetFlags(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.ListFlags(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *GuestHandler) RemoveFlag(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("flag_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.guestServices.RemoveFlag(middleware.CurrentPrincipal(c), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *GuestHandler) GetCredentialTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", h.guestServices.CredentialTypes()})
+}
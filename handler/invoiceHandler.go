@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"hms-backend/middleware"
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceHandler struct {
+	invoiceServices services.InvoiceServices
+}
+
+func NewInvoiceHandler(s services.InvoiceServices) *InvoiceHandler {
+	return &InvoiceHandler{invoiceServices: s}
+}
+
+func (h *InvoiceHandler) IssueInvoice(c *gin.Context) {
+	var req request.IssueInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.invoiceServices.Issue(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+// VoidInvoice cancels the booking's invoice and returns the credit note.
+func (h *InvoiceHandler) VoidInvoice(c *gin.Context) {
+	var req request.VoidInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.invoiceServices.Void(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+// DownloadInvoice sends the booking's invoice as a PDF, or as HTML with
+// ?format=html. A voided invoice or a credit note is picked with ?number=.
+func (h *InvoiceHandler) DownloadInvoice(c *gin.Context) {
+	format := c.DefaultQuery("format", services.InvoiceFormatPDF)
+	res, err := h.invoiceServices.GetInvoice(c.Param("id"), c.Query("number"), format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	if format == services.InvoiceFormatPDF {
+		c.Header("Content-Disposition", `attachment; filename="`+res.FileName+`"`)
+	}
+	c.Data(http.StatusOK, res.ContentType, res.Content)
+}
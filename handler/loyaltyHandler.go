@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"hms-backend/middleware"
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoyaltyHandler struct {
+	loyaltyServices services.LoyaltyServices
+}
+
+func NewLoyaltyHandler(s services.LoyaltyServices) *LoyaltyHandler {
+	return &LoyaltyHandler{loyaltyServices: s}
+}
+
+func (h *LoyaltyHandler) Enroll(c *gin.Context) {
+	var req request.EnrollLoyaltyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.loyaltyServices.Enroll(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *LoyaltyHandler) GetAccount(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("guest_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.loyaltyServices.GetAccount(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *LoyaltyHandler) GetLedger(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("guest_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.loyaltyServices.GetLedger(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *LoyaltyHandler) Redeem(c *gin.Context) {
+	var req request.RedeemPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.loyaltyServices.Redeem(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *LoyaltyHandler) CreditStay(c *gin.Context) {
+	var req request.CreditStayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.loyaltyServices.CreditStay(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"hms-backend/middleware"
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookSignatureHeader carries the gateway's signature of a webhook body.
+const WebhookSignatureHeader = "X-Gateway-Signature"
+
+type PaymentHandler struct {
+	paymentServices services.PaymentServices
+}
+
+func NewPaymentHandler(s services.PaymentServices) *PaymentHandler {
+	return &PaymentHandler{paymentServices: s}
+}
+
+func (h *PaymentHandler) TokenizeCard(c *gin.Context) {
+	var req request.CardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.paymentServices.TokenizeCard(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Successful", res})
+}
+
+func (h *PaymentHandler) Authorize(c *gin.Context) {
+	var req request.AuthorizePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.paymentServices.Authorize(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Successful", res})
+}
+
+func (h *PaymentHandler) Capture(c *gin.Context) {
+	var req request.CapturePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.paymentServices.Capture(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *PaymentHandler) Void(c *gin.Context) {
+	var req request.TransactionIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.paymentServices.Void(middleware.CurrentPrincipal(c), req.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+// Webhook receives asynchronous payment results. It is called by the gateway,
+// not by staff, and is authenticated by the signature of the body.
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	err = h.paymentServices.HandleWebhook(payload, c.GetHeader(WebhookSignatureHeader))
+	if errors.Is(err, services.ErrInvalidWebhookSignature) {
+		c.JSON(http.StatusUnauthorized, response.Response{"401", err.Error(), nil})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", nil})
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"hms-backend/middleware"
+	"hms-backend/model"
+	"hms-backend/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// piiMasker is implemented by responses that carry guest identity data.
+type piiMasker interface {
+	MaskPII()
+}
+
+// hideFlagWarnings drops guest flag warnings unless the caller may read flags.
+func hideFlagWarnings(c *gin.Context, res *response.BookingResponse) {
+	if res != nil && !middleware.HasPermission(c, model.PermGuestFlagRead) {
+		res.Warnings = nil
+	}
+}
+
+// maskPII hides guest identity data in the responses unless the caller's role
+// is granted PII access.
+func maskPII[T piiMasker](c *gin.Context, responses ...T) {
+	if middleware.HasPermission(c, model.PermGuestPII) {
+		return
+	}
+	for _, res := range responses {
+		res.MaskPII()
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PropertyHandler struct {
+	propertyServices services.PropertyServices
+}
+
+func NewPropertyHandler(s services.PropertyServices) *PropertyHandler {
+	return &PropertyHandler{propertyServices: s}
+}
+
+func (h *PropertyHandler) CreateProperty(c *gin.Context) {
+	var req request.PropertyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.propertyServices.Create(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Successful", res})
+}
+
+func (h *PropertyHandler) GetAllProperties(c *gin.Context) {
+	res, err := h.propertyServices.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *PropertyHandler) GetPropertyByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.propertyServices.GetByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *PropertyHandler) UpdateProperty(c *gin.Context) {
+	var req request.UpdatePropertyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.propertyServices.Update(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful update property", res})
+}
+
+func (h *PropertyHandler) GetBusinessDate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.propertyServices.GetBusinessDate(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
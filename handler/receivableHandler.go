@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"hms-backend/middleware"
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReceivableHandler struct {
+	receivableServices services.ReceivableServices
+}
+
+func NewReceivableHandler(s services.ReceivableServices) *ReceivableHandler {
+	return &ReceivableHandler{receivableServices: s}
+}
+
+func (h *ReceivableHandler) InvoiceTransaction(c *gin.Context) {
+	var req request.InvoiceTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.receivableServices.InvoiceTransaction(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *ReceivableHandler) RecordPayment(c *gin.Context) {
+	var req request.ReceivablePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.receivableServices.RecordPayment(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *ReceivableHandler) IssueCreditNote(c *gin.Context) {
+	var req request.CreditNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.receivableServices.IssueCreditNote(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Sucessful", res})
+}
+
+func (h *ReceivableHandler) GetInvoices(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	openOnly := c.Query("open") == "true"
+	res, err := h.receivableServices.GetInvoices(uint(id), openOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *ReceivableHandler) GetStatement(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	var params request.StatementParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.receivableServices.GetStatement(uint(id), &params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
+
+func (h *ReceivableHandler) GetAging(c *gin.Context) {
+	res, err := h.receivableServices.GetAging()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Sucessful", res})
+}
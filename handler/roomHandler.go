@@ -1,12 +1,13 @@
 package handler
 
 import (
+	"hms-backend/middleware"
 	"hms-backend/request"
 	"hms-backend/response"
 	"hms-backend/services"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,7 +23,9 @@ func NewRoomHandler(s services.RoomServices) *RoomHandler {
 
 // GET /rooms
 func (h *RoomHandler) GetAllRoom(c *gin.Context) {
-	resp, err := h.roomServices.GetAll()
+	// property_id is optional; without it rooms of every property are listed.
+	propertyID, _ := strconv.ParseUint(c.Query("property_id"), 10, 32)
+	resp, err := h.roomServices.GetAll(uint(propertyID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, response.Response{"400", "Room Not Found: " + err.Error(), nil})
 		return
@@ -39,7 +42,7 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	}
 
 	// pass by pointer since service expects *CreateRoomRequest
-	room, err := h.roomServices.Create(&req)
+	room, err := h.roomServices.Create(middleware.CurrentPrincipal(c), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", "Failed to create room: " + err.Error(), nil})
 		return
@@ -54,7 +57,7 @@ func (h *RoomHandler) CreateRoomType(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
 		return
 	}
-	room, err := h.roomServices.CreateRoomType(&req)
+	room, err := h.roomServices.CreateRoomType(middleware.CurrentPrincipal(c), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
@@ -62,6 +65,92 @@ func (h *RoomHandler) CreateRoomType(c *gin.Context) {
 	c.JSON(http.StatusCreated, response.Response{"00", "Successful", room})
 }
 
+// GET /rooms/type
+func (h *RoomHandler) GetAllRoomTypes(c *gin.Context) {
+	includeRetired, _ := strconv.ParseBool(c.Query("include_retired"))
+	propertyID, _ := strconv.ParseUint(c.Query("property_id"), 10, 32)
+	roomTypes, err := h.roomServices.GetAllRoomTypes(uint(propertyID), includeRetired)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", roomTypes})
+}
+
+// GET /rooms/type/:id
+func (h *RoomHandler) GetRoomTypeByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	roomType, err := h.roomServices.GetRoomTypeByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", roomType})
+}
+
+// PUT /rooms/type
+func (h *RoomHandler) UpdateRoomType(c *gin.Context) {
+	var req request.UpdateRoomTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	roomType, err := h.roomServices.UpdateRoomType(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful update room type", roomType})
+}
+
+// POST /rooms/type/retire
+func (h *RoomHandler) RetireRoomType(c *gin.Context) {
+	var req request.RoomTypeIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	roomType, err := h.roomServices.RetireRoomType(middleware.CurrentPrincipal(c), req.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful retire room type", roomType})
+}
+
+// POST /rooms/type/reactivate
+func (h *RoomHandler) ReactivateRoomType(c *gin.Context) {
+	var req request.RoomTypeIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	roomType, err := h.roomServices.ReactivateRoomType(middleware.CurrentPrincipal(c), req.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful reactivate room type", roomType})
+}
+
+// DELETE /rooms/type/:id
+func (h *RoomHandler) DeleteRoomType(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	if err := h.roomServices.DeleteRoomType(middleware.CurrentPrincipal(c), uint(id)); err != nil {
+		c.JSON(http.StatusConflict, response.Response{"409", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful delete room type", nil})
+}
+
 func (h *RoomHandler) GetRoomByID(c *gin.Context) {
 	id := c.Param("id")
 	roomID, err := strconv.Atoi(id)
@@ -82,28 +171,32 @@ func (h *RoomHandler) GetAvailableRoom(c *gin.Context) {
 
 	checkInQuery := c.Query("check_in")
 	checkOutQuery := c.Query("check_out")
-	layout := "2006-01-02"
 	if checkInQuery == "" || checkOutQuery == "" {
 		c.JSON(http.StatusBadRequest, response.Response{"400", "check_in and check_out dates are required", nil})
 		return
 	}
 
-	checkInStr, err := time.Parse(layout, checkInQuery)
+	checkInStr, err := request.ParseDate(checkInQuery)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{"400", "invalid date check_in format", nil})
 		return
 	}
 
-	checkOutStr, err := time.Parse(layout, checkOutQuery)
+	checkOutStr, err := request.ParseDate(checkOutQuery)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{"400", "invalid date check_out format", nil})
 		return
 	}
 
+	propertyID, _ := strconv.ParseUint(c.Query("property_id"), 10, 32)
 	params := request.RoomFilterParams{
-		CheckIn:  checkInStr,
-		CheckOut: checkOutStr,
-		Category: c.Query("category"),
+		PropertyID:       uint(propertyID),
+		CheckIn:          checkInStr,
+		CheckOut:         checkOutStr,
+		Category:         c.Query("category"),
+		Building:         c.Query("building"),
+		View:             c.Query("view"),
+		BedConfiguration: c.Query("bed_configuration"),
 	}
 
 	if minPriceStr := c.Query("min_price"); minPriceStr != "" {
@@ -112,6 +205,44 @@ func (h *RoomHandler) GetAvailableRoom(c *gin.Context) {
 	if maxPriceStr := c.Query("max_price"); maxPriceStr != "" {
 		params.MaxPrice, _ = strconv.ParseFloat(maxPriceStr, 64)
 	}
+	if minFloorStr := c.Query("min_floor"); minFloorStr != "" {
+		params.MinFloor, _ = strconv.Atoi(minFloorStr)
+	}
+	if maxFloorStr := c.Query("max_floor"); maxFloorStr != "" {
+		params.MaxFloor, _ = strconv.Atoi(maxFloorStr)
+	}
+	if smokingStr := c.Query("smoking"); smokingStr != "" {
+		smoking, err := strconv.ParseBool(smokingStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{"400", "invalid smoking value, must be true or false", nil})
+			return
+		}
+		params.Smoking = &smoking
+	}
+	if accessibleStr := c.Query("accessible"); accessibleStr != "" {
+		accessible, err := strconv.ParseBool(accessibleStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{"400", "invalid accessible value, must be true or false", nil})
+			return
+		}
+		params.Accessible = &accessible
+	}
+	if adultsStr := c.Query("adults"); adultsStr != "" {
+		adults, _ := strconv.ParseUint(adultsStr, 10, 32)
+		params.Adults = uint(adults)
+	}
+	if childrenStr := c.Query("children"); childrenStr != "" {
+		children, _ := strconv.ParseUint(childrenStr, 10, 32)
+		params.Children = uint(children)
+	}
+	if guestIDStr := c.Query("guest_id"); guestIDStr != "" {
+		guestID, _ := strconv.ParseUint(guestIDStr, 10, 32)
+		params.GuestID = uint(guestID)
+	}
+	// Amenities are passed as a comma separated list, e.g. amenities=minibar,bathtub
+	if amenitiesStr := c.Query("amenities"); amenitiesStr != "" {
+		params.Amenities = strings.Split(amenitiesStr, ",")
+	}
 
 	rooms, err := h.roomServices.FindAvailable(params)
 	if err != nil {
@@ -127,7 +258,7 @@ func (h *RoomHandler) UpdateRoom(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
 		return
 	}
-	room, err := h.roomServices.Update(req)
+	room, err := h.roomServices.Update(middleware.CurrentPrincipal(c), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
@@ -147,7 +278,7 @@ func (h *RoomHandler) ChangeStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
 		return
 	}
-	err = h.roomServices.ChangeStatus(uint(strId), req.Status)
+	err = h.roomServices.ChangeStatus(middleware.CurrentPrincipal(c), uint(strId), req.Status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
@@ -161,7 +292,13 @@ func (h *RoomHandler) DeleteRoom(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, response.Response{"400", "Room number required", nil})
 		return
 	}
-	if err := h.roomServices.Delete(room); err != nil {
+	// Room numbers repeat across properties, so the property must be given.
+	propertyID, err := strconv.ParseUint(c.Query("property_id"), 10, 32)
+	if err != nil || propertyID == 0 {
+		c.JSON(http.StatusBadRequest, response.Response{"400", "Property id required", nil})
+		return
+	}
+	if err := h.roomServices.Delete(middleware.CurrentPrincipal(c), uint(propertyID), room); err != nil {
 		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
 		return
 	}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"hms-backend/middleware"
+	"hms-backend/request"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransactionHandler struct {
+	transactionServices services.TransactionServices
+}
+
+func NewTransactionHandler(s services.TransactionServices) *TransactionHandler {
+	return &TransactionHandler{transactionServices: s}
+}
+
+func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
+	var req request.CreateTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.transactionServices.Create(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Successful", res})
+}
+
+func (h *TransactionHandler) MarkPaid(c *gin.Context) {
+	var req request.TransactionIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.transactionServices.MarkPaid(middleware.CurrentPrincipal(c), req.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.Response{"500", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
+
+func (h *TransactionHandler) Refund(c *gin.Context) {
+	var req request.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	res, err := h.transactionServices.Refund(middleware.CurrentPrincipal(c), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{"400", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusCreated, response.Response{"00", "Successful", res})
+}
+
+func (h *TransactionHandler) GetTransactionsForBooking(c *gin.Context) {
+	res, err := h.transactionServices.GetForBooking(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, response.Response{"404", err.Error(), nil})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{"00", "Successful", res})
+}
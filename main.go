@@ -1,16 +1,31 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"hms-backend/config"
 	"hms-backend/model"
+	"hms-backend/repository"
 	"hms-backend/routes"
+	"hms-backend/services"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // property timezones must load even without system zoneinfo
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
 func init() {
+	// Secrets are never committed: they come from the environment or the
+	// untracked .env.local (see .env.example). Values already set win.
+	if err := godotenv.Load(".env.local"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatal("Error loading .env.local file")
+	}
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
@@ -19,12 +34,96 @@ func init() {
 
 func main() {
 	config.ConnectDB()
-	config.DB.AutoMigrate(&model.Room{},
+	// Times used to be stored in the server's local time; they are UTC now.
+	if n, err := repository.NewMigrationRepository(config.DB).ConvertTimesToUTC(config.LegacyTimezone(), time.Now().UTC()); err != nil {
+		log.Fatal("⚠️ Failed to convert stored times to UTC: ", err)
+	} else if n > 0 {
+		log.Printf("Converted the stored times of %d rows to UTC", n)
+	}
+	encryptionConfig := config.LoadEncryptionConfig()
+	keyring, err := repository.NewKeyring(encryptionConfig.Keys, encryptionConfig.ActiveKeyID, encryptionConfig.BlindIndexKey)
+	if err != nil {
+		log.Fatal("⚠️ Invalid PII encryption settings: ", err)
+	}
+	repository.UseKeyring(keyring)
+	guestRepository := repository.NewGuestRepository(config.DB, keyring)
+	if err := guestRepository.DropPlaintextIndex(); err != nil {
+		log.Fatal("⚠️ Failed to drop plaintext guest index: ", err)
+	}
+	if err := repository.NewRoomRepository(config.DB).DropGlobalUniqueIndexes(); err != nil {
+		log.Fatal("⚠️ Failed to drop global room number and room type name indexes: ", err)
+	}
+	if err := repository.NewInvoiceRepository(config.DB).DropBookingUniqueIndex(); err != nil {
+		log.Fatal("⚠️ Failed to drop the one invoice per booking index: ", err)
+	}
+	// Transactions used to store an integer booking_id that matched no booking.
+	if n, err := repository.NewTransactionRepository(config.DB).MigrateBookingLink(); err != nil {
+		log.Fatal("⚠️ Failed to migrate transaction booking links: ", err)
+	} else if n > 0 {
+		log.Printf("%d transactions keep their old booking id in legacy_booking_id and need to be linked by hand", n)
+	}
+	config.DB.AutoMigrate(&model.Property{},
+		&model.Room{},
 		&model.RoomType{},
+		&model.Amenity{},
 		&model.Booking{},
+		&model.BookingOccupant{},
 		&model.Guest{},
-		&model.Transaction{})
+		&model.GuestFlag{},
+		&model.Transaction{},
+		&model.LoyaltyAccount{},
+		&model.LoyaltyEntry{},
+		&model.CorporateAccount{},
+		&model.CorporateGuest{},
+		&model.ReceivableEntry{},
+		&model.ReceivableAllocation{},
+		&model.Invoice{},
+		&model.InvoiceSequence{},
+		&model.StaffUser{},
+		&model.AuthSession{},
+		&model.RetiredRefreshToken{},
+		&model.APIKey{},
+		&model.AuditLog{},
+		&model.SchemaMigration{})
+	// Data created before multi-property support is assigned to the default property.
+	if _, err := repository.NewPropertyRepository(config.DB).EnsureDefault(); err != nil {
+		log.Fatal("⚠️ Failed to prepare default property: ", err)
+	}
+	// Encrypts legacy plaintext rows and moves old ciphertexts to the active key.
+	if n, err := guestRepository.EncryptExisting(); err != nil {
+		log.Fatal("⚠️ Failed to encrypt guest data: ", err)
+	} else if n > 0 {
+		log.Printf("Encrypted guest data in %d rows", n)
+	}
+	// Lookups use normalised credentials; older rows hold them as entered.
+	if n, skipped, err := guestRepository.NormalizeCredentials(); err != nil {
+		log.Fatal("⚠️ Failed to normalise guest credentials: ", err)
+	} else if n > 0 || skipped > 0 {
+		log.Printf("Normalised credentials of %d guests; %d duplicates left for merging", n, skipped)
+	}
+	authConfig := config.LoadAuthConfig()
+	paymentConfig := config.LoadPaymentConfig()
+	if err := services.BootstrapAdmin(repository.NewStaffRepository(config.DB), authConfig.AdminUsername, authConfig.AdminPassword); err != nil {
+		log.Fatal("⚠️ Failed to prepare admin account: ", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	r := gin.Default()
-	routes.RegisterRoutes(r, config.DB)
-	r.Run(":4000")
+	bookingServices := routes.RegisterRoutes(r, config.DB, authConfig, paymentConfig, keyring)
+	// Pending bookings are cancelled within the hour once their deposit is
+	// overdue. Only one instance checks at a time.
+	go services.RunDepositExpiry(ctx, bookingServices, repository.NewJobLock(config.DB), time.Hour)
+
+	server := &http.Server{Addr: ":4000", Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdown); err != nil {
+			log.Printf("Server shutdown failed: %v", err)
+		}
+	}()
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal("⚠️ Server failed: ", err)
+	}
 }
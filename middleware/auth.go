@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"hms-backend/model"
+	"hms-backend/response"
+	"hms-backend/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrincipalKey is the gin context key holding the *model.Principal of the caller.
+const PrincipalKey = "principal"
+
+// APIKeyHeader carries the key of machine clients.
+const APIKeyHeader = "X-API-Key"
+
+// RequireAuth rejects requests that carry neither a valid
+// "Authorization: Bearer <token>" staff token nor an X-API-Key header.
+func RequireAuth(auth services.AuthServices) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal *model.Principal
+		var err error
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			principal, err = auth.AuthenticateAPIKey(key)
+		} else {
+			token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if !found || token == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{"401", "Missing bearer token or API key", nil})
+				return
+			}
+			principal, err = auth.Authenticate(token)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.Response{"401", err.Error(), nil})
+			return
+		}
+		c.Set(PrincipalKey, principal)
+		c.Next()
+	}
+}
+
+// CurrentPrincipal returns the caller stored by RequireAuth, or nil.
+func CurrentPrincipal(c *gin.Context) *model.Principal {
+	if v, ok := c.Get(PrincipalKey); ok {
+		if principal, ok := v.(*model.Principal); ok {
+			return principal
+		}
+	}
+	return nil
+}
+
+// RequirePermission rejects callers that are not granted the permission.
+// It must run after RequireAuth.
+func RequirePermission(perm model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Response{"403", "Forbidden: missing permission " + string(perm), nil})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the authenticated caller is granted the permission.
+func HasPermission(c *gin.Context, perm model.Permission) bool {
+	return CurrentPrincipal(c).Can(perm)
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// APIKey authenticates a machine client such as the booking engine or a kiosk.
+// Only a hash of the key is stored; the plain key is shown once at creation.
+type APIKey struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"not null"`
+
+	// Prefix is the public, non-secret part of the key used for lookup.
+	Prefix  string `gorm:"unique;not null;type:varchar(16)"`
+	KeyHash string `gorm:"not null;type:char(64)"`
+
+	// Permissions granted to the key, independent of any staff role.
+	Permissions []Permission `gorm:"serializer:json;type:text"`
+
+	CreatedByID uint
+
+	// --- Usage Tracking ---
+
+	LastUsedAt *time.Time
+	UsageCount uint64 `gorm:"not null;default:0"`
+
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+
+	CreatedAt time.Time
+}
+
+// Can reports whether the key is granted the permission.
+func (k *APIKey) Can(p Permission) bool {
+	for _, granted := range k.Permissions {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Define a custom type for AuditAction for type safety.
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	AuditMerge  AuditAction = "merge"
+)
+
+// Entity types recorded in the audit trail.
+const (
+	EntityRoom           = "room"
+	EntityRoomType       = "room_type"
+	EntityGuest          = "guest"
+	EntityBooking        = "booking"
+	EntityGuestFlag      = "guest_flag"
+	EntityOccupant       = "booking_occupant"
+	EntityTransaction    = "transaction"
+	EntityLoyaltyAccount = "loyalty_account"
+	EntityLoyaltyEntry   = "loyalty_entry"
+	EntityCorporate      = "corporate_account"
+	EntityCorporateGuest = "corporate_guest"
+	EntityReceivable     = "receivable_entry"
+	EntityInvoice        = "invoice"
+)
+
+// FieldChange is the before and after value of one field.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditLog is one entry of the append-only audit trail.
+type AuditLog struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Actor describes who made the change, see Principal.Name.
+	Actor string `gorm:"not null;type:varchar(100)"`
+
+	EntityType string      `gorm:"not null;type:varchar(30);index:idx_audit_entity,priority:1"`
+	EntityID   string      `gorm:"not null;type:varchar(64);index:idx_audit_entity,priority:2"`
+	Action     AuditAction `gorm:"not null;type:varchar(10)"`
+
+	// Changes maps field names to their before/after values.
+	Changes map[string]FieldChange `gorm:"serializer:json;type:json"`
+
+	CreatedAt time.Time `gorm:"index"`
+}
+
+var ErrAuditLogImmutable = errors.New("audit log entries cannot be changed")
+
+// BeforeUpdate keeps the audit trail append-only.
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete keeps the audit trail append-only.
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
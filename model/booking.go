@@ -23,18 +23,55 @@ type Booking struct {
 	// --- Foreign Keys and Relationships ---
 
 	// Use uint for foreign keys pointing to auto-incrementing IDs.
-	RoomID  uint
-	GuestID uint
+	// PropertyID is copied from the room so bookings can be scoped without a join.
+	PropertyID uint `gorm:"index"`
+	RoomID     uint
+	GuestID    uint
 
 	// Add the struct fields for GORM relationships. This enables Preload.
 	Room  *Room
 	Guest *Guest
 
+	// Companions sharing the room; the main guest is not repeated here.
+	Occupants []BookingOccupant
+
+	// CorporateAccountID is set when the guest books under a company
+	// contract. With DirectBill the room charges go to the company at check-out.
+	CorporateAccountID *uint `gorm:"index"`
+	CorporateAccount   *CorporateAccount
+	DirectBill         bool `gorm:"not null;default:false"`
+
+	// CorporateDiscount is the negotiated discount, in percent, already
+	// taken off the room price in NightlyRate.
+	CorporateDiscount float64
+
 	// --- Booking Details ---
 
 	CheckInDate  time.Time
 	CheckOutDate time.Time
 
+	// --- Occupancy ---
+
+	// Infants do not count towards the room type capacity.
+	Adults    uint `gorm:"not null;default:1"`
+	Children  uint `gorm:"not null;default:0"`
+	Infants   uint `gorm:"not null;default:0"`
+	ExtraBeds uint `gorm:"not null;default:0"`
+
+	// NightlyRate is the room price plus extra bed and per-person supplements,
+	// captured when the booking is made.
+	NightlyRate float64
+
+	// --- Prepayment Schedule ---
+
+	// A booking taken with a deposit stays pending until DepositAmount is
+	// paid, and is cancelled when DepositDueDate passes first. BalanceDueDate
+	// is when the rest of the stay is due, if it is prepaid. Payments are
+	// booking transactions and count towards the final bill.
+	DepositAmount  float64
+	DepositDueDate *time.Time `gorm:"index"`
+	BalanceDueDate *time.Time
+
 	// Use the custom BookingStatus type to prevent typos.
 	// GORM will store this as a string in the database.
 	Status BookingStatus `gorm:"type:varchar(20)"`
@@ -42,9 +79,36 @@ type Booking struct {
 	// Using `type:text` allows for longer notes if needed.
 	Notes string `gorm:"type:text"`
 
+	// SpecialRequests are the structured requests for this stay; Notes stays free text.
+	SpecialRequests []SpecialRequest `gorm:"serializer:json;type:json"`
+
 	// --- Automatic Timestamps ---
 
 	// GORM automatically handles these fields by name.
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// Nights is the length of the stay; dates are stored as midnight UTC.
+func (b *Booking) Nights() int {
+	return int(b.CheckOutDate.Sub(b.CheckInDate).Hours() / 24)
+}
+
+// RoomCharges is the price of every night of the stay at the booked rate.
+func (b *Booking) RoomCharges() float64 {
+	return b.NightlyRate * float64(b.Nights())
+}
+
+// IsActive reports whether the booking still holds its room.
+func (b *Booking) IsActive() bool {
+	switch b.Status {
+	case StatusPending, StatusConfirmed, StatusCheckedIn:
+		return true
+	}
+	return false
+}
+
+// Overlaps reports whether the stay shares at least one night with [checkIn, checkOut).
+func (b *Booking) Overlaps(checkIn, checkOut time.Time) bool {
+	return b.CheckInDate.Before(checkOut) && checkIn.Before(b.CheckOutDate)
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// PaymentMethodDirectBill marks room charges billed to a corporate account
+// at check-out instead of being paid by the guest.
+const PaymentMethodDirectBill = "direct_bill"
+
+// BillingAccountType tells companies from travel agents. Both are billed
+// through the same accounts and city ledger.
+type BillingAccountType string
+
+const (
+	BillingCompany     BillingAccountType = "company"
+	BillingTravelAgent BillingAccountType = "travel_agent"
+)
+
+// CorporateAccount is a company or travel agent with a negotiated contract.
+// Its guests book at a discount off RoomType.Price and may have their stay
+// billed to it.
+type CorporateAccount struct {
+	ID   uint   `gorm:"primaryKey"`
+	Code string `gorm:"type:varchar(20);uniqueIndex"`
+	Name string
+	Type BillingAccountType `gorm:"type:varchar(20);not null;default:company"`
+
+	ContactEmail   string
+	BillingAddress string `gorm:"type:text"`
+
+	// DiscountPercent applies to every room type unless RoomTypeDiscounts
+	// has a rate for it. Keys are room type IDs.
+	DiscountPercent   float64
+	RoomTypeDiscounts map[string]float64 `gorm:"serializer:json;type:json"`
+
+	// CreditLimit caps what may be owed through direct billing; zero
+	// means the company is not set up for direct billing.
+	CreditLimit float64
+
+	Active bool `gorm:"not null;default:true"`
+
+	// Guests allowed to book under the contract.
+	Guests []CorporateGuest `gorm:"foreignKey:AccountID"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DiscountFor returns the negotiated discount for a room type, in percent.
+func (a *CorporateAccount) DiscountFor(roomTypeID uint) float64 {
+	if d, ok := a.RoomTypeDiscounts[strconv.FormatUint(uint64(roomTypeID), 10)]; ok {
+		return d
+	}
+	return a.DiscountPercent
+}
+
+// Allows reports whether the guest may book under the account.
+func (a *CorporateAccount) Allows(guestID uint) bool {
+	for _, g := range a.Guests {
+		if g.GuestID == guestID {
+			return true
+		}
+	}
+	return false
+}
+
+// CorporateGuest links a guest to a corporate account they may book under.
+type CorporateGuest struct {
+	ID        uint `gorm:"primaryKey"`
+	AccountID uint `gorm:"uniqueIndex:idx_corporate_guest,priority:1"`
+	GuestID   uint `gorm:"uniqueIndex:idx_corporate_guest,priority:2"`
+	Guest     *Guest
+	CreatedAt time.Time
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type CredentialType string
+
+const (
+	CredentialNationalID     CredentialType = "national_id"
+	CredentialPassport       CredentialType = "passport"
+	CredentialDriversLicence CredentialType = "drivers_licence"
+)
+
+// CredentialSpec describes how identity documents of one type are checked.
+type CredentialSpec struct {
+	Type            CredentialType
+	Name            string
+	Pattern         *regexp.Regexp
+	Format          string
+	RequiresExpiry  bool
+	RequiresCountry bool
+}
+
+var credentialSpecs = map[CredentialType]CredentialSpec{
+	CredentialNationalID: {
+		Type:            CredentialNationalID,
+		Name:            "National ID card",
+		Pattern:         regexp.MustCompile(`^[A-Z0-9]{5,20}$`),
+		Format:          "5 to 20 letters or digits",
+		RequiresCountry: true,
+	},
+	CredentialPassport: {
+		Type:            CredentialPassport,
+		Name:            "Passport",
+		Pattern:         regexp.MustCompile(`^[A-Z0-9]{6,9}$`),
+		Format:          "6 to 9 letters or digits",
+		RequiresExpiry:  true,
+		RequiresCountry: true,
+	},
+	CredentialDriversLicence: {
+		Type:            CredentialDriversLicence,
+		Name:            "Driver's licence",
+		Pattern:         regexp.MustCompile(`^[A-Z0-9-]{5,20}$`),
+		Format:          "5 to 20 letters, digits or dashes",
+		RequiresExpiry:  true,
+		RequiresCountry: true,
+	},
+}
+
+// credentialAliases maps common spellings to the registered types.
+var credentialAliases = map[string]CredentialType{
+	"id_card":         CredentialNationalID,
+	"national_id":     CredentialNationalID,
+	"passport":        CredentialPassport,
+	"drivers_license": CredentialDriversLicence,
+	"drivers_licence": CredentialDriversLicence,
+	"driving_licence": CredentialDriversLicence,
+}
+
+var countryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// NormalizeCredentialType maps aliases to the registered type. Unknown types
+// are returned lower-cased so existing records can still be looked up.
+func NormalizeCredentialType(s string) string {
+	key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), " ", "_")
+	if t, ok := credentialAliases[key]; ok {
+		return string(t)
+	}
+	return key
+}
+
+// NormalizeIDNumber upper-cases the number and drops spaces.
+func NormalizeIDNumber(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+func LookupCredentialSpec(credentialType string) (CredentialSpec, bool) {
+	spec, ok := credentialSpecs[CredentialType(NormalizeCredentialType(credentialType))]
+	return spec, ok
+}
+
+// CredentialSpecs lists the registry, sorted by type.
+func CredentialSpecs() []CredentialSpec {
+	specs := make([]CredentialSpec, 0, len(credentialSpecs))
+	for _, spec := range credentialSpecs {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Type < specs[j].Type })
+	return specs
+}
+
+// Validate checks a normalised document number and its issuing details.
+// today is the date the document must still be valid on.
+func (spec CredentialSpec) Validate(number, issuingCountry string, expiresOn *time.Time, today time.Time) error {
+	if !spec.Pattern.MatchString(number) {
+		return fmt.Errorf("invalid %s number, expected %s", spec.Name, spec.Format)
+	}
+	if issuingCountry != "" && !countryCodePattern.MatchString(issuingCountry) {
+		return fmt.Errorf("invalid issuing country %q, expected a two-letter ISO 3166 code", issuingCountry)
+	}
+	if spec.RequiresCountry && issuingCountry == "" {
+		return fmt.Errorf("%s needs an issuing country", spec.Name)
+	}
+	if spec.RequiresExpiry && expiresOn == nil {
+		return fmt.Errorf("%s needs an expiry date", spec.Name)
+	}
+	if IsDocumentExpired(expiresOn, today) {
+		return fmt.Errorf("%s expired on %s", spec.Name, expiresOn.Format("2006-01-02"))
+	}
+	return nil
+}
+
+// IsDocumentExpired reports whether a document expiring on expiresOn can no
+// longer be used on today. Documents are valid through their expiry date.
+func IsDocumentExpired(expiresOn *time.Time, today time.Time) bool {
+	return expiresOn != nil && expiresOn.Before(today)
+}
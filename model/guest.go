@@ -1,13 +1,73 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+	"unicode"
+)
 
 type Guest struct {
 	ID             uint `gorm:"primaryKey"`
 	CredentialType string
 	FullName       string
-	Phone          string
-	Email          string
-	IDNumber       string `gorm:"unique"`
-	CreatedAt      time.Time
+
+	// Contact and identity data are encrypted at rest, see repository.Keyring.
+	Phone    string `gorm:"type:text;serializer:encrypted"`
+	Email    string `gorm:"type:text;serializer:encrypted"`
+	IDNumber string `gorm:"type:text;serializer:encrypted"`
+
+	// Issuing country (ISO 3166 alpha-2) and expiry of the identity document.
+	IssuingCountry string `gorm:"type:char(2)"`
+	DocumentExpiry *time.Time
+
+	// IDNumberIndex is a keyed hash of IDNumber, used for lookups and to keep
+	// identity numbers unique now that the column itself is encrypted.
+	IDNumberIndex *string `gorm:"type:char(64);uniqueIndex"`
+
+	// Keyed hashes of the normalised phone and email, used to find duplicates.
+	PhoneIndex string `gorm:"type:char(64);index"`
+	EmailIndex string `gorm:"type:char(64);index"`
+
+	// MergedIntoID points to the surviving profile once this one has been
+	// merged as a duplicate. Lookups by this profile's credential follow it.
+	MergedIntoID *uint `gorm:"index"`
+
+	Preferences GuestPreferences `gorm:"serializer:json;type:json"`
+
+	// ErasedAt is set once the guest's personal data has been anonymised on
+	// request. The row stays so bookings and transactions keep their owner.
+	ErasedAt *time.Time
+
+	CreatedAt time.Time
+}
+
+// ErasedGuestName replaces the full name of an erased guest.
+const ErasedGuestName = "Erased Guest"
+
+func (g *Guest) IsErased() bool {
+	return g.ErasedAt != nil
+}
+
+func (g *Guest) IsMerged() bool {
+	return g.MergedIntoID != nil
+}
+
+// NormalizePhone keeps the last nine digits so the same number matches with
+// or without a country code and formatting.
+func NormalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if len(d) > 9 {
+		d = d[len(d)-9:]
+	}
+	return d
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+type GuestFlagType string
+
+const (
+	FlagDoNotRent       GuestFlagType = "do_not_rent"
+	FlagVIP             GuestFlagType = "vip"
+	FlagRequiresDeposit GuestFlagType = "requires_deposit"
+	FlagFraudSuspicion  GuestFlagType = "fraud_suspicion"
+)
+
+// FlagAction is what happens when a flagged guest books or checks in.
+type FlagAction string
+
+const (
+	FlagActionNone   FlagAction = "none"
+	FlagActionWarn   FlagAction = "warn"
+	FlagActionRefuse FlagAction = "refuse"
+)
+
+// FlagPolicy decides the action per stage for one flag type.
+type FlagPolicy struct {
+	Booking FlagAction
+	CheckIn FlagAction
+}
+
+// FlagPolicies is the policy for every known flag type.
+var FlagPolicies = map[GuestFlagType]FlagPolicy{
+	FlagDoNotRent:       {Booking: FlagActionRefuse, CheckIn: FlagActionRefuse},
+	FlagFraudSuspicion:  {Booking: FlagActionWarn, CheckIn: FlagActionRefuse},
+	FlagRequiresDeposit: {Booking: FlagActionWarn, CheckIn: FlagActionWarn},
+	FlagVIP:             {Booking: FlagActionWarn, CheckIn: FlagActionWarn},
+}
+
+func (t GuestFlagType) IsValid() bool {
+	_, ok := FlagPolicies[t]
+	return ok
+}
+
+// GuestFlag marks a guest for special handling. Flags are never deleted;
+// removing one sets RemovedAt so the history stays visible.
+type GuestFlag struct {
+	ID        uint          `gorm:"primaryKey"`
+	GuestID   uint          `gorm:"index;not null"`
+	Type      GuestFlagType `gorm:"type:varchar(20);not null"`
+	Reason    string        `gorm:"type:text;not null"`
+	ExpiresAt *time.Time
+	CreatedBy string `gorm:"size:100"`
+	RemovedAt *time.Time
+	RemovedBy string `gorm:"size:100"`
+	CreatedAt time.Time
+}
+
+// IsActive reports whether the flag applies at the given time.
+func (f *GuestFlag) IsActive(now time.Time) bool {
+	return f.RemovedAt == nil && (f.ExpiresAt == nil || f.ExpiresAt.After(now))
+}
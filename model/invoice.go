@@ -0,0 +1,109 @@
+package model
+
+import "time"
+
+// Invoice is the bill issued for a booking. Numbers run per property without
+// gaps. What was billed is kept in Document, so the charges read the same
+// every time the invoice is downloaded.
+type Invoice struct {
+	ID         uint   `gorm:"primaryKey"`
+	PropertyID uint   `gorm:"uniqueIndex:idx_invoices_property_sequence,priority:1"`
+	Sequence   uint   `gorm:"uniqueIndex:idx_invoices_property_sequence,priority:2"`
+	Number     string `gorm:"type:varchar(40);uniqueIndex"`
+	BookingID  string `gorm:"type:char(26);index"`
+
+	// BalanceDue is what was owed when the invoice was issued. Downloads
+	// show the payments received since.
+	Total      float64
+	BalanceDue float64
+	Document   InvoiceDocument `gorm:"serializer:json;type:json"`
+
+	// CreditOfID is the invoice a credit note cancels. A credit note takes
+	// the next number of the same sequence and negates the invoice. Once
+	// VoidedAt is set, the booking can be invoiced again.
+	CreditOfID *uint `gorm:"index"`
+	VoidedAt   *time.Time
+
+	IssuedBy  string
+	CreatedAt time.Time
+}
+
+func (i *Invoice) IsCreditNote() bool {
+	return i.CreditOfID != nil
+}
+
+func (i *Invoice) IsVoided() bool {
+	return i.VoidedAt != nil
+}
+
+// InvoiceSequence holds the last invoice number used at a property. It is
+// locked while a number is taken, so numbers are never skipped or reused.
+type InvoiceSequence struct {
+	PropertyID uint `gorm:"primaryKey;autoIncrement:false"`
+	Last       uint
+}
+
+type InvoiceParty struct {
+	Name      string `json:"name"`
+	Address   string `json:"address,omitempty"`
+	Email     string `json:"email,omitempty"`
+	TaxNumber string `json:"tax_number,omitempty"`
+}
+
+type InvoiceLine struct {
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+// InvoiceTax is the tax included in the total. Base is the amount before tax.
+type InvoiceTax struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Base   float64 `json:"base"`
+	Amount float64 `json:"amount"`
+}
+
+// InvoicePayment is money received, or given back when Refund is set, in
+// which case Amount is negative. Pending payments are recorded but not
+// received yet, e.g. charges billed to a company, and are not deducted.
+type InvoicePayment struct {
+	Date    string  `json:"date"`
+	Method  string  `json:"method"`
+	Refund  bool    `json:"refund,omitempty"`
+	Pending bool    `json:"pending,omitempty"`
+	Amount  float64 `json:"amount"`
+}
+
+// InvoiceDocument is everything printed on an invoice.
+type InvoiceDocument struct {
+	Number   string `json:"number"`
+	IssuedOn string `json:"issued_on"`
+	Currency string `json:"currency"`
+
+	// CreditOf is the number of the invoice a credit note cancels.
+	CreditOf string `json:"credit_of,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+
+	Hotel  InvoiceParty `json:"hotel"`
+	BillTo InvoiceParty `json:"bill_to"`
+
+	GuestName        string `json:"guest_name"`
+	BookingReference string `json:"booking_reference"`
+	CheckInDate      string `json:"check_in_date"`
+	CheckOutDate     string `json:"check_out_date"`
+	RoomNumber       string `json:"room_number"`
+	RoomType         string `json:"room_type"`
+
+	// Line amounts include tax; Subtotal is the total before tax.
+	Lines    []InvoiceLine    `json:"lines"`
+	Subtotal float64          `json:"subtotal"`
+	Taxes    []InvoiceTax     `json:"taxes"`
+	Total    float64          `json:"total"`
+	Payments []InvoicePayment `json:"payments"`
+	Paid     float64          `json:"paid"`
+
+	BalanceDue float64 `json:"balance_due"`
+}
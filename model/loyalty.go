@@ -0,0 +1,124 @@
+package model
+
+import "time"
+
+type LoyaltyTier string
+
+const (
+	TierMember   LoyaltyTier = "member"
+	TierSilver   LoyaltyTier = "silver"
+	TierGold     LoyaltyTier = "gold"
+	TierPlatinum LoyaltyTier = "platinum"
+)
+
+// TierRule is reached with either the nights or the room spend of the
+// qualifying period. Points earned are multiplied by EarnMultiplier.
+type TierRule struct {
+	Tier           LoyaltyTier
+	MinNights      int
+	MinSpend       float64
+	EarnMultiplier float64
+}
+
+// LoyaltyTiers is ordered from the lowest tier to the highest.
+var LoyaltyTiers = []TierRule{
+	{TierMember, 0, 0, 1},
+	{TierSilver, 10, 2000, 1.25},
+	{TierGold, 25, 6000, 1.5},
+	{TierPlatinum, 50, 15000, 2},
+}
+
+const (
+	// PointsPerUnit are the base points earned per unit of room revenue.
+	PointsPerUnit = 10
+	// PointValue is what one point is worth when redeemed against a bill.
+	PointValue = 0.005
+	// MinRedemption is the smallest number of points that can be redeemed.
+	MinRedemption = 500
+)
+
+// Qualifying period for tiers and validity of earned points, in months.
+const (
+	TierQualifyingMonths = 12
+	PointsValidityMonths = 24
+)
+
+// TierRuleFor returns the rule of the tier, or the member rule if unknown.
+func TierRuleFor(tier LoyaltyTier) TierRule {
+	for _, rule := range LoyaltyTiers {
+		if rule.Tier == tier {
+			return rule
+		}
+	}
+	return LoyaltyTiers[0]
+}
+
+// QualifiedTier is the highest tier reached with the given nights or spend.
+func QualifiedTier(nights int, spend float64) LoyaltyTier {
+	tier := LoyaltyTiers[0].Tier
+	for _, rule := range LoyaltyTiers {
+		if nights >= rule.MinNights || spend >= rule.MinSpend {
+			tier = rule.Tier
+		}
+	}
+	return tier
+}
+
+// TierRank orders tiers, higher is better.
+func TierRank(tier LoyaltyTier) int {
+	for i, rule := range LoyaltyTiers {
+		if rule.Tier == tier {
+			return i
+		}
+	}
+	return 0
+}
+
+// LoyaltyAccount enrols a guest in the loyalty program. The points balance is
+// not stored here, it is the sum of the account's ledger.
+type LoyaltyAccount struct {
+	ID           uint        `gorm:"primaryKey"`
+	GuestID      uint        `gorm:"uniqueIndex"`
+	MemberNumber string      `gorm:"type:varchar(20);uniqueIndex"`
+	Tier         LoyaltyTier `gorm:"type:varchar(20)"`
+
+	// TierReachedAt is when the current tier was last qualified for. A tier
+	// is kept for a full qualifying period even if activity drops.
+	TierReachedAt time.Time
+
+	CreatedAt time.Time
+}
+
+type LoyaltyEntryType string
+
+const (
+	LoyaltyEarn   LoyaltyEntryType = "earn"
+	LoyaltyRedeem LoyaltyEntryType = "redeem"
+	LoyaltyExpire LoyaltyEntryType = "expire"
+)
+
+// LoyaltyEntry is one line of a guest's points ledger. Earned points are
+// positive, redeemed and expired points negative. Redemptions and expiries
+// draw down earnings oldest first, tracked in Remaining.
+type LoyaltyEntry struct {
+	ID      uint             `gorm:"primaryKey"`
+	GuestID uint             `gorm:"index"`
+	Type    LoyaltyEntryType `gorm:"type:varchar(10)"`
+	Points  int
+
+	// Remaining is the part of an earning not yet redeemed or expired.
+	Remaining int
+	ExpiresAt *time.Time
+
+	// BookingID is the stay points were earned on or redeemed against.
+	BookingID     *string `gorm:"type:char(26);index"`
+	TransactionID *uint
+
+	Description string
+	CreatedAt   time.Time
+}
+
+// IsExpired reports whether the unused part of an earning has lapsed on today.
+func (e *LoyaltyEntry) IsExpired(today time.Time) bool {
+	return e.ExpiresAt != nil && !e.ExpiresAt.After(today)
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+// SchemaMigration marks a one-time data migration as applied.
+type SchemaMigration struct {
+	Name      string `gorm:"primaryKey;size:64"`
+	AppliedAt time.Time
+}
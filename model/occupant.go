@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// Define a custom type for the age category of an occupant.
+type AgeCategory string
+
+const (
+	AgeAdult  AgeCategory = "adult"
+	AgeChild  AgeCategory = "child"
+	AgeInfant AgeCategory = "infant"
+)
+
+// BookingOccupant is a companion sharing the room with the booking's main guest.
+// It either links an existing guest profile or carries its own identity data.
+type BookingOccupant struct {
+	ID        uint   `gorm:"primaryKey"`
+	BookingID string `gorm:"type:char(26);index;not null"`
+
+	// GuestID is set when the occupant already has a guest profile.
+	GuestID *uint
+	Guest   *Guest
+
+	// Lightweight identity data, used when GuestID is not set.
+	FullName       string
+	CredentialType string
+	IDNumber       string `gorm:"type:text;serializer:encrypted"`
+	IssuingCountry string `gorm:"type:char(2)"`
+	DocumentExpiry *time.Time
+
+	AgeCategory AgeCategory `gorm:"type:varchar(10);not null"`
+
+	CreatedAt time.Time
+}
+
+// HasIdentity reports whether the occupant carries ID data usable for police registration.
+func (o *BookingOccupant) HasIdentity() bool {
+	if o.Guest != nil {
+		return o.Guest.CredentialType != "" && o.Guest.IDNumber != ""
+	}
+	return o.CredentialType != "" && o.IDNumber != ""
+}
+
+// IdentityExpiry returns the expiry of the identity document on file.
+func (o *BookingOccupant) IdentityExpiry() *time.Time {
+	if o.Guest != nil {
+		return o.Guest.DocumentExpiry
+	}
+	return o.DocumentExpiry
+}
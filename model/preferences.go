@@ -0,0 +1,130 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PillowType string
+
+const (
+	PillowSoft           PillowType = "soft"
+	PillowFirm           PillowType = "firm"
+	PillowFeather        PillowType = "feather"
+	PillowHypoallergenic PillowType = "hypoallergenic"
+)
+
+type FloorPreference string
+
+const (
+	FloorLow  FloorPreference = "low"
+	FloorHigh FloorPreference = "high"
+)
+
+// Accessibility needs. Mobility and wheelchair needs require an accessible room.
+const (
+	AccessWheelchair = "wheelchair"
+	AccessMobility   = "mobility"
+	AccessHearing    = "hearing"
+	AccessVisual     = "visual"
+)
+
+// GuestPreferences are kept on the guest profile and apply to every stay.
+// They are stored as JSON, so the tags below are also the column format.
+type GuestPreferences struct {
+	PillowType      PillowType      `json:"pillow_type,omitempty"`
+	FloorPreference FloorPreference `json:"floor_preference,omitempty"`
+	Dietary         []string        `json:"dietary,omitempty"`
+	Allergies       []string        `json:"allergies,omitempty"`
+	Accessibility   []string        `json:"accessibility,omitempty"`
+}
+
+func (p GuestPreferences) Validate() error {
+	switch p.PillowType {
+	case "", PillowSoft, PillowFirm, PillowFeather, PillowHypoallergenic:
+	default:
+		return fmt.Errorf("invalid pillow type %q", p.PillowType)
+	}
+	switch p.FloorPreference {
+	case "", FloorLow, FloorHigh:
+	default:
+		return fmt.Errorf("invalid floor preference %q, must be 'low' or 'high'", p.FloorPreference)
+	}
+	for _, need := range p.Accessibility {
+		switch need {
+		case AccessWheelchair, AccessMobility, AccessHearing, AccessVisual:
+		default:
+			return fmt.Errorf("invalid accessibility need %q", need)
+		}
+	}
+	return nil
+}
+
+// Normalize lower-cases and de-duplicates the list values.
+func (p GuestPreferences) Normalize() GuestPreferences {
+	p.PillowType = PillowType(strings.ToLower(strings.TrimSpace(string(p.PillowType))))
+	p.FloorPreference = FloorPreference(strings.ToLower(strings.TrimSpace(string(p.FloorPreference))))
+	p.Dietary = normalizeList(p.Dietary)
+	p.Allergies = normalizeList(p.Allergies)
+	p.Accessibility = normalizeList(p.Accessibility)
+	return p
+}
+
+// NeedsAccessibleRoom reports whether only accessible rooms are suitable.
+func (p GuestPreferences) NeedsAccessibleRoom() bool {
+	for _, need := range p.Accessibility {
+		if need == AccessWheelchair || need == AccessMobility {
+			return true
+		}
+	}
+	return false
+}
+
+type SpecialRequestType string
+
+const (
+	RequestEarlyCheckIn   SpecialRequestType = "early_check_in"
+	RequestLateCheckOut   SpecialRequestType = "late_check_out"
+	RequestCrib           SpecialRequestType = "crib"
+	RequestExtraPillows   SpecialRequestType = "extra_pillows"
+	RequestQuietRoom      SpecialRequestType = "quiet_room"
+	RequestAdjoiningRooms SpecialRequestType = "adjoining_rooms"
+	RequestAirportPickup  SpecialRequestType = "airport_pickup"
+	RequestCelebration    SpecialRequestType = "celebration"
+	RequestOther          SpecialRequestType = "other"
+)
+
+// SpecialRequest is a one-off request for a single booking.
+type SpecialRequest struct {
+	Type   SpecialRequestType `json:"type"`
+	Detail string             `json:"detail,omitempty"`
+}
+
+func (r SpecialRequest) Validate() error {
+	switch r.Type {
+	case RequestEarlyCheckIn, RequestLateCheckOut, RequestCrib, RequestExtraPillows, RequestQuietRoom,
+		RequestAdjoiningRooms, RequestAirportPickup, RequestCelebration:
+		return nil
+	case RequestOther:
+		if strings.TrimSpace(r.Detail) == "" {
+			return fmt.Errorf("special request of type 'other' needs a detail")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid special request type %q", r.Type)
+	}
+}
+
+func normalizeList(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package model
+
+// Principal is the authenticated caller of a request: either a signed-in
+// staff user with a session, or an API key.
+type Principal struct {
+	StaffUser *StaffUser
+	Session   *AuthSession
+	APIKey    *APIKey
+
+	// Job names a background task acting on its own, e.g. "deposit-expiry".
+	Job string
+}
+
+// JobPrincipal is the caller recorded for changes made by a background task.
+func JobPrincipal(name string) *Principal {
+	return &Principal{Job: name}
+}
+
+// Can reports whether the caller is granted the permission.
+func (p *Principal) Can(perm Permission) bool {
+	switch {
+	case p == nil:
+		return false
+	case p.APIKey != nil:
+		return p.APIKey.Can(perm)
+	case p.StaffUser != nil:
+		return p.StaffUser.Role.Can(perm)
+	default:
+		return false
+	}
+}
+
+// Name describes the caller, e.g. "staff:jdoe" or "api_key:kiosk-lobby".
+func (p *Principal) Name() string {
+	switch {
+	case p == nil:
+		return "anonymous"
+	case p.APIKey != nil:
+		return "api_key:" + p.APIKey.Name
+	case p.StaffUser != nil:
+		return "staff:" + p.StaffUser.Username
+	case p.Job != "":
+		return "system:" + p.Job
+	default:
+		return "anonymous"
+	}
+}
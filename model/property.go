@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Property is a single hotel of the group. Rooms, room types and bookings
+// belong to one property, while guest profiles are shared across all of them.
+type Property struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Code is a short unique handle, e.g. "JKT01".
+	Code    string `gorm:"unique;not null;type:varchar(20)"`
+	Name    string `gorm:"not null"`
+	Address string `gorm:"type:text"`
+
+	// --- Settings ---
+
+	// Timezone is an IANA location name such as "Asia/Jakarta".
+	Timezone string `gorm:"not null;type:varchar(64);default:UTC"`
+
+	// Currency is an ISO 4217 code such as "IDR".
+	Currency string `gorm:"not null;type:char(3);default:USD"`
+
+	// Check-in and check-out times use the "15:04" layout, in the property timezone.
+	CheckInTime  string `gorm:"not null;type:varchar(5);default:14:00"`
+	CheckOutTime string `gorm:"not null;type:varchar(5);default:12:00"`
+
+	// Room prices include tax at TaxRate percent; invoices show the split.
+	// TaxNumber is the property's tax registration, printed on invoices.
+	TaxName   string  `gorm:"not null;type:varchar(30);default:Tax"`
+	TaxRate   float64 `gorm:"not null;default:0"`
+	TaxNumber string  `gorm:"type:varchar(50)"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DefaultPropertyCode identifies the property that owns data created before
+// multi-property support existed.
+const DefaultPropertyCode = "DEFAULT"
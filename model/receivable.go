@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+type ReceivableEntryType string
+
+const (
+	ReceivableInvoice    ReceivableEntryType = "invoice"
+	ReceivablePayment    ReceivableEntryType = "payment"
+	ReceivableCreditNote ReceivableEntryType = "credit_note"
+)
+
+// ReceivableEntry is one line of a billing account's city ledger. Amounts
+// are always positive: invoices raise what the account owes, payments and
+// credit notes lower it through their allocations to invoices.
+type ReceivableEntry struct {
+	ID        uint                `gorm:"primaryKey"`
+	AccountID uint                `gorm:"index"`
+	Type      ReceivableEntryType `gorm:"type:varchar(20)"`
+	Amount    float64
+
+	// PostedOn is the calendar date of the entry, used for statements and aging.
+	PostedOn time.Time
+
+	// TransactionID is the unpaid booking transaction an invoice was raised
+	// for; each transaction is invoiced once.
+	TransactionID *uint   `gorm:"uniqueIndex"`
+	BookingID     *string `gorm:"type:char(26)"`
+
+	// Settled is the part of an invoice covered by payments and credit notes.
+	Settled float64
+
+	PaymentMethod string
+	Reference     string
+	Notes         string `gorm:"type:text"`
+
+	Allocations []ReceivableAllocation `gorm:"foreignKey:EntryID"`
+
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// Open is what is still owed on an invoice.
+func (e *ReceivableEntry) Open() float64 {
+	if e.Type != ReceivableInvoice {
+		return 0
+	}
+	return e.Amount - e.Settled
+}
+
+// IsSettled allows for rounding left over from the float amounts.
+func (e *ReceivableEntry) IsSettled() bool {
+	return e.Open() < 0.005
+}
+
+// ReceivableAllocation applies part of a payment or credit note to an invoice.
+type ReceivableAllocation struct {
+	ID        uint `gorm:"primaryKey"`
+	EntryID   uint `gorm:"index"`
+	InvoiceID uint `gorm:"index"`
+	Amount    float64
+	CreatedAt time.Time
+}
+
+// Aging buckets, by days since the invoice was posted.
+const (
+	AgingCurrent = "current"
+	Aging30      = "30"
+	Aging60      = "60"
+	Aging90Plus  = "90+"
+)
+
+// AgingBucket returns the bucket of an invoice posted days ago.
+func AgingBucket(days int) string {
+	switch {
+	case days <= 30:
+		return AgingCurrent
+	case days <= 60:
+		return Aging30
+	case days <= 90:
+		return Aging60
+	default:
+		return Aging90Plus
+	}
+}
@@ -0,0 +1,146 @@
+package model
+
+// Define a custom type for staff roles for type safety.
+type Role string
+
+const (
+	RoleAdmin        Role = "admin"
+	RoleFrontDesk    Role = "front_desk"
+	RoleHousekeeping Role = "housekeeping"
+	RoleRevenue      Role = "revenue"
+	RoleNightAuditor Role = "night_auditor"
+)
+
+// Permission is a single capability checked by routes and response masking.
+type Permission string
+
+const (
+	PermPropertyRead   Permission = "property:read"
+	PermPropertyManage Permission = "property:manage"
+
+	PermRoomRead       Permission = "room:read"
+	PermRoomManage     Permission = "room:manage"
+	PermRoomStatus     Permission = "room:status"
+	PermRoomTypeManage Permission = "room_type:manage"
+
+	PermGuestRead   Permission = "guest:read"
+	PermGuestWrite  Permission = "guest:write"
+	PermGuestDelete Permission = "guest:delete"
+
+	// PermGuestPII allows reading identity document numbers unmasked.
+	PermGuestPII Permission = "guest:pii"
+
+	PermGuestFlagRead   Permission = "guest_flag:read"
+	PermGuestFlagManage Permission = "guest_flag:manage"
+
+	PermBookingRead    Permission = "booking:read"
+	PermBookingWrite   Permission = "booking:write"
+	PermBookingCheckIn Permission = "booking:check_in"
+
+	PermTransactionRead  Permission = "transaction:read"
+	PermTransactionWrite Permission = "transaction:write"
+
+	// PermTransactionRefund allows giving money back from a payment.
+	PermTransactionRefund Permission = "transaction:refund"
+
+	PermLoyaltyRead   Permission = "loyalty:read"
+	PermLoyaltyManage Permission = "loyalty:manage"
+
+	PermCorporateRead   Permission = "corporate:read"
+	PermCorporateManage Permission = "corporate:manage"
+
+	// Accounts receivable (city ledger) of corporate and travel agent accounts.
+	PermReceivableRead   Permission = "receivable:read"
+	PermReceivableManage Permission = "receivable:manage"
+
+	PermAuditRead Permission = "audit:read"
+
+	PermStaffManage  Permission = "staff:manage"
+	PermAPIKeyManage Permission = "api_key:manage"
+)
+
+// AllPermissions lists every known permission.
+var AllPermissions = []Permission{
+	PermPropertyRead, PermPropertyManage,
+	PermRoomRead, PermRoomManage, PermRoomStatus, PermRoomTypeManage,
+	PermGuestRead, PermGuestWrite, PermGuestDelete, PermGuestPII,
+	PermGuestFlagRead, PermGuestFlagManage,
+	PermBookingRead, PermBookingWrite, PermBookingCheckIn,
+	PermTransactionRead, PermTransactionWrite, PermTransactionRefund,
+	PermLoyaltyRead, PermLoyaltyManage,
+	PermCorporateRead, PermCorporateManage,
+	PermReceivableRead, PermReceivableManage,
+	PermAuditRead,
+	PermStaffManage, PermAPIKeyManage,
+}
+
+// IsValid reports whether p is one of the known permissions.
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions {
+		if known == p {
+			return true
+		}
+	}
+	return false
+}
+
+// rolePermissions is the permission matrix. Admins are granted everything.
+var rolePermissions = map[Role][]Permission{
+	RoleFrontDesk: {
+		PermPropertyRead,
+		PermRoomRead, PermRoomStatus,
+		PermGuestRead, PermGuestWrite, PermGuestPII, PermGuestFlagRead,
+		PermBookingRead, PermBookingWrite, PermBookingCheckIn,
+		PermTransactionRead, PermTransactionWrite,
+		PermLoyaltyRead, PermLoyaltyManage,
+		PermCorporateRead,
+	},
+	RoleHousekeeping: {
+		PermPropertyRead,
+		PermRoomRead, PermRoomStatus,
+		PermBookingRead,
+	},
+	RoleRevenue: {
+		PermPropertyRead,
+		PermRoomRead, PermRoomTypeManage,
+		PermGuestRead,
+		PermBookingRead,
+		PermTransactionRead,
+		PermLoyaltyRead,
+		PermCorporateRead, PermCorporateManage,
+		PermReceivableRead, PermReceivableManage,
+	},
+	RoleNightAuditor: {
+		PermPropertyRead,
+		PermRoomRead, PermRoomStatus,
+		PermGuestRead, PermGuestPII, PermGuestFlagRead, PermGuestFlagManage,
+		PermBookingRead, PermBookingWrite, PermBookingCheckIn,
+		PermTransactionRead, PermTransactionWrite, PermTransactionRefund,
+		PermLoyaltyRead, PermLoyaltyManage,
+		PermCorporateRead,
+		PermReceivableRead, PermReceivableManage,
+		PermAuditRead,
+	},
+}
+
+// IsValid reports whether r is one of the known roles.
+func (r Role) IsValid() bool {
+	if r == RoleAdmin {
+		return true
+	}
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role is granted the permission.
+func (r Role) Can(p Permission) bool {
+	if r == RoleAdmin {
+		return true
+	}
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
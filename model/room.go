@@ -15,25 +15,66 @@ const (
 	StatusMaintenance RoomStatus = "maintenance"
 )
 
+// --- Enum-like type for Bed Configuration ---
+
+type BedConfiguration string
+
+const (
+	BedKing   BedConfiguration = "king"
+	BedQueen  BedConfiguration = "queen"
+	BedDouble BedConfiguration = "double"
+	BedTwin   BedConfiguration = "twin"
+	BedSingle BedConfiguration = "single"
+)
+
 // --- Room Model ---
 
 type Room struct {
 	ID         uint `gorm:"primaryKey"`
 	RoomTypeID uint
-	Number     string `gorm:"unique;not null"`
+
+	// Room numbers are only unique within a property.
+	PropertyID uint   `gorm:"not null;uniqueIndex:idx_rooms_property_number,priority:1"`
+	Number     string `gorm:"not null;size:191;uniqueIndex:idx_rooms_property_number,priority:2"`
 
 	// Use the custom RoomStatus type for type safety.
 	// GORM will store its string value (e.g., "available") in the database.
 	Status RoomStatus `gorm:"not null;default:available"`
 
+	// --- Physical Attributes ---
+
+	Floor    int
+	Building string `gorm:"type:varchar(50)"`
+
+	// View is a free-form label such as "sea", "city" or "garden".
+	View             string           `gorm:"type:varchar(30)"`
+	BedConfiguration BedConfiguration `gorm:"type:varchar(20)"`
+	Smoking          bool             `gorm:"not null;default:false"`
+	Accessible       bool             `gorm:"not null;default:false"`
+
 	// Define the relationship for GORM Preload.
 	RoomType RoomType
 
+	// Amenities are shared records, linked through the room_amenities join table.
+	Amenities []Amenity `gorm:"many2many:room_amenities"`
+
+	// ConnectingRooms is a self-referencing relation; each connection is stored in both directions.
+	ConnectingRooms []*Room `gorm:"many2many:room_connections;joinForeignKey:RoomID;joinReferences:ConnectingRoomID"`
+
 	// Automatic timestamps for auditing.
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// --- Amenity Model ---
+
+type Amenity struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Name is the lookup key used by filters, e.g. "minibar" or "bathtub".
+	Name string `gorm:"unique;not null;type:varchar(50)"`
+}
+
 // --- RoomType Model ---
 
 type RoomType struct {
@@ -41,9 +82,42 @@ type RoomType struct {
 	Description string  `gorm:"type:text"`
 	Price       float64 `gorm:"not null"`
 
-	// Name should be unique to prevent duplicate room types.
-	Name string `gorm:"unique;not null"`
+	// Name should be unique within a property to prevent duplicate room types.
+	PropertyID uint   `gorm:"not null;uniqueIndex:idx_room_types_property_name,priority:1"`
+	Name       string `gorm:"not null;size:191;uniqueIndex:idx_room_types_property_name,priority:2"`
 
 	// Capacity should be an unsigned integer.
+	// It is the number of adults and children the room sleeps without extra beds.
 	Capacity uint `gorm:"not null"`
+
+	// --- Occupancy Rules ---
+
+	// BaseOccupancy is the number of guests included in Price; 0 means the full Capacity.
+	BaseOccupancy uint `gorm:"not null;default:0"`
+
+	// Each extra bed raises the capacity by one guest and is charged per night.
+	MaxExtraBeds  uint    `gorm:"not null;default:0"`
+	ExtraBedPrice float64 `gorm:"not null;default:0"`
+
+	// Nightly supplements for each guest above BaseOccupancy.
+	ExtraAdultPrice float64 `gorm:"not null;default:0"`
+	ChildPrice      float64 `gorm:"not null;default:0"`
+
+	// --- Deposit Policy ---
+
+	// DepositPercent of the stay is due within DepositDueDays of booking,
+	// otherwise the booking is cancelled; 0 means no deposit is taken.
+	DepositPercent float64 `gorm:"not null;default:0"`
+	DepositDueDays uint    `gorm:"not null;default:0"`
+
+	// BalanceDueDays before arrival the rest of the stay is due; 0 means it
+	// is paid at the hotel.
+	BalanceDueDays uint `gorm:"not null;default:0"`
+
+	// Retired room types keep their history but cannot be used for new rooms or bookings.
+	Retired   bool `gorm:"not null;default:false"`
+	RetiredAt *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
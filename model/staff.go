@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// StaffUser is a hotel employee who can sign in to the API.
+type StaffUser struct {
+	ID       uint   `gorm:"primaryKey"`
+	Username string `gorm:"unique;not null;type:varchar(50)"`
+	FullName string `gorm:"not null"`
+
+	// Role decides which routes and fields the user can access.
+	Role Role `gorm:"type:varchar(20);not null;default:front_desk"`
+
+	// PasswordHash is a bcrypt hash; the plain password is never stored.
+	PasswordHash string `gorm:"not null"`
+
+	// Inactive staff cannot sign in and their sessions stop working.
+	Active bool `gorm:"not null;default:true"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// AuthSession is one signed-in device of a staff user. Access tokens carry
+// the session ID, so revoking the session also rejects its access tokens.
+type AuthSession struct {
+	ID          string `gorm:"primaryKey;type:char(26)"`
+	StaffUserID uint   `gorm:"index;not null"`
+	StaffUser   *StaffUser
+
+	// RefreshTokenHash is the SHA-256 of the refresh token, which rotates on every refresh.
+	RefreshTokenHash string `gorm:"unique;not null;type:char(64)"`
+	ExpiresAt        time.Time
+	RevokedAt        *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RetiredRefreshToken is a refresh token that has been rotated. Presenting
+// it again means it was copied, so its session is revoked.
+type RetiredRefreshToken struct {
+	TokenHash string `gorm:"primaryKey;type:char(64)"`
+	SessionID string `gorm:"index;not null;type:char(26)"`
+	RetiredAt time.Time
+}
@@ -2,12 +2,82 @@ package model
 
 import "time"
 
+// PaymentMethodLoyalty marks a payment made by redeeming loyalty points.
+const PaymentMethodLoyalty = "loyalty_points"
+
+// PaymentMethodCard marks a card payment taken through the payment gateway.
+const PaymentMethodCard = "card"
+
+// GatewayStatus is the state of a card payment or refund at the payment
+// gateway.
+type GatewayStatus string
+
+const (
+	GatewayPending    GatewayStatus = "pending"
+	GatewayAuthorized GatewayStatus = "authorized"
+	GatewayCaptured   GatewayStatus = "captured"
+	GatewayVoided     GatewayStatus = "voided"
+	GatewayRefunded   GatewayStatus = "refunded"
+	GatewayFailed     GatewayStatus = "failed"
+)
+
+// TransactionType tells money received from money given back.
+type TransactionType string
+
+const (
+	TransactionPayment TransactionType = "payment"
+	TransactionRefund  TransactionType = "refund"
+)
+
 type Transaction struct {
-	Id            uint `gorm:"primaryKey"`
-	BookingID     uint
-	Booking       Booking
+	Id            uint   `gorm:"primaryKey"`
+	BookingID     string `gorm:"type:char(26);index"`
+	Booking       *Booking
+	Type          TransactionType `gorm:"type:varchar(20);not null;default:payment"`
 	Amount        float64
 	PaymentMethod string
 	Paid          bool
-	CreatedAt     time.Time
+
+	// CorporateAccountID is the company a direct-billed charge is owed by.
+	CorporateAccountID *uint `gorm:"index"`
+
+	// RefundOfID is the payment a refund gives money back from. Refunds keep
+	// a positive Amount and the payment method of that payment.
+	RefundOfID *uint `gorm:"index"`
+	Reason     string
+
+	// GatewayID is the gateway's ID of a card payment or refund, and
+	// GatewayStatus its last known state there. Card payments are only paid
+	// once captured.
+	GatewayID     string        `gorm:"type:varchar(64);index"`
+	GatewayStatus GatewayStatus `gorm:"type:varchar(20)"`
+	CardBrand     string        `gorm:"type:varchar(20)"`
+	CardLast4     string        `gorm:"type:char(4)"`
+
+	CreatedAt time.Time
+}
+
+// ViaGateway reports whether the transaction is settled through the payment
+// gateway rather than by hand. Card payments and refunds are recorded as
+// pending before the gateway is called, so they count before they have a
+// GatewayID.
+func (t *Transaction) ViaGateway() bool {
+	return t.GatewayID != "" || t.GatewayStatus != ""
+}
+
+func (t *Transaction) IsRefund() bool {
+	return t.Type == TransactionRefund
+}
+
+// Received is what the transaction adds to the money held for the booking:
+// the amount of a paid payment, less the amount of a refund.
+func (t *Transaction) Received() float64 {
+	switch {
+	case !t.Paid:
+		return 0
+	case t.IsRefund():
+		return -t.Amount
+	default:
+		return t.Amount
+	}
 }
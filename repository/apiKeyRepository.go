@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"hms-backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(k *model.APIKey) error
+	FindAll() ([]*model.APIKey, error)
+	FindByID(id uint) (*model.APIKey, error)
+	FindByPrefix(prefix string) (*model.APIKey, error)
+	Revoke(id uint, at time.Time) error
+	RecordUsage(id uint, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) Create(k *model.APIKey) error {
+	return r.db.Create(k).Error
+}
+
+func (r *apiKeyRepository) FindAll() ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	err := r.db.Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *apiKeyRepository) FindByID(id uint) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("id = ?", id).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByPrefix(prefix string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Revoke(id uint, at time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at).Error
+}
+
+// RecordUsage bumps the counter in SQL so concurrent requests do not lose updates.
+func (r *apiKeyRepository) RecordUsage(id uint, at time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", id).Updates(map[string]any{
+		"usage_count":  gorm.Expr("usage_count + 1"),
+		"last_used_at": at,
+	}).Error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"encoding/json"
+	"hms-backend/model"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository only appends and reads; entries are never deleted, and
+// only RedactChanges may rewrite them, to honour erasure requests.
+type AuditRepository interface {
+	Create(a *model.AuditLog) error
+	FindByEntity(entityType, entityID string) ([]*model.AuditLog, error)
+	RedactChanges(id uint, changes map[string]model.FieldChange) error
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db}
+}
+
+func (r *auditRepository) Create(a *model.AuditLog) error {
+	return r.db.Create(a).Error
+}
+
+func (r *auditRepository) FindByEntity(entityType, entityID string) ([]*model.AuditLog, error) {
+	var logs []*model.AuditLog
+	err := r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("created_at, id").Find(&logs).Error
+	return logs, err
+}
+
+// RedactChanges overwrites the recorded changes of an entry. It writes to the
+// table directly, past the hooks that keep the model append-only.
+func (r *auditRepository) RedactChanges(id uint, changes map[string]model.FieldChange) error {
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	return r.db.Table("audit_logs").Where("id = ?", id).Update("changes", string(raw)).Error
+}
@@ -12,8 +12,14 @@ type BookingRepository interface {
 	Update(b *model.Booking) error
 	FindByID(s string) (*model.Booking, error)
 	FindByReferenceID(s string) (*model.Booking, error)
-	FindForDateRange(start, end time.Time) ([]*model.Booking, error)
+	FindForDateRange(propertyID uint, start, end time.Time) ([]*model.Booking, error)
 	FindByGuestID(guestID uint) ([]*model.Booking, error)
+	FindByGuestIDs(guestIDs []uint) ([]*model.Booking, error)
+	FindArrivals(propertyID uint, date time.Time) ([]*model.Booking, error)
+	FindPendingDeposits(dueBefore time.Time) ([]*model.Booking, error)
+	AddOccupant(o *model.BookingOccupant) error
+	FindOccupantByID(id uint) (*model.BookingOccupant, error)
+	DeleteOccupant(id uint) error
 }
 
 type bookingRepository struct {
@@ -28,7 +34,8 @@ func (r *bookingRepository) Create(b *model.Booking) error {
 	return r.db.Create(b).Error
 }
 func (r *bookingRepository) Update(b *model.Booking) error {
-	return r.db.Save(b).Error
+	// Occupants are managed through their own methods.
+	return r.db.Omit("Occupants").Save(b).Error
 }
 func (r *bookingRepository) FindByID(s string) (*model.Booking, error) {
 	var booking model.Booking
@@ -40,20 +47,72 @@ func (r *bookingRepository) FindByID(s string) (*model.Booking, error) {
 }
 func (r *bookingRepository) FindByReferenceID(s string) (*model.Booking, error) {
 	var booking model.Booking
-	err := r.db.Preload("Room.RoomType").Preload("Guest").Where("booking_reference = ?", s).First(&booking).Error
+	err := r.db.Preload("Room.RoomType").Preload("Guest").Preload("Occupants.Guest").Where("booking_reference = ?", s).First(&booking).Error
 	if err != nil {
 		return nil, err
 	}
 	return &booking, err
 }
-func (r *bookingRepository) FindForDateRange(start, end time.Time) ([]*model.Booking, error) {
+func (r *bookingRepository) FindForDateRange(propertyID uint, start, end time.Time) ([]*model.Booking, error) {
 	var bookings []*model.Booking
-	err := r.db.Preload("Room.RoomType").Preload("Guest").Where("check_in_date < ? AND check_out_date > ?", end, start).
-		Find(&bookings).Error
+	query := r.db.Preload("Room.RoomType").Preload("Guest").Where("check_in_date < ? AND check_out_date > ?", end, start)
+	if propertyID != 0 {
+		query = query.Where("property_id = ?", propertyID)
+	}
+	err := query.Find(&bookings).Error
 	return bookings, err
 }
 func (r *bookingRepository) FindByGuestID(guestID uint) ([]*model.Booking, error) {
 	var bookings []*model.Booking
-	err := r.db.Preload("Room.RoomType").Where("guest_id = ?", guestID).Find(&bookings).Error
+	err := r.db.Preload("Room.RoomType").Preload("Guest").Where("guest_id = ?", guestID).
+		Order("check_in_date DESC").Find(&bookings).Error
+	return bookings, err
+}
+
+// FindArrivals returns the bookings due to check in on date that have not arrived yet.
+func (r *bookingRepository) FindArrivals(propertyID uint, date time.Time) ([]*model.Booking, error) {
+	var bookings []*model.Booking
+	err := r.db.Preload("Room.RoomType").Preload("Guest").Preload("Occupants.Guest").
+		Where("property_id = ? AND check_in_date = ? AND status IN ?", propertyID, date,
+			[]model.BookingStatus{model.StatusPending, model.StatusConfirmed}).
+		Order("room_id").Find(&bookings).Error
+	return bookings, err
+}
+
+// FindByGuestIDs returns the bookings of several guests, latest stay first.
+func (r *bookingRepository) FindByGuestIDs(guestIDs []uint) ([]*model.Booking, error) {
+	var bookings []*model.Booking
+	if len(guestIDs) == 0 {
+		return bookings, nil
+	}
+	err := r.db.Preload("Room").Where("guest_id IN ?", guestIDs).
+		Order("check_in_date DESC").Find(&bookings).Error
 	return bookings, err
 }
+
+// FindPendingDeposits returns the pending bookings whose deposit was due
+// before dueBefore.
+func (r *bookingRepository) FindPendingDeposits(dueBefore time.Time) ([]*model.Booking, error) {
+	var bookings []*model.Booking
+	err := r.db.Preload("Room.RoomType").Preload("Guest").
+		Where("status = ? AND deposit_due_date < ?", model.StatusPending, dueBefore).
+		Order("deposit_due_date").Find(&bookings).Error
+	return bookings, err
+}
+
+func (r *bookingRepository) AddOccupant(o *model.BookingOccupant) error {
+	return r.db.Create(o).Error
+}
+
+func (r *bookingRepository) FindOccupantByID(id uint) (*model.BookingOccupant, error) {
+	var occupant model.BookingOccupant
+	err := r.db.Preload("Guest").Where("id = ?", id).First(&occupant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &occupant, nil
+}
+
+func (r *bookingRepository) DeleteOccupant(id uint) error {
+	return r.db.Delete(&model.BookingOccupant{}, id).Error
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"hms-backend/model"
+
+	"gorm.io/gorm"
+)
+
+type CorporateRepository interface {
+	Create(a *model.CorporateAccount) error
+	Update(a *model.CorporateAccount) error
+	FindAll() ([]*model.CorporateAccount, error)
+	FindByID(id uint) (*model.CorporateAccount, error)
+	FindByCode(code string) (*model.CorporateAccount, error)
+	AddGuest(g *model.CorporateGuest) error
+	RemoveGuest(accountID, guestID uint) error
+	UnpaidBalance(accountID uint) (float64, error)
+	FindOpenDirectBills(accountID uint) ([]*model.Booking, error)
+}
+
+type corporateRepository struct {
+	db *gorm.DB
+}
+
+func NewCorporateRepository(db *gorm.DB) CorporateRepository {
+	return &corporateRepository{db}
+}
+
+func (r *corporateRepository) Create(a *model.CorporateAccount) error {
+	return r.db.Omit("Guests").Create(a).Error
+}
+
+func (r *corporateRepository) Update(a *model.CorporateAccount) error {
+	return r.db.Omit("Guests").Save(a).Error
+}
+
+func (r *corporateRepository) FindAll() ([]*model.CorporateAccount, error) {
+	var accounts []*model.CorporateAccount
+	err := r.db.Order("name").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *corporateRepository) FindByID(id uint) (*model.CorporateAccount, error) {
+	var account model.CorporateAccount
+	err := r.db.Preload("Guests.Guest").Where("id = ?", id).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *corporateRepository) FindByCode(code string) (*model.CorporateAccount, error) {
+	var account model.CorporateAccount
+	err := r.db.Where("code = ?", code).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *corporateRepository) AddGuest(g *model.CorporateGuest) error {
+	return r.db.Omit("Guest").Create(g).Error
+}
+
+func (r *corporateRepository) RemoveGuest(accountID, guestID uint) error {
+	return r.db.Where("account_id = ? AND guest_id = ?", accountID, guestID).Delete(&model.CorporateGuest{}).Error
+}
+
+// UnpaidBalance is what the company owes for stays already billed to it,
+// less what has been settled on their invoices.
+func (r *corporateRepository) UnpaidBalance(accountID uint) (float64, error) {
+	var balance float64
+	err := r.db.Model(&model.Transaction{}).
+		Joins("LEFT JOIN receivable_entries ON receivable_entries.transaction_id = transactions.id").
+		Where("transactions.corporate_account_id = ? AND transactions.paid = ?", accountID, false).
+		Select("COALESCE(SUM(transactions.amount - COALESCE(receivable_entries.settled, 0)), 0)").
+		Scan(&balance).Error
+	return balance, err
+}
+
+// FindOpenDirectBills returns the company's direct-billed bookings that have
+// not been checked out yet, so are not billed yet either.
+func (r *corporateRepository) FindOpenDirectBills(accountID uint) ([]*model.Booking, error) {
+	var bookings []*model.Booking
+	err := r.db.Where("corporate_account_id = ? AND direct_bill = ? AND status IN ?", accountID, true,
+		[]model.BookingStatus{model.StatusPending, model.StatusConfirmed, model.StatusCheckedIn}).
+		Find(&bookings).Error
+	return bookings, err
+}
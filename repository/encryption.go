@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Keyring encrypts sensitive columns with AES-256-GCM. Every ciphertext is
+// prefixed with the id of the key that produced it ("v2:<base64>"), so values
+// written with an older key stay readable after a new key becomes active.
+type Keyring struct {
+	keys     map[uint32]cipher.AEAD
+	activeID uint32
+	indexKey []byte
+}
+
+func NewKeyring(keys map[uint32][]byte, activeID uint32, indexKey []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[uint32]cipher.AEAD), activeID: activeID, indexKey: indexKey}
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %d: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %d: %w", id, err)
+		}
+		k.keys[id] = aead
+	}
+	if _, ok := k.keys[activeID]; !ok {
+		return nil, fmt.Errorf("active encryption key %d is not configured", activeID)
+	}
+	if len(indexKey) == 0 {
+		return nil, errors.New("blind index key is not configured")
+	}
+	return k, nil
+}
+
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	aead := k.keys[k.activeID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return "v" + strconv.FormatUint(uint64(k.activeID), 10) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns values without a key prefix unchanged; those are rows
+// written before encryption was enabled and not yet migrated.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	keyID, payload, ok := splitCiphertext(value)
+	if !ok {
+		return value, nil
+	}
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("encryption key %d is not configured", keyID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed ciphertext")
+	}
+	nonce, data := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// NeedsRotation reports whether a stored value is plaintext or was written
+// with a key other than the active one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+	keyID, _, ok := splitCiphertext(value)
+	return !ok || keyID != k.activeID
+}
+
+// BlindIndex is a keyed hash of the normalised value. It supports equality
+// lookups and unique constraints without revealing the plaintext.
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(strings.ToUpper(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func splitCiphertext(value string) (uint32, string, bool) {
+	prefix, payload, ok := strings.Cut(value, ":")
+	if !ok || len(prefix) < 2 || prefix[0] != 'v' {
+		return 0, "", false
+	}
+	keyID, err := strconv.ParseUint(prefix[1:], 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint32(keyID), payload, true
+}
+
+// UseKeyring registers the "encrypted" GORM serializer. It must be called
+// before any model with `serializer:encrypted` fields is used.
+func UseKeyring(k *Keyring) {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{k})
+}
+
+type encryptedSerializer struct {
+	keyring *Keyring
+}
+
+func (s encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		stored = string(v)
+	case string:
+		stored = v
+	default:
+		return fmt.Errorf("unsupported value %T for encrypted field %s", dbValue, field.Name)
+	}
+	plain, err := s.keyring.Decrypt(stored)
+	if err != nil {
+		return fmt.Errorf("decrypting %s: %w", field.Name, err)
+	}
+	return field.Set(ctx, dst, plain)
+}
+
+func (s encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	plain, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string", field.Name)
+	}
+	return s.keyring.Encrypt(plain)
+}
+
+// reencryptColumns rewrites the given columns of every row whose value is
+// still plaintext or encrypted with a retired key. The writes go through a
+// plain table update so the serializer does not encrypt them a second time.
+func reencryptColumns(db *gorm.DB, k *Keyring, table string, columns ...string) (int, error) {
+	const batchSize = 500
+	changed := 0
+	var lastID any = 0
+	for {
+		var rows []map[string]any
+		err := db.Table(table).Select(append([]string{"id"}, columns...)).
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&rows).Error
+		if err != nil {
+			return changed, err
+		}
+		for _, row := range rows {
+			updates := make(map[string]any)
+			for _, column := range columns {
+				stored := columnString(row[column])
+				if !k.NeedsRotation(stored) {
+					continue
+				}
+				plain, err := k.Decrypt(stored)
+				if err != nil {
+					return changed, fmt.Errorf("%s.%s of row %v: %w", table, column, row["id"], err)
+				}
+				if updates[column], err = k.Encrypt(plain); err != nil {
+					return changed, err
+				}
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			if err := db.Table(table).Where("id = ?", row["id"]).Updates(updates).Error; err != nil {
+				return changed, err
+			}
+			changed++
+		}
+		if len(rows) < batchSize {
+			return changed, nil
+		}
+		lastID = rows[len(rows)-1]["id"]
+	}
+}
+
+func columnString(v any) string {
+	switch s := v.(type) {
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	default:
+		return ""
+	}
+}
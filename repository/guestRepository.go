@@ -2,6 +2,9 @@ package repository
 
 import (
 	"hms-backend/model"
+	"hms-backend/request"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -11,15 +14,26 @@ type GuestRepository interface {
 	FindByID(id uint) (*model.Guest, error)
 	FindByCredentialID(credType, credID string) (*model.Guest, error)
 	Update(m *model.Guest) error
-	Delete(credType, credID string) error
+	Erase(g *model.Guest, at time.Time) error
+	FindDuplicateCandidates(g *model.Guest) ([]*model.Guest, error)
+	Merge(survivor, duplicate *model.Guest) error
+	Search(query string, terms []string, sort string, offset, limit int) ([]*model.Guest, int64, error)
+	CreateFlag(f *model.GuestFlag) error
+	FindFlagByID(id uint) (*model.GuestFlag, error)
+	FindFlags(guestID uint) ([]*model.GuestFlag, error)
+	UpdateFlag(f *model.GuestFlag) error
+	DropPlaintextIndex() error
+	EncryptExisting() (int, error)
+	NormalizeCredentials() (changed int, skipped int, err error)
 }
 
 type guestRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	keyring *Keyring
 }
 
-func NewGuestRepository(db *gorm.DB) GuestRepository {
-	return &guestRepository{db}
+func NewGuestRepository(db *gorm.DB, keyring *Keyring) GuestRepository {
+	return &guestRepository{db, keyring}
 }
 
 func (r *guestRepository) FindByID(id uint) (*model.Guest, error) {
@@ -29,6 +43,7 @@ func (r *guestRepository) FindByID(id uint) (*model.Guest, error) {
 }
 
 func (r *guestRepository) Create(m *model.Guest) (*model.Guest, error) {
+	r.setIndexes(m)
 	err := r.db.Create(m).Error
 	if err != nil {
 		return nil, err
@@ -38,14 +53,322 @@ func (r *guestRepository) Create(m *model.Guest) (*model.Guest, error) {
 
 func (r *guestRepository) FindByCredentialID(credType, credID string) (*model.Guest, error) {
 	var guest model.Guest
-	err := r.db.Where("id_number = ? AND credential_type = ?", credID, credType).First(&guest)
+	err := r.db.Where("id_number_index = ? AND credential_type = ?", r.keyring.BlindIndex(credID), credType).First(&guest)
 	return &guest, err.Error
 }
 
 func (r *guestRepository) Update(m *model.Guest) error {
+	if !m.IsErased() {
+		r.setIndexes(m)
+	}
 	return r.db.Save(&m).Error
 }
 
-func (r *guestRepository) Delete(credType, credID string) error {
-	return r.db.Where("id_number = ? and credential_type = ?", credID, credType).Delete(&model.Guest{}).Error
+// Erase anonymises the guest in place. The blind index is cleared so the
+// identity number can no longer be matched, even by its hash.
+func (r *guestRepository) Erase(g *model.Guest, at time.Time) error {
+	g.FullName = model.ErasedGuestName
+	g.CredentialType = ""
+	g.IDNumber = ""
+	g.IssuingCountry = ""
+	g.DocumentExpiry = nil
+	g.Phone = ""
+	g.Email = ""
+	g.Preferences = model.GuestPreferences{}
+	g.IDNumberIndex = nil
+	g.PhoneIndex = ""
+	g.EmailIndex = ""
+	g.ErasedAt = &at
+	return r.db.Save(g).Error
+}
+
+// FindDuplicateCandidates returns active profiles sharing an identity
+// number, phone, email or surname with g. Scoring is left to the caller.
+func (r *guestRepository) FindDuplicateCandidates(g *model.Guest) ([]*model.Guest, error) {
+	query := r.db.Where("id_number_index = ?", r.keyring.BlindIndex(g.IDNumber))
+	if g.PhoneIndex != "" {
+		query = query.Or("phone_index = ?", g.PhoneIndex)
+	}
+	if g.EmailIndex != "" {
+		query = query.Or("email_index = ?", g.EmailIndex)
+	}
+	if names := strings.Fields(g.FullName); len(names) > 0 && len(names[len(names)-1]) >= 3 {
+		query = query.Or("full_name LIKE ?", containsPattern(names[len(names)-1]))
+	}
+	var guests []*model.Guest
+	err := r.db.Where(query).
+		Where("id <> ? AND erased_at IS NULL AND merged_into_id IS NULL", g.ID).
+		Find(&guests).Error
+	return guests, err
+}
+
+// Merge moves the bookings, occupancies, flags and loyalty points of
+// duplicate to survivor and marks duplicate as merged. Survivor is saved as
+// given, so the caller can fill in contact details taken from the duplicate.
+func (r *guestRepository) Merge(survivor, duplicate *model.Guest) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Booking{}).Where("guest_id = ?", duplicate.ID).
+			Update("guest_id", survivor.ID).Error; err != nil {
+			return err
+		}
+		// The survivor cannot also be a companion on their own booking.
+		if err := tx.Where("guest_id = ? AND booking_id IN (?)", duplicate.ID,
+			tx.Model(&model.Booking{}).Select("id").Where("guest_id = ?", survivor.ID)).
+			Delete(&model.BookingOccupant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.BookingOccupant{}).Where("guest_id = ?", duplicate.ID).
+			Update("guest_id", survivor.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.GuestFlag{}).Where("guest_id = ?", duplicate.ID).
+			Update("guest_id", survivor.ID).Error; err != nil {
+			return err
+		}
+		if err := mergeLoyalty(tx, survivor, duplicate); err != nil {
+			return err
+		}
+		if err := mergeCorporate(tx, survivor, duplicate); err != nil {
+			return err
+		}
+		r.setIndexes(survivor)
+		if err := tx.Save(survivor).Error; err != nil {
+			return err
+		}
+		duplicate.MergedIntoID = &survivor.ID
+		return tx.Model(duplicate).Update("merged_into_id", survivor.ID).Error
+	})
+}
+
+// mergeCorporate moves the duplicate's company contracts to the survivor.
+// Contracts the survivor is already on keep a single link. Their accounts are
+// read first, as MySQL cannot delete from a table it selects from.
+func mergeCorporate(tx *gorm.DB, survivor, duplicate *model.Guest) error {
+	var accountIDs []uint
+	if err := tx.Model(&model.CorporateGuest{}).Where("guest_id = ?", survivor.ID).
+		Pluck("account_id", &accountIDs).Error; err != nil {
+		return err
+	}
+	if len(accountIDs) > 0 {
+		if err := tx.Where("guest_id = ? AND account_id IN ?", duplicate.ID, accountIDs).
+			Delete(&model.CorporateGuest{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&model.CorporateGuest{}).Where("guest_id = ?", duplicate.ID).
+		Update("guest_id", survivor.ID).Error
+}
+
+// mergeLoyalty moves the duplicate's points to the survivor. The duplicate's
+// membership is kept only when the survivor is not a member yet.
+func mergeLoyalty(tx *gorm.DB, survivor, duplicate *model.Guest) error {
+	if err := tx.Model(&model.LoyaltyEntry{}).Where("guest_id = ?", duplicate.ID).
+		Update("guest_id", survivor.ID).Error; err != nil {
+		return err
+	}
+	var members int64
+	if err := tx.Model(&model.LoyaltyAccount{}).Where("guest_id = ?", survivor.ID).Count(&members).Error; err != nil {
+		return err
+	}
+	if members > 0 {
+		return tx.Where("guest_id = ?", duplicate.ID).Delete(&model.LoyaltyAccount{}).Error
+	}
+	return tx.Model(&model.LoyaltyAccount{}).Where("guest_id = ?", duplicate.ID).
+		Update("guest_id", survivor.ID).Error
+}
+
+// Search ranks active guests against a search query and returns one page
+// of them with the total number of matches. Names match partially, or by
+// sound for typos, on any of the terms; phone and email match exactly
+// through their blind index, and booking references partially. Ranking and
+// paging happen in SQL so that every match is considered.
+func (r *guestRepository) Search(query string, terms []string, sort string, offset, limit int) ([]*model.Guest, int64, error) {
+	scores := []string{"0"}
+	var args []any
+	if len(terms) > 0 {
+		// Each term scores 60 on a word prefix, 40 inside a word and 30 when
+		// the first or last name sounds like it; the name scores their mean.
+		termScores := make([]string, len(terms))
+		for i, term := range terms {
+			termScores[i] = `CASE
+				WHEN full_name LIKE ? OR full_name LIKE ? THEN 60
+				WHEN full_name LIKE ? THEN 40
+				WHEN SOUNDEX(SUBSTRING_INDEX(full_name, ' ', 1)) = SOUNDEX(?)
+					OR SOUNDEX(SUBSTRING_INDEX(full_name, ' ', -1)) = SOUNDEX(?) THEN 30
+				ELSE 0 END`
+			args = append(args, escapeLike(term)+"%", "% "+escapeLike(term)+"%", containsPattern(term), term, term)
+		}
+		scores = append(scores, "("+strings.Join(termScores, " + ")+") DIV ?",
+			"CASE WHEN LOWER(full_name) = ? THEN 80 ELSE 0 END")
+		args = append(args, len(terms), strings.Join(terms, " "))
+	}
+	if index := r.optionalIndex(model.NormalizePhone(query)); index != "" {
+		scores = append(scores, "CASE WHEN phone_index = ? THEN 90 ELSE 0 END")
+		args = append(args, index)
+	}
+	if index := r.optionalIndex(model.NormalizeEmail(query)); index != "" {
+		scores = append(scores, "CASE WHEN email_index = ? THEN 90 ELSE 0 END")
+		args = append(args, index)
+	}
+	scores = append(scores, `CASE
+		WHEN EXISTS (SELECT 1 FROM bookings WHERE bookings.guest_id = guests.id AND bookings.booking_reference = ?) THEN 100
+		WHEN EXISTS (SELECT 1 FROM bookings WHERE bookings.guest_id = guests.id AND bookings.booking_reference LIKE ?) THEN 70
+		ELSE 0 END`)
+	args = append(args, query, containsPattern(query))
+
+	scored := r.db.Model(&model.Guest{}).
+		Select("guests.*, GREATEST("+strings.Join(scores, ", ")+") AS search_score", args...).
+		Where("erased_at IS NULL AND merged_into_id IS NULL")
+	matches := r.db.Table("(?) AS guests", scored).Where("search_score > 0")
+
+	var total int64
+	if err := matches.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	ordered := matches.Session(&gorm.Session{})
+	switch sort {
+	case request.GuestSortName:
+	case request.GuestSortRecent:
+		ordered = ordered.Order("(SELECT MAX(check_in_date) FROM bookings WHERE bookings.guest_id = guests.id) DESC")
+	default:
+		ordered = ordered.Order("search_score DESC")
+	}
+	var guests []*model.Guest
+	err := ordered.Order("full_name, id").Offset(offset).Limit(limit).Find(&guests).Error
+	return guests, total, err
+}
+
+func (r *guestRepository) CreateFlag(f *model.GuestFlag) error {
+	return r.db.Create(f).Error
+}
+
+func (r *guestRepository) FindFlagByID(id uint) (*model.GuestFlag, error) {
+	var flag model.GuestFlag
+	err := r.db.Where("id = ?", id).First(&flag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// FindFlags returns every flag of the guest, including expired and removed ones.
+func (r *guestRepository) FindFlags(guestID uint) ([]*model.GuestFlag, error) {
+	var flags []*model.GuestFlag
+	err := r.db.Where("guest_id = ?", guestID).Order("created_at DESC").Find(&flags).Error
+	return flags, err
+}
+
+func (r *guestRepository) UpdateFlag(f *model.GuestFlag) error {
+	return r.db.Save(f).Error
+}
+
+// containsPattern builds a LIKE pattern matching s anywhere, with the
+// wildcards in s itself escaped.
+func containsPattern(s string) string {
+	return "%" + escapeLike(s) + "%"
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *guestRepository) setIndexes(g *model.Guest) {
+	g.IDNumberIndex = r.blindIndex(g.IDNumber)
+	g.PhoneIndex = r.optionalIndex(model.NormalizePhone(g.Phone))
+	g.EmailIndex = r.optionalIndex(model.NormalizeEmail(g.Email))
+}
+
+func (r *guestRepository) blindIndex(idNumber string) *string {
+	index := r.keyring.BlindIndex(idNumber)
+	return &index
+}
+
+func (r *guestRepository) optionalIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	return r.keyring.BlindIndex(value)
+}
+
+// DropPlaintextIndex removes the unique constraint on the old plaintext
+// id_number column. It must run before AutoMigrate turns the column into
+// an encrypted text column.
+func (r *guestRepository) DropPlaintextIndex() error {
+	const legacy = "uni_guests_id_number"
+	if r.db.Migrator().HasConstraint(&model.Guest{}, legacy) {
+		return r.db.Migrator().DropConstraint(&model.Guest{}, legacy)
+	}
+	return nil
+}
+
+// EncryptExisting encrypts guest and occupant PII still stored in plaintext,
+// re-encrypts values written with a retired key and fills in missing blind
+// indexes. It returns the number of rows changed.
+func (r *guestRepository) EncryptExisting() (int, error) {
+	changed, err := reencryptColumns(r.db, r.keyring, "guests", "id_number", "phone", "email")
+	if err != nil {
+		return changed, err
+	}
+	occupants, err := reencryptColumns(r.db, r.keyring, "booking_occupants", "id_number")
+	changed += occupants
+	if err != nil {
+		return changed, err
+	}
+	var guests []*model.Guest
+	err = r.db.Where("(id_number_index IS NULL OR phone_index IS NULL OR email_index IS NULL) AND erased_at IS NULL").
+		FindInBatches(&guests, 500, func(tx *gorm.DB, batch int) error {
+			for _, g := range guests {
+				r.setIndexes(g)
+				// A fresh session on the batch's connection, without its conditions.
+				err := tx.Session(&gorm.Session{NewDB: true}).Model(g).
+					Select("id_number_index", "phone_index", "email_index").Updates(g).Error
+				if err != nil {
+					return err
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	return changed, err
+}
+
+// NormalizeCredentials rewrites the credential type, identity number and its
+// blind index of guests stored before credentials were normalised, so the
+// normalised lookups find them. A guest whose normalised credential already
+// belongs to another guest is a duplicate and is left for merging; it is
+// counted in skipped.
+func (r *guestRepository) NormalizeCredentials() (changed int, skipped int, err error) {
+	var guests []*model.Guest
+	err = r.db.Where("erased_at IS NULL").
+		FindInBatches(&guests, 500, func(tx *gorm.DB, batch int) error {
+			db := tx.Session(&gorm.Session{NewDB: true})
+			for _, g := range guests {
+				credType := model.NormalizeCredentialType(g.CredentialType)
+				number := model.NormalizeIDNumber(g.IDNumber)
+				index := r.keyring.BlindIndex(number)
+				if credType == g.CredentialType && number == g.IDNumber &&
+					g.IDNumberIndex != nil && *g.IDNumberIndex == index {
+					continue
+				}
+				var taken int64
+				if err := db.Model(&model.Guest{}).
+					Where("id_number_index = ? AND id <> ?", index, g.ID).Count(&taken).Error; err != nil {
+					return err
+				}
+				if taken > 0 {
+					skipped++
+					continue
+				}
+				g.CredentialType = credType
+				g.IDNumber = number
+				g.IDNumberIndex = &index
+				if err := db.Model(g).Select("credential_type", "id_number", "id_number_index").Updates(g).Error; err != nil {
+					return err
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	return changed, skipped, err
 }
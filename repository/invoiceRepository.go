@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"errors"
+	"hms-backend/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository interface {
+	FindActiveByBookingID(bookingID string) (*model.Invoice, error)
+	FindByNumber(number string) (*model.Invoice, error)
+	CreateNumbered(inv *model.Invoice, number func(sequence uint) string) error
+	Void(inv *model.Invoice, note *model.Invoice, number func(sequence uint) string) error
+	DropBookingUniqueIndex() error
+}
+
+// ErrInvoiceIssued is returned by CreateNumbered when the booking already
+// has an invoice that is not voided.
+var ErrInvoiceIssued = errors.New("booking already has an invoice")
+
+// ErrInvoiceVoided is returned by Void when the invoice was voided first.
+var ErrInvoiceVoided = errors.New("invoice is already voided")
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{db}
+}
+
+// FindActiveByBookingID returns the booking's invoice that is not voided.
+func (r *invoiceRepository) FindActiveByBookingID(bookingID string) (*model.Invoice, error) {
+	var inv model.Invoice
+	err := r.db.Where("booking_id = ? AND credit_of_id IS NULL AND voided_at IS NULL", bookingID).
+		First(&inv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *invoiceRepository) FindByNumber(number string) (*model.Invoice, error) {
+	var inv model.Invoice
+	err := r.db.Where("number = ?", number).First(&inv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// CreateNumbered takes the next number of the invoice's property and stores
+// the invoice in the same transaction. The sequence row stays locked until
+// commit, and a failed insert rolls the number back, so there are no gaps.
+// The lock also keeps two invoices from being issued for one booking.
+func (r *invoiceRepository) CreateNumbered(inv *model.Invoice, number func(sequence uint) string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSequence(tx, inv, number); err != nil {
+			return err
+		}
+		var issued int64
+		if err := tx.Model(&model.Invoice{}).
+			Where("booking_id = ? AND credit_of_id IS NULL AND voided_at IS NULL", inv.BookingID).
+			Count(&issued).Error; err != nil {
+			return err
+		}
+		if issued > 0 {
+			return ErrInvoiceIssued
+		}
+		return tx.Create(inv).Error
+	})
+}
+
+// Void marks the invoice voided and stores the credit note that cancels it,
+// numbered from the same sequence, in one transaction.
+func (r *invoiceRepository) Void(inv *model.Invoice, note *model.Invoice, number func(sequence uint) string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSequence(tx, note, number); err != nil {
+			return err
+		}
+		res := tx.Model(&model.Invoice{}).Where("id = ? AND voided_at IS NULL", inv.ID).
+			Update("voided_at", note.CreatedAt)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvoiceVoided
+		}
+		inv.VoidedAt = &note.CreatedAt
+		return tx.Create(note).Error
+	})
+}
+
+// lockSequence locks the sequence of the invoice's property and numbers the
+// invoice with the next value.
+func lockSequence(tx *gorm.DB, inv *model.Invoice, number func(sequence uint) string) error {
+	seq := model.InvoiceSequence{PropertyID: inv.PropertyID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seq).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("property_id = ?", inv.PropertyID).First(&seq).Error; err != nil {
+		return err
+	}
+	seq.Last++
+	if err := tx.Model(&seq).Update("last", seq.Last).Error; err != nil {
+		return err
+	}
+	inv.Sequence = seq.Last
+	inv.Number = number(seq.Last)
+	inv.Document.Number = inv.Number
+	return nil
+}
+
+// DropBookingUniqueIndex removes the unique index that allowed one invoice
+// per booking. A voided invoice is followed by its credit note and a new
+// invoice, and AutoMigrate does not drop indexes, so this must run before it.
+// The plain index AutoMigrate puts back under the same name is kept.
+func (r *invoiceRepository) DropBookingUniqueIndex() error {
+	m := r.db.Migrator()
+	if !m.HasTable(&model.Invoice{}) {
+		return nil
+	}
+	indexes, err := m.GetIndexes(&model.Invoice{})
+	if err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		unique, _ := idx.Unique()
+		if !unique || len(idx.Columns()) != 1 || idx.Columns()[0] != "booking_id" {
+			continue
+		}
+		if err := m.DropIndex(&model.Invoice{}, idx.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
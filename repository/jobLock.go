@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// JobLock keeps a background job to one instance at a time when the API
+// runs on several.
+type JobLock interface {
+	// TryLock takes the named lock without waiting. It reports false when
+	// another instance holds it.
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+}
+
+type jobLock struct {
+	db *gorm.DB
+}
+
+// NewJobLock returns a JobLock on MySQL named locks. They belong to a
+// connection, so each lock holds one from the pool until it is released, and
+// are freed by the server if the instance dies.
+func NewJobLock(db *gorm.DB) JobLock {
+	return &jobLock{db}
+}
+
+func (l *jobLock) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", name).Scan(&got); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if got.Int64 != 1 {
+		conn.Close()
+		return nil, false, nil
+	}
+	unlock := func() {
+		// The job may have ended because ctx was cancelled, so the lock is
+		// released without it.
+		if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name); err != nil {
+			log.Printf("job lock: releasing %s failed: %v", name, err)
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"errors"
+	"hms-backend/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoyaltyRepository interface {
+	CreateAccount(a *model.LoyaltyAccount) error
+	FindAccountByGuestID(guestID uint) (*model.LoyaltyAccount, error)
+	UpdateAccount(a *model.LoyaltyAccount) error
+	FindEntries(guestID uint) ([]*model.LoyaltyEntry, error)
+	FindOpenEarnings(guestID uint) ([]*model.LoyaltyEntry, error)
+	HasEarning(bookingID string) (bool, error)
+	Post(entries []*model.LoyaltyEntry, draws []PointsDraw, payment *model.Transaction) error
+	Earn(entry *model.LoyaltyEntry) error
+}
+
+// ErrPointsDrawn is returned by Post when an earning no longer holds the
+// points to be drawn, because another redemption or expiry took them first.
+var ErrPointsDrawn = errors.New("loyalty points were drawn concurrently")
+
+// ErrStayEarned is returned by Earn when the stay has already earned points.
+var ErrStayEarned = errors.New("points for this stay were already credited")
+
+// PointsDraw takes points off what is left of an earning.
+type PointsDraw struct {
+	EntryID uint
+	Points  int
+}
+
+type loyaltyRepository struct {
+	db *gorm.DB
+}
+
+func NewLoyaltyRepository(db *gorm.DB) LoyaltyRepository {
+	return &loyaltyRepository{db}
+}
+
+func (r *loyaltyRepository) CreateAccount(a *model.LoyaltyAccount) error {
+	return r.db.Create(a).Error
+}
+
+func (r *loyaltyRepository) FindAccountByGuestID(guestID uint) (*model.LoyaltyAccount, error) {
+	var account model.LoyaltyAccount
+	err := r.db.Where("guest_id = ?", guestID).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *loyaltyRepository) UpdateAccount(a *model.LoyaltyAccount) error {
+	return r.db.Save(a).Error
+}
+
+func (r *loyaltyRepository) FindEntries(guestID uint) ([]*model.LoyaltyEntry, error) {
+	var entries []*model.LoyaltyEntry
+	err := r.db.Where("guest_id = ?", guestID).Order("created_at DESC, id DESC").Find(&entries).Error
+	return entries, err
+}
+
+// FindOpenEarnings returns earnings with points left, the first to expire first.
+func (r *loyaltyRepository) FindOpenEarnings(guestID uint) ([]*model.LoyaltyEntry, error) {
+	var entries []*model.LoyaltyEntry
+	err := r.db.Where("guest_id = ? AND type = ? AND remaining > 0", guestID, model.LoyaltyEarn).
+		Order("expires_at, id").Find(&entries).Error
+	return entries, err
+}
+
+func (r *loyaltyRepository) HasEarning(bookingID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.LoyaltyEntry{}).
+		Where("booking_id = ? AND type = ?", bookingID, model.LoyaltyEarn).Count(&count).Error
+	return count > 0, err
+}
+
+// Post writes new ledger entries together with the earnings they draw down,
+// so the balance never changes half way. A redemption also records its
+// payment, which the new entries are linked to. Earnings are decremented in
+// SQL and only while they still hold the points, so concurrent redemptions
+// cannot spend the same points twice.
+func (r *loyaltyRepository) Post(entries []*model.LoyaltyEntry, draws []PointsDraw, payment *model.Transaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if payment != nil {
+			if err := tx.Create(payment).Error; err != nil {
+				return err
+			}
+			for _, e := range entries {
+				e.TransactionID = &payment.Id
+			}
+		}
+		for _, d := range draws {
+			res := tx.Model(&model.LoyaltyEntry{}).
+				Where("id = ? AND remaining >= ?", d.EntryID, d.Points).
+				Update("remaining", gorm.Expr("remaining - ?", d.Points))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrPointsDrawn
+			}
+		}
+		for _, e := range entries {
+			if err := tx.Create(e).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Earn writes the earning of a stay unless the stay already has one. The
+// guest's account row is locked while checking, so a stay credited twice at
+// the same time earns once.
+func (r *loyaltyRepository) Earn(entry *model.LoyaltyEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var account model.LoyaltyAccount
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("guest_id = ?", entry.GuestID).First(&account).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&model.LoyaltyEntry{}).
+			Where("booking_id = ? AND type = ?", entry.BookingID, model.LoyaltyEarn).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrStayEarned
+		}
+		return tx.Create(entry).Error
+	})
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MigrationRepository interface {
+	ConvertTimesToUTC(zone string, now time.Time) (int64, error)
+}
+
+// ErrLegacyTimezone is returned by ConvertTimesToUTC when the database holds
+// local times and the zone they are in is not known.
+var ErrLegacyTimezone = errors.New("the database holds times in the server's local zone; set DB_LEGACY_TIMEZONE to that zone")
+
+// utcMigration names the conversion of local datetimes to UTC.
+const utcMigration = "datetimes_to_utc"
+
+type migrationRepository struct {
+	db *gorm.DB
+}
+
+func NewMigrationRepository(db *gorm.DB) MigrationRepository {
+	return &migrationRepository{db}
+}
+
+// ConvertTimesToUTC converts every datetime column of a database written
+// while the connection used loc=Local, when the columns held the times of
+// the application server's zone, to UTC. zone is that zone as a MySQL time
+// zone name or an offset such as +02:00.
+//
+// It runs once and must run before AutoMigrate: a database without the
+// marker but with a bookings table is taken to hold local times, a new one is
+// only marked. The columns are converted and the marker is stored in one
+// transaction, so a failed run converts nothing and is tried again on the
+// next start. It returns the number of rows changed.
+func (r *migrationRepository) ConvertTimesToUTC(zone string, now time.Time) (int64, error) {
+	m := r.db.Migrator()
+	if m.HasTable(&model.SchemaMigration{}) {
+		var applied int64
+		if err := r.db.Model(&model.SchemaMigration{}).Where("name = ?", utcMigration).Count(&applied).Error; err != nil {
+			return 0, err
+		}
+		if applied > 0 {
+			return 0, nil
+		}
+	}
+	legacy := m.HasTable(&model.Booking{})
+	if err := m.AutoMigrate(&model.SchemaMigration{}); err != nil {
+		return 0, err
+	}
+	marker := &model.SchemaMigration{Name: utcMigration, AppliedAt: now}
+	if !legacy {
+		return 0, r.db.Create(marker).Error
+	}
+	if zone == "" {
+		return 0, ErrLegacyTimezone
+	}
+	// CONVERT_TZ returns NULL for a zone the server does not know.
+	var probe sql.NullString
+	if err := r.db.Raw("SELECT CONVERT_TZ('2000-01-01 00:00:00', ?, '+00:00')", zone).Row().Scan(&probe); err != nil {
+		return 0, err
+	}
+	if !probe.Valid {
+		return 0, fmt.Errorf("MySQL does not know the time zone %q: load its time zone tables or give an offset such as +02:00", zone)
+	}
+
+	var columns []struct {
+		TableName  string
+		ColumnName string
+	}
+	err := r.db.Raw(`SELECT c.table_name AS table_name, c.column_name AS column_name
+		FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = DATABASE() AND t.table_type = 'BASE TABLE'
+			AND c.data_type IN ('datetime', 'timestamp') AND c.table_name <> ?
+		ORDER BY c.table_name, c.ordinal_position`, "schema_migrations").Scan(&columns).Error
+	if err != nil {
+		return 0, err
+	}
+	var tables []string
+	sets := make(map[string][]string)
+	for _, c := range columns {
+		if _, ok := sets[c.TableName]; !ok {
+			tables = append(tables, c.TableName)
+		}
+		sets[c.TableName] = append(sets[c.TableName], fmt.Sprintf("`%s` = CONVERT_TZ(`%s`, @legacy_zone, '+00:00')", c.ColumnName, c.ColumnName))
+	}
+
+	var changed int64
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET @legacy_zone = ?", zone).Error; err != nil {
+			return err
+		}
+		for _, table := range tables {
+			// Raw SQL also passes the hooks that keep the audit trail append-only.
+			res := tx.Exec(fmt.Sprintf("UPDATE `%s` SET %s", table, strings.Join(sets[table], ", ")))
+			if res.Error != nil {
+				return res.Error
+			}
+			changed += res.RowsAffected
+		}
+		return tx.Create(marker).Error
+	})
+	return changed, err
+}
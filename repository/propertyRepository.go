@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"hms-backend/model"
+
+	"gorm.io/gorm"
+)
+
+type PropertyRepository interface {
+	Create(p *model.Property) (*model.Property, error)
+	FindAll() ([]*model.Property, error)
+	FindByID(id uint) (*model.Property, error)
+	FindByCode(code string) (*model.Property, error)
+	Update(p *model.Property) error
+	EnsureDefault() (*model.Property, error)
+}
+
+type propertyRepository struct {
+	db *gorm.DB
+}
+
+func NewPropertyRepository(db *gorm.DB) PropertyRepository {
+	return &propertyRepository{db}
+}
+
+func (r *propertyRepository) Create(p *model.Property) (*model.Property, error) {
+	err := r.db.Create(p).Error
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (r *propertyRepository) FindAll() ([]*model.Property, error) {
+	var properties []*model.Property
+	err := r.db.Order("code").Find(&properties).Error
+	return properties, err
+}
+
+func (r *propertyRepository) FindByID(id uint) (*model.Property, error) {
+	var property model.Property
+	err := r.db.Where("id = ?", id).First(&property).Error
+	if err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
+
+func (r *propertyRepository) FindByCode(code string) (*model.Property, error) {
+	var property model.Property
+	err := r.db.Where("code = ?", code).First(&property).Error
+	if err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
+
+func (r *propertyRepository) Update(p *model.Property) error {
+	return r.db.Save(p).Error
+}
+
+// EnsureDefault creates the default property if needed and assigns it to
+// every room, room type and booking that has no property yet.
+func (r *propertyRepository) EnsureDefault() (*model.Property, error) {
+	property := model.Property{Code: model.DefaultPropertyCode}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(model.Property{Code: model.DefaultPropertyCode}).
+			Attrs(model.Property{Name: "Default Property"}).
+			FirstOrCreate(&property).Error; err != nil {
+			return err
+		}
+		for _, m := range []any{&model.Room{}, &model.RoomType{}, &model.Booking{}} {
+			if err := tx.Model(m).Where("property_id = 0 OR property_id IS NULL").
+				Update("property_id", property.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"errors"
+	"hms-backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReceivableRepository interface {
+	FindByID(id uint) (*model.ReceivableEntry, error)
+	FindByTransactionID(transactionID uint) (*model.ReceivableEntry, error)
+	FindInvoices(accountID uint, openOnly bool) ([]*model.ReceivableEntry, error)
+	FindAllOpenInvoices() ([]*model.ReceivableEntry, error)
+	FindEntries(accountID uint, from, to time.Time) ([]*model.ReceivableEntry, error)
+	BalanceBefore(accountID uint, date time.Time) (float64, error)
+	Post(entry *model.ReceivableEntry) error
+}
+
+// ErrOverSettled is returned by Post when an allocation would settle more
+// than is open on its invoice, e.g. after a concurrent payment.
+var ErrOverSettled = errors.New("allocation exceeds what is open on the invoice")
+
+type receivableRepository struct {
+	db *gorm.DB
+}
+
+func NewReceivableRepository(db *gorm.DB) ReceivableRepository {
+	return &receivableRepository{db}
+}
+
+func (r *receivableRepository) FindByID(id uint) (*model.ReceivableEntry, error) {
+	var entry model.ReceivableEntry
+	err := r.db.Preload("Allocations").Where("id = ?", id).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *receivableRepository) FindByTransactionID(transactionID uint) (*model.ReceivableEntry, error) {
+	var entry model.ReceivableEntry
+	err := r.db.Where("transaction_id = ?", transactionID).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindInvoices returns the account's invoices, oldest first.
+func (r *receivableRepository) FindInvoices(accountID uint, openOnly bool) ([]*model.ReceivableEntry, error) {
+	query := r.db.Where("account_id = ? AND type = ?", accountID, model.ReceivableInvoice)
+	if openOnly {
+		query = query.Where("settled < amount - 0.005")
+	}
+	var invoices []*model.ReceivableEntry
+	err := query.Order("posted_on, id").Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *receivableRepository) FindAllOpenInvoices() ([]*model.ReceivableEntry, error) {
+	var invoices []*model.ReceivableEntry
+	err := r.db.Where("type = ? AND settled < amount - 0.005", model.ReceivableInvoice).
+		Order("account_id, posted_on, id").Find(&invoices).Error
+	return invoices, err
+}
+
+// FindEntries returns the entries posted from from to to, both included.
+func (r *receivableRepository) FindEntries(accountID uint, from, to time.Time) ([]*model.ReceivableEntry, error) {
+	var entries []*model.ReceivableEntry
+	err := r.db.Preload("Allocations").
+		Where("account_id = ? AND posted_on >= ? AND posted_on <= ?", accountID, from, to).
+		Order("posted_on, id").Find(&entries).Error
+	return entries, err
+}
+
+// BalanceBefore is what the account owed at the start of date.
+func (r *receivableRepository) BalanceBefore(accountID uint, date time.Time) (float64, error) {
+	var balance float64
+	err := r.db.Model(&model.ReceivableEntry{}).
+		Where("account_id = ? AND posted_on < ?", accountID, date).
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN amount ELSE -amount END), 0)", model.ReceivableInvoice).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// Post writes an entry with its allocations and settles the invoices they
+// are applied to in one transaction. Settlement is added in SQL and only
+// while it stays within the invoice amount, so concurrent payments cannot
+// settle an invoice twice. Once payments cover an invoice in full, the
+// booking transaction it was raised for is marked as paid.
+func (r *receivableRepository) Post(entry *model.ReceivableEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		for _, a := range entry.Allocations {
+			res := tx.Model(&model.ReceivableEntry{}).
+				Where("id = ? AND type = ? AND settled + ? <= amount + 0.005", a.InvoiceID, model.ReceivableInvoice, a.Amount).
+				Update("settled", gorm.Expr("settled + ?", a.Amount))
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrOverSettled
+			}
+			if entry.Type != model.ReceivablePayment {
+				continue
+			}
+			// Only money received pays the booking transaction. What a credit
+			// note settles was never paid and must not become refundable.
+			received := tx.Table("receivable_allocations AS a").Select("COALESCE(SUM(a.amount), 0)").
+				Joins("JOIN receivable_entries AS e ON e.id = a.entry_id").
+				Where("a.invoice_id = ? AND e.type = ?", a.InvoiceID, model.ReceivablePayment)
+			paid := tx.Model(&model.ReceivableEntry{}).Select("transaction_id").
+				Where("id = ? AND amount <= (?) + 0.005", a.InvoiceID, received)
+			if err := tx.Model(&model.Transaction{}).Where("id IN (?)", paid).
+				Update("paid", true).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -12,30 +12,45 @@ type roomRepository struct {
 }
 
 type RoomRepository interface {
-	FindAll() ([]*model.Room, error)
+	FindAll(propertyID uint) ([]*model.Room, error)
 	FindByID(id uint) (*model.Room, error)
 	Create(room *model.Room) (*model.Room, error)
 	Update(room *model.Room) error
 	Delete(id uint) error
-	FindByNumber(number string) (*model.Room, error)
+	FindByNumber(propertyID uint, number string) (*model.Room, error)
 	FindAvailable(params request.RoomFilterParams) ([]*model.Room, error)
 	ChangeStatus(id uint, status string) error
 	CreateRoomType(roomType *model.RoomType) (*model.RoomType, error)
+	FindAllRoomTypes(propertyID uint, includeRetired bool) ([]*model.RoomType, error)
+	FindRoomTypeByID(id uint) (*model.RoomType, error)
+	FindRoomTypeByName(propertyID uint, name string) (*model.RoomType, error)
+	UpdateRoomType(roomType *model.RoomType) error
+	DeleteRoomType(id uint) error
+	CountRoomsByType(roomTypeID uint) (int64, error)
+	CountBookingsByRoomType(roomTypeID uint) (int64, error)
+	FindOrCreateAmenities(names []string) ([]model.Amenity, error)
+	ReplaceAmenities(room *model.Room, amenities []model.Amenity) error
+	ReplaceConnectingRooms(roomID uint, connectingIDs []uint) error
+	DropGlobalUniqueIndexes() error
 }
 
 func NewRoomRepository(db *gorm.DB) RoomRepository {
 	return &roomRepository{db}
 }
 
-func (r *roomRepository) FindAll() ([]*model.Room, error) {
+func (r *roomRepository) FindAll(propertyID uint) ([]*model.Room, error) {
 	var rooms []*model.Room
-	err := r.db.Preload("Room.RoomType").Find(&rooms).Error
+	query := r.db.Preload("RoomType").Preload("Amenities").Preload("ConnectingRooms")
+	if propertyID != 0 {
+		query = query.Where("property_id = ?", propertyID)
+	}
+	err := query.Find(&rooms).Error
 	return rooms, err
 }
 
 func (r *roomRepository) FindByID(id uint) (*model.Room, error) {
 	var room model.Room
-	err := r.db.Preload("RoomType").Where("id = ?", id).First(&room).Error
+	err := r.db.Preload("RoomType").Preload("Amenities").Preload("ConnectingRooms").Where("id = ?", id).First(&room).Error
 	return &room, err
 }
 
@@ -52,12 +67,21 @@ func (r *roomRepository) Update(m *model.Room) error {
 }
 
 func (r *roomRepository) Delete(id uint) error {
-	return r.db.Delete(&model.Room{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Clear join rows first so no dangling amenity or connection links remain.
+		if err := tx.Exec("DELETE FROM room_amenities WHERE room_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM room_connections WHERE room_id = ? OR connecting_room_id = ?", id, id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Room{}, id).Error
+	})
 }
 
-func (r *roomRepository) FindByNumber(s string) (*model.Room, error) {
+func (r *roomRepository) FindByNumber(propertyID uint, s string) (*model.Room, error) {
 	var room model.Room
-	err := r.db.Preload("Room.RoomType").Where("number = ?", s).First(&room).Error
+	err := r.db.Preload("RoomType").Where("property_id = ? AND number = ?", propertyID, s).First(&room).Error
 	return &room, err
 }
 
@@ -68,7 +92,7 @@ func (r *roomRepository) FindAvailable(params request.RoomFilterParams) ([]*mode
 		model.StatusConfirmed,
 	}
 	// 1. Start query chain. Preload loads the RoomType data efficiently after the query is done.
-	query := r.db.Model(&model.Room{}).Preload("Room.RoomType")
+	query := r.db.Model(&model.Room{}).Preload("RoomType").Preload("Amenities").Preload("ConnectingRooms")
 
 	// 2. JOIN with room_types table to allow filtering on price and category name.
 	//    We use the actual table name `room_types` for clarity.
@@ -84,6 +108,10 @@ func (r *roomRepository) FindAvailable(params request.RoomFilterParams) ([]*mode
 
 	// --- Dynamically add the rest of the user's filters ---
 
+	if params.PropertyID != 0 {
+		query = query.Where("rooms.property_id = ?", params.PropertyID)
+	}
+
 	// 5. Filter by category (RoomType name) if provided.
 	if params.Category != "" {
 		query = query.Where("room_types.name = ?", params.Category)
@@ -100,7 +128,45 @@ func (r *roomRepository) FindAvailable(params request.RoomFilterParams) ([]*mode
 		query = query.Where("room_types.price <= ?", params.MaxPrice)
 	}
 
-	query = query.Where("status = ?", model.StatusAvailable)
+	// 8. Filter by the room's physical attributes.
+	if params.MinFloor != 0 {
+		query = query.Where("rooms.floor >= ?", params.MinFloor)
+	}
+	if params.MaxFloor != 0 {
+		query = query.Where("rooms.floor <= ?", params.MaxFloor)
+	}
+	if params.Building != "" {
+		query = query.Where("rooms.building = ?", params.Building)
+	}
+	if params.View != "" {
+		query = query.Where("rooms.view = ?", params.View)
+	}
+	if params.BedConfiguration != "" {
+		query = query.Where("rooms.bed_configuration = ?", params.BedConfiguration)
+	}
+	if params.Smoking != nil {
+		query = query.Where("rooms.smoking = ?", *params.Smoking)
+	}
+	if params.Accessible != nil {
+		query = query.Where("rooms.accessible = ?", *params.Accessible)
+	}
+
+	// 9. A room must have ALL requested amenities, so count the matches per room.
+	if len(params.Amenities) > 0 {
+		amenityQuery := r.db.Table("room_amenities").Select("room_amenities.room_id").
+			Joins("JOIN amenities ON amenities.id = room_amenities.amenity_id").
+			Where("amenities.name IN ?", params.Amenities).
+			Group("room_amenities.room_id").
+			Having("COUNT(DISTINCT amenities.id) = ?", len(params.Amenities))
+		query = query.Where("rooms.id IN (?)", amenityQuery)
+	}
+
+	// 10. The room type must hold the whole party, counting any extra beds it allows.
+	if guests := params.Adults + params.Children; guests > 0 {
+		query = query.Where("room_types.capacity + room_types.max_extra_beds >= ?", guests)
+	}
+
+	query = query.Where("rooms.status = ?", model.StatusAvailable).Where("room_types.retired = ?", false)
 
 	// Execute the fully constructed query
 	err := query.Find(&rooms).Error
@@ -112,8 +178,8 @@ func (r *roomRepository) ChangeStatus(id uint, status string) error {
 	if err != nil {
 		return err
 	}
-	room.Status = model.RoomStatus(status)
-	return r.db.Save(&room).Error
+	// Update the single column so preloaded relations are not re-saved.
+	return r.db.Model(room).Update("status", model.RoomStatus(status)).Error
 }
 
 func (r *roomRepository) CreateRoomType(roomType *model.RoomType) (*model.RoomType, error) {
@@ -123,3 +189,123 @@ func (r *roomRepository) CreateRoomType(roomType *model.RoomType) (*model.RoomTy
 	}
 	return roomType, nil
 }
+
+func (r *roomRepository) FindAllRoomTypes(propertyID uint, includeRetired bool) ([]*model.RoomType, error) {
+	var roomTypes []*model.RoomType
+	query := r.db.Order("name")
+	if propertyID != 0 {
+		query = query.Where("property_id = ?", propertyID)
+	}
+	if !includeRetired {
+		query = query.Where("retired = ?", false)
+	}
+	err := query.Find(&roomTypes).Error
+	return roomTypes, err
+}
+
+func (r *roomRepository) FindRoomTypeByID(id uint) (*model.RoomType, error) {
+	var roomType model.RoomType
+	err := r.db.Where("id = ?", id).First(&roomType).Error
+	if err != nil {
+		return nil, err
+	}
+	return &roomType, nil
+}
+
+func (r *roomRepository) FindRoomTypeByName(propertyID uint, name string) (*model.RoomType, error) {
+	var roomType model.RoomType
+	err := r.db.Where("property_id = ? AND name = ?", propertyID, name).First(&roomType).Error
+	if err != nil {
+		return nil, err
+	}
+	return &roomType, nil
+}
+
+func (r *roomRepository) UpdateRoomType(roomType *model.RoomType) error {
+	return r.db.Save(roomType).Error
+}
+
+func (r *roomRepository) DeleteRoomType(id uint) error {
+	return r.db.Delete(&model.RoomType{}, id).Error
+}
+
+func (r *roomRepository) CountRoomsByType(roomTypeID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Room{}).Where("room_type_id = ?", roomTypeID).Count(&count).Error
+	return count, err
+}
+
+func (r *roomRepository) CountBookingsByRoomType(roomTypeID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Booking{}).
+		Joins("JOIN rooms ON rooms.id = bookings.room_id").
+		Where("rooms.room_type_id = ?", roomTypeID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *roomRepository) FindOrCreateAmenities(names []string) ([]model.Amenity, error) {
+	amenities := make([]model.Amenity, 0, len(names))
+	for _, name := range names {
+		var amenity model.Amenity
+		if err := r.db.Where(model.Amenity{Name: name}).FirstOrCreate(&amenity).Error; err != nil {
+			return nil, err
+		}
+		amenities = append(amenities, amenity)
+	}
+	return amenities, nil
+}
+
+func (r *roomRepository) ReplaceAmenities(room *model.Room, amenities []model.Amenity) error {
+	return r.db.Model(room).Association("Amenities").Replace(amenities)
+}
+
+func (r *roomRepository) ReplaceConnectingRooms(roomID uint, connectingIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM room_connections WHERE room_id = ? OR connecting_room_id = ?", roomID, roomID).Error; err != nil {
+			return err
+		}
+		if len(connectingIDs) == 0 {
+			return nil
+		}
+		// Store every connection in both directions so either room can be queried.
+		rows := make([]map[string]any, 0, len(connectingIDs)*2)
+		for _, id := range connectingIDs {
+			rows = append(rows,
+				map[string]any{"room_id": roomID, "connecting_room_id": id},
+				map[string]any{"room_id": id, "connecting_room_id": roomID},
+			)
+		}
+		return tx.Table("room_connections").Create(&rows).Error
+	})
+}
+
+// DropGlobalUniqueIndexes removes the unique indexes that made room numbers
+// and room type names unique across all properties. They are unique per
+// property now, and AutoMigrate does not drop indexes, so this must run
+// before it. Older GORM versions named the index after the column.
+func (r *roomRepository) DropGlobalUniqueIndexes() error {
+	legacy := []struct {
+		model any
+		names []string
+	}{
+		{&model.Room{}, []string{"uni_rooms_number", "number"}},
+		{&model.RoomType{}, []string{"uni_room_types_name", "name"}},
+	}
+	m := r.db.Migrator()
+	for _, l := range legacy {
+		for _, name := range l.names {
+			switch {
+			case m.HasConstraint(l.model, name):
+				if err := m.DropConstraint(l.model, name); err != nil {
+					return err
+				}
+			case m.HasIndex(l.model, name):
+				if err := m.DropIndex(l.model, name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
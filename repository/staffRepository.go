@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"hms-backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type StaffRepository interface {
+	Create(u *model.StaffUser) (*model.StaffUser, error)
+	FindByID(id uint) (*model.StaffUser, error)
+	FindByUsername(username string) (*model.StaffUser, error)
+	Update(u *model.StaffUser) error
+	CountByRole(role model.Role) (int64, error)
+	CreateSession(s *model.AuthSession) error
+	FindSessionByID(id string) (*model.AuthSession, error)
+	FindSessionByRefreshHash(hash string) (*model.AuthSession, error)
+	FindSessionByRetiredHash(hash string) (*model.AuthSession, error)
+	UpdateSession(s *model.AuthSession, oldHash string) (bool, error)
+	RevokeSession(id string, at time.Time) error
+}
+
+type staffRepository struct {
+	db *gorm.DB
+}
+
+func NewStaffRepository(db *gorm.DB) StaffRepository {
+	return &staffRepository{db}
+}
+
+func (r *staffRepository) Create(u *model.StaffUser) (*model.StaffUser, error) {
+	err := r.db.Create(u).Error
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (r *staffRepository) FindByID(id uint) (*model.StaffUser, error) {
+	var user model.StaffUser
+	err := r.db.Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *staffRepository) FindByUsername(username string) (*model.StaffUser, error) {
+	var user model.StaffUser
+	err := r.db.Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *staffRepository) Update(u *model.StaffUser) error {
+	return r.db.Save(u).Error
+}
+
+func (r *staffRepository) CountByRole(role model.Role) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.StaffUser{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+func (r *staffRepository) CreateSession(s *model.AuthSession) error {
+	return r.db.Create(s).Error
+}
+
+func (r *staffRepository) FindSessionByID(id string) (*model.AuthSession, error) {
+	var session model.AuthSession
+	err := r.db.Preload("StaffUser").Where("id = ?", id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *staffRepository) FindSessionByRefreshHash(hash string) (*model.AuthSession, error) {
+	var session model.AuthSession
+	err := r.db.Preload("StaffUser").Where("refresh_token_hash = ?", hash).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindSessionByRetiredHash returns the session a rotated refresh token
+// belonged to.
+func (r *staffRepository) FindSessionByRetiredHash(hash string) (*model.AuthSession, error) {
+	var retired model.RetiredRefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&retired).Error; err != nil {
+		return nil, err
+	}
+	return r.FindSessionByID(retired.SessionID)
+}
+
+// UpdateSession stores the session's new refresh token hash and expiry if its
+// token is still oldHash, and keeps oldHash as retired. It reports false when
+// another refresh rotated the token first.
+func (r *staffRepository) UpdateSession(s *model.AuthSession, oldHash string) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.AuthSession{}).
+			Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", s.ID, oldHash).
+			Updates(map[string]any{
+				"refresh_token_hash": s.RefreshTokenHash,
+				"expires_at":         s.ExpiresAt,
+				"updated_at":         s.UpdatedAt,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		rotated = true
+		return tx.Create(&model.RetiredRefreshToken{TokenHash: oldHash, SessionID: s.ID, RetiredAt: s.UpdatedAt}).Error
+	})
+	return rotated && err == nil, err
+}
+
+func (r *staffRepository) RevokeSession(id string, at time.Time) error {
+	return r.db.Model(&model.AuthSession{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at).Error
+}
@@ -2,14 +2,21 @@ package repository
 
 import (
 	"hms-backend/model"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionRepository interface {
 	Create(t *model.Transaction) error
 	GetByID(i string) (model.Transaction, error)
 	Update(t *model.Transaction) error
+	MigrateBookingLink() (int64, error)
+	FindByBookingID(bookingID string) ([]*model.Transaction, error)
+	FindByGatewayID(gatewayID string) (*model.Transaction, error)
+	CreateRefund(refund *model.Transaction, check func(payment *model.Transaction, refunded float64) error) error
+	SwapGatewayStatus(id uint, from, to model.GatewayStatus) (bool, error)
 }
 
 type transactionRepository struct {
@@ -26,10 +33,95 @@ func (r *transactionRepository) Create(t *model.Transaction) error {
 
 func (r *transactionRepository) GetByID(i string) (model.Transaction, error) {
 	var t model.Transaction
-	err := r.db.Preload("bookings").Table("transactions").Where("id = ?", i).First(&t).Error
+	err := r.db.Preload("Booking").Table("transactions").Where("id = ?", i).First(&t).Error
 	return t, err
 }
 
 func (r *transactionRepository) Update(t *model.Transaction) error {
 	return r.db.Model(&model.Transaction{}).Where("id = ?", t.Id).Updates(t).Error
 }
+
+// MigrateBookingLink prepares a transactions table from before booking_id
+// held the booking's char(26) ID. The old integer column could never point
+// at a booking, so it is kept as legacy_booking_id for manual reconciliation
+// and AutoMigrate creates the new column. It must run before AutoMigrate and
+// returns the number of transactions left to reconcile.
+func (r *transactionRepository) MigrateBookingLink() (int64, error) {
+	m := r.db.Migrator()
+	if !m.HasTable(&model.Transaction{}) || m.HasColumn(&model.Transaction{}, "legacy_booking_id") {
+		return 0, nil
+	}
+	columns, err := m.ColumnTypes(&model.Transaction{})
+	if err != nil {
+		return 0, err
+	}
+	legacy := false
+	for _, c := range columns {
+		if c.Name() == "booking_id" && strings.Contains(strings.ToLower(c.DatabaseTypeName()), "int") {
+			legacy = true
+		}
+	}
+	if !legacy {
+		return 0, nil
+	}
+	const fk = "fk_transactions_booking"
+	if m.HasConstraint(&model.Transaction{}, fk) {
+		if err := m.DropConstraint(&model.Transaction{}, fk); err != nil {
+			return 0, err
+		}
+	}
+	if err := m.RenameColumn(&model.Transaction{}, "booking_id", "legacy_booking_id"); err != nil {
+		return 0, err
+	}
+	var n int64
+	err = r.db.Table("transactions").Where("legacy_booking_id <> 0").Count(&n).Error
+	return n, err
+}
+
+func (r *transactionRepository) FindByBookingID(bookingID string) ([]*model.Transaction, error) {
+	var ts []*model.Transaction
+	err := r.db.Where("booking_id = ?", bookingID).Order("created_at, id").Find(&ts).Error
+	return ts, err
+}
+
+func (r *transactionRepository) FindByGatewayID(gatewayID string) (*model.Transaction, error) {
+	var t model.Transaction
+	err := r.db.Where("gateway_id = ?", gatewayID).First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SwapGatewayStatus moves the transaction from one gateway status to another
+// and reports false if it was no longer in from, e.g. because a concurrent
+// request claimed it first.
+func (r *transactionRepository) SwapGatewayStatus(id uint, from, to model.GatewayStatus) (bool, error) {
+	res := r.db.Model(&model.Transaction{}).Where("id = ? AND gateway_status = ?", id, from).
+		Update("gateway_status", to)
+	return res.RowsAffected > 0, res.Error
+}
+
+// CreateRefund stores a refund of the payment refund.RefundOfID. The payment
+// stays locked while check looks at what was already refunded from it, so
+// two refunds made at the same time cannot together exceed the payment.
+func (r *transactionRepository) CreateRefund(refund *model.Transaction, check func(payment *model.Transaction, refunded float64) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var payment model.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", refund.RefundOfID).First(&payment).Error; err != nil {
+			return err
+		}
+		var refunded float64
+		if err := tx.Model(&model.Transaction{}).
+			Where("refund_of_id = ? AND type = ?", payment.Id, model.TransactionRefund).
+			Where("gateway_status IS NULL OR gateway_status <> ?", model.GatewayFailed).
+			Select("COALESCE(SUM(amount), 0)").Scan(&refunded).Error; err != nil {
+			return err
+		}
+		if err := check(&payment, refunded); err != nil {
+			return err
+		}
+		return tx.Create(refund).Error
+	})
+}
@@ -0,0 +1,34 @@
+package request
+
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type CreateStaffRequest struct {
+	Username string `json:"username" binding:"required"`
+	FullName string `json:"full_name" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+	Role     string `json:"role" binding:"required"`
+}
+
+type ChangeStaffRoleRequest struct {
+	ID   uint   `json:"id" binding:"required"`
+	Role string `json:"role" binding:"required"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+
+	// ExpiresAt is optional and uses the RFC 3339 format.
+	ExpiresAt string `json:"expires_at"`
+}
+
+type RevokeAPIKeyRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
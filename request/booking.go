@@ -1,18 +1,63 @@
 package request
 
+import "hms-backend/model"
+
 type CreateBookingRequest struct {
 	RoomID       uint   `json:"room_id" binding:"required"`
 	GuestID      uint   `json:"guest_id" binding:"required"`
 	CheckInDate  string `json:"check_in_date" binding:"required"`
 	CheckOutDate string `json:"check_out_date" binding:"required"`
 	Notes        string `json:"notes"`
+
+	SpecialRequests []model.SpecialRequest `json:"special_requests"`
+
+	// CorporateAccountID books at the company's negotiated rate. DirectBill
+	// bills the room charges to the company at check-out.
+	CorporateAccountID *uint `json:"corporate_account_id"`
+	DirectBill         bool  `json:"direct_bill"`
+
+	// Adults defaults to 1 when omitted.
+	Adults    uint `json:"adults"`
+	Children  uint `json:"children"`
+	Infants   uint `json:"infants"`
+	ExtraBeds uint `json:"extra_beds"`
+}
+
+type UpdateSpecialRequestsRequest struct {
+	BookingReference string                 `json:"booking_id" binding:"required"`
+	SpecialRequests  []model.SpecialRequest `json:"special_requests"`
 }
 
 type CancelBookingRequest struct {
 	BookingReference string `json:"booking_id" binding:"required"`
 	Reason           string `json:"reason" binding:"required"`
+
+	// RefundAmount limits what is refunded from the payments made; by
+	// default all of it is refunded.
+	RefundAmount *float64 `json:"refund_amount" binding:"omitempty,gte=0"`
 }
 
 type CheckInCheckoutRequest struct {
 	BookingReference string `json:"booking_id" binding:"required"`
 }
+
+type AddOccupantRequest struct {
+	BookingReference string `json:"booking_id" binding:"required"`
+	AgeCategory      string `json:"age_category" binding:"required"`
+
+	// Either GuestID or the identity fields below describe the occupant.
+	GuestID        *uint  `json:"guest_id"`
+	FullName       string `json:"full_name"`
+	CredentialType string `json:"credential_type"`
+	IDNumber       string `json:"id_number"`
+	IssuingCountry string `json:"issuing_country"`
+	DocumentExpiry string `json:"document_expiry"`
+}
+
+// DepositPaymentRequest records a prepayment. Without an amount, whatever is
+// due next is paid.
+type DepositPaymentRequest struct {
+	BookingReference string  `json:"booking_id" binding:"required"`
+	Amount           float64 `json:"amount" binding:"omitempty,gt=0"`
+	PaymentMethod    string  `json:"payment_method" binding:"required"`
+}
@@ -0,0 +1,30 @@
+package request
+
+type CorporateAccountRequest struct {
+	Code           string `json:"code" binding:"required,max=20"`
+	Name           string `json:"name" binding:"required"`
+	Type           string `json:"type" binding:"omitempty,oneof=company travel_agent"`
+	ContactEmail   string `json:"contact_email" binding:"omitempty,email"`
+	BillingAddress string `json:"billing_address"`
+
+	// Discounts are percentages off RoomType.Price. RoomTypeDiscounts is
+	// keyed by room type ID and overrides DiscountPercent.
+	DiscountPercent   float64          `json:"discount_percent" binding:"gte=0,lt=100"`
+	RoomTypeDiscounts map[uint]float64 `json:"room_type_discounts"`
+
+	// CreditLimit of zero disables direct billing.
+	CreditLimit float64 `json:"credit_limit" binding:"gte=0"`
+
+	// Active defaults to true.
+	Active *bool `json:"active"`
+}
+
+type UpdateCorporateAccountRequest struct {
+	ID uint `json:"id" binding:"required"`
+	CorporateAccountRequest
+}
+
+type CorporateGuestRequest struct {
+	AccountID uint `json:"account_id" binding:"required"`
+	GuestID   uint `json:"guest_id" binding:"required"`
+}
@@ -0,0 +1,13 @@
+package request
+
+import "time"
+
+// DateLayout is the format of every calendar date accepted by the API.
+const DateLayout = "2006-01-02"
+
+// ParseDate parses a calendar date such as a check-in day.
+// Calendar dates are kept at midnight UTC so they compare equally no matter
+// which timezone the server or the property is in.
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, s, time.UTC)
+}
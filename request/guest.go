@@ -1,9 +1,45 @@
 package request
 
+import "hms-backend/model"
+
 type GuestRequest struct {
 	CredentialType string `json:"credential_type" binding:"required"`
 	IDNumber       string `json:"id_number" binding:"required"`
+	IssuingCountry string `json:"issuing_country"`
+	// DocumentExpiry is a calendar date, YYYY-MM-DD.
+	DocumentExpiry string `json:"document_expiry"`
 	FullName       string `json:"full_name" binding:"required"`
 	Phone          string `json:"phone_number" binding:"required"`
 	Email          string `json:"email"`
+
+	// Preferences replaces the stored preferences; omit it to keep them.
+	Preferences *model.GuestPreferences `json:"preferences"`
+}
+
+type MergeGuestRequest struct {
+	SurvivorID  uint `json:"survivor_id" binding:"required"`
+	DuplicateID uint `json:"duplicate_id" binding:"required,nefield=SurvivorID"`
+}
+
+// Guest search sort orders.
+const (
+	GuestSortRelevance = "relevance"
+	GuestSortName      = "name"
+	GuestSortRecent    = "recent"
+)
+
+type GuestSearchParams struct {
+	Query    string `form:"q" binding:"required,min=2"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=relevance name recent"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type CreateGuestFlagRequest struct {
+	GuestID uint   `json:"guest_id" binding:"required"`
+	Type    string `json:"type" binding:"required"`
+	Reason  string `json:"reason" binding:"required"`
+
+	// ExpiresAt is optional, RFC 3339. Flags without it stay until removed.
+	ExpiresAt string `json:"expires_at"`
 }
@@ -0,0 +1,14 @@
+package request
+
+type EnrollLoyaltyRequest struct {
+	GuestID uint `json:"guest_id" binding:"required"`
+}
+
+type CreditStayRequest struct {
+	BookingReference string `json:"booking_id" binding:"required"`
+}
+
+type RedeemPointsRequest struct {
+	BookingReference string `json:"booking_id" binding:"required"`
+	Points           int    `json:"points" binding:"required,gt=0"`
+}
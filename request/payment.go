@@ -0,0 +1,26 @@
+package request
+
+// CardRequest carries card details to be exchanged for a gateway token.
+type CardRequest struct {
+	Number     string `json:"number" binding:"required"`
+	HolderName string `json:"holder_name" binding:"required"`
+	ExpMonth   int    `json:"exp_month" binding:"required,min=1,max=12"`
+	ExpYear    int    `json:"exp_year" binding:"required,min=2000"`
+	CVC        string `json:"cvc" binding:"required,min=3,max=4"`
+}
+
+// AuthorizePaymentRequest holds an amount on a tokenised card for a booking.
+// With Capture set the amount is taken straight away.
+type AuthorizePaymentRequest struct {
+	BookingReference string  `json:"booking_id" binding:"required"`
+	Amount           float64 `json:"amount" binding:"required,gt=0"`
+	CardToken        string  `json:"card_token" binding:"required"`
+	Capture          bool    `json:"capture"`
+}
+
+// CapturePaymentRequest takes an authorized card payment. Without an amount
+// all of it is captured.
+type CapturePaymentRequest struct {
+	TransactionID uint    `json:"transaction_id" binding:"required"`
+	Amount        float64 `json:"amount" binding:"omitempty,gt=0"`
+}
@@ -0,0 +1,21 @@
+package request
+
+type PropertyRequest struct {
+	Code         string `json:"code" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	Address      string `json:"address"`
+	Timezone     string `json:"timezone" binding:"required"`
+	Currency     string `json:"currency" binding:"required,len=3"`
+	CheckInTime  string `json:"check_in_time" binding:"required"`
+	CheckOutTime string `json:"check_out_time" binding:"required"`
+
+	// TaxRate is a percentage included in room prices.
+	TaxName   string  `json:"tax_name"`
+	TaxRate   float64 `json:"tax_rate" binding:"gte=0,lt=100"`
+	TaxNumber string  `json:"tax_number"`
+}
+
+type UpdatePropertyRequest struct {
+	ID uint `json:"id" binding:"required"`
+	PropertyRequest
+}
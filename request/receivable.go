@@ -0,0 +1,38 @@
+package request
+
+type InvoiceTransactionRequest struct {
+	TransactionID uint `json:"transaction_id" binding:"required"`
+	AccountID     uint `json:"account_id" binding:"required"`
+}
+
+type ReceivablePaymentRequest struct {
+	AccountID     uint    `json:"account_id" binding:"required"`
+	Amount        float64 `json:"amount" binding:"required,gt=0"`
+	PaymentMethod string  `json:"payment_method" binding:"required"`
+	Reference     string  `json:"reference"`
+
+	// ReceivedOn is a calendar date, YYYY-MM-DD; it defaults to today.
+	ReceivedOn string `json:"received_on"`
+
+	// Allocations must add up to Amount. Without them the payment settles
+	// the oldest open invoices first.
+	Allocations []ReceivableAllocationRequest `json:"allocations" binding:"dive"`
+}
+
+type ReceivableAllocationRequest struct {
+	InvoiceID uint    `json:"invoice_id" binding:"required"`
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+}
+
+type CreditNoteRequest struct {
+	InvoiceID uint    `json:"invoice_id" binding:"required"`
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Reason    string  `json:"reason" binding:"required"`
+}
+
+// StatementParams are calendar dates, YYYY-MM-DD. The statement defaults to
+// the current month up to today.
+type StatementParams struct {
+	From string `form:"from"`
+	To   string `form:"to"`
+}
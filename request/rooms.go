@@ -3,9 +3,11 @@ package request
 import "time"
 
 type CreateRoomRequest struct {
+	PropertyID uint   `json:"property_id" binding:"required"`
 	Number     string `json:"number" binding:"required"`
 	Status     string `json:"status" binding:"required"`
 	RoomTypeID int    `json:"room_type_id" binding:"required"`
+	RoomAttributes
 }
 
 type UpdateRoomRequest struct {
@@ -13,6 +15,19 @@ type UpdateRoomRequest struct {
 	Number     string `json:"number" binding:"required"`
 	Status     string `json:"status" binding:"required"`
 	RoomTypeID uint   `json:"room_type_id" binding:"required"`
+	RoomAttributes
+}
+
+// RoomAttributes holds the optional physical attributes shared by create and update.
+type RoomAttributes struct {
+	Floor             int      `json:"floor"`
+	Building          string   `json:"building"`
+	View              string   `json:"view"`
+	BedConfiguration  string   `json:"bed_configuration"`
+	Smoking           bool     `json:"smoking"`
+	Accessible        bool     `json:"accessible"`
+	ConnectingRoomIDs []uint   `json:"connecting_room_ids"`
+	Amenities         []string `json:"amenities"`
 }
 
 type ChangeRoomStatusRequest struct {
@@ -20,18 +35,70 @@ type ChangeRoomStatusRequest struct {
 }
 
 type CreateRoomTypeRequest struct {
-	Price       float64 `json:"price"`
-	Capacity    uint    `json:"capacity"`
+	PropertyID  uint    `json:"property_id" binding:"required"`
+	Price       float64 `json:"price" binding:"required,gt=0"`
+	Capacity    uint    `json:"capacity" binding:"required,gt=0"`
 	Description string  `json:"description"`
-	Name        string  `json:"name"`
+	Name        string  `json:"name" binding:"required"`
+	OccupancyRules
+	DepositPolicy
+}
+
+type UpdateRoomTypeRequest struct {
+	ID          uint    `json:"id" binding:"required"`
+	Price       float64 `json:"price" binding:"required,gt=0"`
+	Capacity    uint    `json:"capacity" binding:"required,gt=0"`
+	Description string  `json:"description"`
+	Name        string  `json:"name" binding:"required"`
+	OccupancyRules
+	DepositPolicy
+}
+
+// OccupancyRules holds the optional extra bed and supplement settings of a room type.
+type OccupancyRules struct {
+	BaseOccupancy   uint    `json:"base_occupancy"`
+	MaxExtraBeds    uint    `json:"max_extra_beds"`
+	ExtraBedPrice   float64 `json:"extra_bed_price" binding:"gte=0"`
+	ExtraAdultPrice float64 `json:"extra_adult_price" binding:"gte=0"`
+	ChildPrice      float64 `json:"child_price" binding:"gte=0"`
+}
+
+// DepositPolicy holds the optional prepayment settings of a room type.
+type DepositPolicy struct {
+	DepositPercent float64 `json:"deposit_percent" binding:"gte=0,lte=100"`
+	DepositDueDays uint    `json:"deposit_due_days"`
+	BalanceDueDays uint    `json:"balance_due_days"`
+}
+
+type RoomTypeIDRequest struct {
+	ID uint `json:"id" binding:"required"`
 }
 
 type RoomFilterParams struct {
-	CheckIn  time.Time
-	CheckOut time.Time
-	Category string
-	MinPrice float64
-	MaxPrice float64
+	// PropertyID limits the search to one hotel; 0 searches every property.
+	PropertyID uint
+	CheckIn    time.Time
+	CheckOut   time.Time
+	Category   string
+	MinPrice   float64
+	MaxPrice   float64
+
+	// Attribute filters; zero values mean "no preference".
+	MinFloor         int
+	MaxFloor         int
+	Building         string
+	View             string
+	BedConfiguration string
+	Smoking          *bool
+	Accessible       *bool
+	Amenities        []string
+
+	// Party size; rooms whose type cannot hold the party even with extra beds are excluded.
+	Adults   uint
+	Children uint
+
+	// GuestID ranks the rooms by that guest's preferences.
+	GuestID uint
 }
 
 type ChangeStatus struct {
@@ -0,0 +1,30 @@
+package request
+
+type CreateTransactionRequest struct {
+	BookingReference string  `json:"booking_id" binding:"required"`
+	Amount           float64 `json:"amount" binding:"required,gt=0"`
+	PaymentMethod    string  `json:"payment_method" binding:"required"`
+}
+
+type TransactionIDRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// RefundRequest refunds a paid payment. Without an amount, all that is left
+// of the payment is refunded.
+type RefundRequest struct {
+	TransactionID uint    `json:"transaction_id" binding:"required"`
+	Amount        float64 `json:"amount" binding:"omitempty,gt=0"`
+	Reason        string  `json:"reason" binding:"required"`
+}
+
+type IssueInvoiceRequest struct {
+	BookingReference string `json:"booking_id" binding:"required"`
+}
+
+// VoidInvoiceRequest cancels a booking's invoice with a credit note, e.g. to
+// correct it, after which a new invoice can be issued.
+type VoidInvoiceRequest struct {
+	BookingReference string `json:"booking_id" binding:"required"`
+	Reason           string `json:"reason" binding:"required"`
+}
@@ -0,0 +1,16 @@
+package response
+
+import (
+	"hms-backend/model"
+	"time"
+)
+
+type AuditLogResponse struct {
+	ID         uint                         `json:"id"`
+	Actor      string                       `json:"actor"`
+	EntityType string                       `json:"entity_type"`
+	EntityID   string                       `json:"entity_id"`
+	Action     model.AuditAction            `json:"action"`
+	Changes    map[string]model.FieldChange `json:"changes"`
+	CreatedAt  time.Time                    `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package response
+
+import (
+	"hms-backend/model"
+	"time"
+)
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type StaffResponse struct {
+	ID       uint       `json:"id"`
+	Username string     `json:"username"`
+	FullName string     `json:"full_name"`
+	Role     model.Role `json:"role"`
+	Active   bool       `json:"active"`
+}
+
+type APIKeyResponse struct {
+	ID          uint               `json:"id"`
+	Name        string             `json:"name"`
+	Prefix      string             `json:"prefix"`
+	Permissions []model.Permission `json:"permissions"`
+	LastUsedAt  *time.Time         `json:"last_used_at"`
+	UsageCount  uint64             `json:"usage_count"`
+	ExpiresAt   *time.Time         `json:"expires_at"`
+	RevokedAt   *time.Time         `json:"revoked_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+
+	// Key is the plain API key, only returned once when the key is created.
+	Key string `json:"key,omitempty"`
+}
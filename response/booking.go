@@ -3,15 +3,59 @@ package response
 import "hms-backend/model"
 
 type BookingResponse struct {
-	BookingID      string                              `json:"booking_id" binding:"required"`
-	CheckInDate    string                              `json:"check_in_date" binding:"required"`
-	CheckOutDate   string                              `json:"check_out_date" binding:"required"`
-	Status         model.BookingStatus                 `json:"status"`
-	Notes          string                              `json:"notes"`
+	BookingID       string                 `json:"booking_id" binding:"required"`
+	PropertyID      uint                   `json:"property_id"`
+	CheckInDate     string                 `json:"check_in_date" binding:"required"`
+	CheckOutDate    string                 `json:"check_out_date" binding:"required"`
+	Status          model.BookingStatus    `json:"status"`
+	Notes           string                 `json:"notes"`
+	SpecialRequests []model.SpecialRequest `json:"special_requests"`
+	Adults          uint                   `json:"adults"`
+	Children        uint                   `json:"children"`
+	Infants         uint                   `json:"infants"`
+	ExtraBeds       uint                   `json:"extra_beds"`
+	NightlyRate     float64                `json:"nightly_rate"`
+
+	CorporateAccountID *uint   `json:"corporate_account_id,omitempty"`
+	CorporateDiscount  float64 `json:"corporate_discount,omitempty"`
+	DirectBill         bool    `json:"direct_bill"`
+
+	DepositAmount  float64 `json:"deposit_amount,omitempty"`
+	DepositDueDate string  `json:"deposit_due_date,omitempty"`
+	BalanceDueDate string  `json:"balance_due_date,omitempty"`
+
 	AdditionalInfo AdditionalInfoCreateBookingResponse `json:"additionalInfo"`
+
+	// PointsEarned is set at check-out when the guest is a loyalty member.
+	PointsEarned int `json:"points_earned,omitempty"`
+
+	// Refunded is what was given back when the booking was cancelled.
+	Refunded float64 `json:"refunded,omitempty"`
+
+	// Warnings come from guest flags and are only shown to roles that may read flags.
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 type AdditionalInfoCreateBookingResponse struct {
-	Room  RoomResponse  `json:"room"`
-	Guest GuestResponse `json:"guest"`
+	Room      RoomResponse       `json:"room"`
+	Guest     GuestResponse      `json:"guest"`
+	Occupants []OccupantResponse `json:"occupants"`
+}
+
+type OccupantResponse struct {
+	ID             uint              `json:"id"`
+	GuestID        *uint             `json:"guest_id,omitempty"`
+	FullName       string            `json:"full_name"`
+	CredentialType string            `json:"credential_type"`
+	IDNumber       string            `json:"id_number"`
+	IssuingCountry string            `json:"issuing_country"`
+	DocumentExpiry string            `json:"document_expiry,omitempty"`
+	AgeCategory    model.AgeCategory `json:"age_category"`
+}
+
+// DepositExpiryResponse lists the pending bookings a deposit check confirmed
+// or cancelled, by reference.
+type DepositExpiryResponse struct {
+	Confirmed []string `json:"confirmed"`
+	Cancelled []string `json:"cancelled"`
 }
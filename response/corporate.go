@@ -0,0 +1,35 @@
+package response
+
+import "hms-backend/model"
+
+type CorporateAccountResponse struct {
+	ID                uint                     `json:"id"`
+	Code              string                   `json:"code"`
+	Name              string                   `json:"name"`
+	Type              model.BillingAccountType `json:"type"`
+	ContactEmail      string                   `json:"contact_email"`
+	BillingAddress    string                   `json:"billing_address"`
+	DiscountPercent   float64                  `json:"discount_percent"`
+	RoomTypeDiscounts map[string]float64       `json:"room_type_discounts"`
+	CreditLimit       float64                  `json:"credit_limit"`
+	Active            bool                     `json:"active"`
+
+	// Credit and Guests are only filled in when a single account is read.
+	Credit *CorporateCreditResponse `json:"credit,omitempty"`
+	Guests []CorporateGuestResponse `json:"guests,omitempty"`
+}
+
+// CorporateCreditResponse splits the credit limit into what is billed and
+// unpaid, what is held by direct-billed stays not checked out yet, and what
+// is left.
+type CorporateCreditResponse struct {
+	Limit     float64 `json:"limit"`
+	Billed    float64 `json:"billed"`
+	Pending   float64 `json:"pending"`
+	Available float64 `json:"available"`
+}
+
+type CorporateGuestResponse struct {
+	GuestID  uint   `json:"guest_id"`
+	FullName string `json:"full_name"`
+}
@@ -1,10 +1,97 @@
 package response
 
+import (
+	"hms-backend/model"
+	"time"
+)
+
 type GuestResponse struct {
 	CredentialType string `json:"credential_type" binding:"required"`
 	IDNumber       string `json:"id_number" binding:"required"`
+	IssuingCountry string `json:"issuing_country"`
+	DocumentExpiry string `json:"document_expiry,omitempty"`
 	FullName       string `json:"full_name" binding:"required"`
 	Phone          string `json:"phone_number" binding:"required"`
 	Email          string `json:"email"`
 	ID             uint   `json:"id" binding :"required"`
+
+	Preferences model.GuestPreferences `json:"preferences"`
+}
+
+// GuestDataExport bundles everything stored about a guest, for data subject access requests.
+type GuestDataExport struct {
+	ExportedAt   time.Time              `json:"exported_at"`
+	Guest        GuestResponse          `json:"guest"`
+	Bookings     []*BookingResponse     `json:"bookings"`
+	Transactions []*TransactionResponse `json:"transactions"`
+}
+
+// GuestDuplicateResponse is a possible duplicate profile with the reasons it matched.
+type GuestDuplicateResponse struct {
+	Guest   GuestResponse `json:"guest"`
+	Score   int           `json:"score"`
+	Reasons []string      `json:"reasons"`
+}
+
+// StaySummary is a short view of a booking, shown next to a guest.
+type StaySummary struct {
+	BookingID    string              `json:"booking_id"`
+	PropertyID   uint                `json:"property_id"`
+	RoomNumber   string              `json:"room_number"`
+	CheckInDate  string              `json:"check_in_date"`
+	CheckOutDate string              `json:"check_out_date"`
+	Status       model.BookingStatus `json:"status"`
+}
+
+type GuestSearchResult struct {
+	Guest       GuestResponse `json:"guest"`
+	MatchedOn   []string      `json:"matched_on"`
+	RecentStays []StaySummary `json:"recent_stays"`
+}
+
+type GuestSearchResponse struct {
+	Results  []GuestSearchResult `json:"results"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	Total    int                 `json:"total"`
+}
+
+type GuestHistoryResponse struct {
+	Guest         GuestResponse      `json:"guest"`
+	Bookings      []*BookingResponse `json:"bookings"`
+	TotalStays    int                `json:"total_stays"`
+	TotalNights   int                `json:"total_nights"`
+	Revenue       []RevenueResponse  `json:"revenue"`
+	Cancellations int                `json:"cancellations"`
+	NoShows       int                `json:"no_shows"`
+	LastStayDate  string             `json:"last_stay_date,omitempty"`
+	RepeatGuest   bool               `json:"repeat_guest"`
+}
+
+// RevenueResponse is the revenue in one currency. Amounts in different
+// currencies are never added up.
+type RevenueResponse struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+type GuestFlagResponse struct {
+	ID        uint                `json:"id"`
+	GuestID   uint                `json:"guest_id"`
+	Type      model.GuestFlagType `json:"type"`
+	Reason    string              `json:"reason"`
+	Active    bool                `json:"active"`
+	ExpiresAt *time.Time          `json:"expires_at,omitempty"`
+	CreatedBy string              `json:"created_by"`
+	CreatedAt time.Time           `json:"created_at"`
+	RemovedAt *time.Time          `json:"removed_at,omitempty"`
+	RemovedBy string              `json:"removed_by,omitempty"`
+}
+
+type CredentialTypeResponse struct {
+	Type            model.CredentialType `json:"type"`
+	Name            string               `json:"name"`
+	Format          string               `json:"format"`
+	RequiresExpiry  bool                 `json:"requires_expiry"`
+	RequiresCountry bool                 `json:"requires_issuing_country"`
 }
@@ -0,0 +1,56 @@
+package response
+
+import (
+	"hms-backend/model"
+	"time"
+)
+
+type LoyaltyAccountResponse struct {
+	GuestID       uint              `json:"guest_id"`
+	MemberNumber  string            `json:"member_number"`
+	Tier          model.LoyaltyTier `json:"tier"`
+	TierReachedAt time.Time         `json:"tier_reached_at"`
+	Balance       int               `json:"balance"`
+	BalanceValue  float64           `json:"balance_value"`
+
+	// The points lapsing first and the day they do.
+	PointsExpiring int    `json:"points_expiring"`
+	NextExpiry     string `json:"next_expiry,omitempty"`
+
+	// Nights and room spend of checked-out stays in the qualifying period.
+	QualifyingNights int               `json:"qualifying_nights"`
+	QualifyingSpend  float64           `json:"qualifying_spend"`
+	NextTier         *NextTierResponse `json:"next_tier,omitempty"`
+
+	EnrolledAt time.Time `json:"enrolled_at"`
+}
+
+// NextTierResponse tells how far the member is from the next tier; either
+// the nights or the spend is enough.
+type NextTierResponse struct {
+	Tier         model.LoyaltyTier `json:"tier"`
+	NightsNeeded int               `json:"nights_needed"`
+	SpendNeeded  float64           `json:"spend_needed"`
+}
+
+type LoyaltyEntryResponse struct {
+	ID            uint                   `json:"id"`
+	Type          model.LoyaltyEntryType `json:"type"`
+	Points        int                    `json:"points"`
+	Remaining     *int                   `json:"remaining,omitempty"`
+	ExpiresAt     string                 `json:"expires_at,omitempty"`
+	BookingID     *string                `json:"booking_id,omitempty"`
+	TransactionID *uint                  `json:"transaction_id,omitempty"`
+	Description   string                 `json:"description"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+type LoyaltyEarningResponse struct {
+	PointsEarned int                    `json:"points_earned"`
+	Account      LoyaltyAccountResponse `json:"account"`
+}
+
+type LoyaltyRedemptionResponse struct {
+	Transaction TransactionResponse    `json:"transaction"`
+	Account     LoyaltyAccountResponse `json:"account"`
+}
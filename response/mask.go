@@ -0,0 +1,62 @@
+package response
+
+import "hms-backend/model"
+
+// maskValue keeps only the last four characters of sensitive values.
+func maskValue(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// MaskPII hides the identity document number and the preferences, which
+// include allergies and accessibility needs, for callers without PII access.
+func (g *GuestResponse) MaskPII() {
+	if g == nil {
+		return
+	}
+	if g.IDNumber != "" {
+		g.IDNumber = maskValue(g.IDNumber)
+	}
+	g.Preferences = model.GuestPreferences{}
+}
+
+// MaskPII hides the guest's PII and the identity document numbers of every occupant.
+func (b *BookingResponse) MaskPII() {
+	if b == nil {
+		return
+	}
+	b.AdditionalInfo.Guest.MaskPII()
+	for i := range b.AdditionalInfo.Occupants {
+		if b.AdditionalInfo.Occupants[i].IDNumber != "" {
+			b.AdditionalInfo.Occupants[i].IDNumber = maskValue(b.AdditionalInfo.Occupants[i].IDNumber)
+		}
+	}
+}
+
+func (d *GuestDuplicateResponse) MaskPII() {
+	if d == nil {
+		return
+	}
+	d.Guest.MaskPII()
+}
+
+func (s *GuestSearchResponse) MaskPII() {
+	if s == nil {
+		return
+	}
+	for i := range s.Results {
+		s.Results[i].Guest.MaskPII()
+	}
+}
+
+func (h *GuestHistoryResponse) MaskPII() {
+	if h == nil {
+		return
+	}
+	h.Guest.MaskPII()
+	for _, b := range h.Bookings {
+		b.MaskPII()
+	}
+}
@@ -0,0 +1,10 @@
+package response
+
+// CardTokenResponse stands in for a card; the card number itself is not kept.
+type CardTokenResponse struct {
+	Token    string `json:"token"`
+	Brand    string `json:"brand"`
+	Last4    string `json:"last4"`
+	ExpMonth int    `json:"exp_month"`
+	ExpYear  int    `json:"exp_year"`
+}
@@ -0,0 +1,23 @@
+package response
+
+type PropertyResponse struct {
+	ID           uint   `json:"id"`
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	Address      string `json:"address"`
+	Timezone     string `json:"timezone"`
+	Currency     string `json:"currency"`
+	CheckInTime  string `json:"check_in_time"`
+	CheckOutTime string `json:"check_out_time"`
+
+	TaxName   string  `json:"tax_name"`
+	TaxRate   float64 `json:"tax_rate"`
+	TaxNumber string  `json:"tax_number"`
+}
+
+type BusinessDateResponse struct {
+	PropertyID   uint   `json:"property_id"`
+	Timezone     string `json:"timezone"`
+	BusinessDate string `json:"business_date"`
+	LocalTime    string `json:"local_time"`
+}
@@ -0,0 +1,74 @@
+package response
+
+import (
+	"hms-backend/model"
+	"time"
+)
+
+type ReceivableEntryResponse struct {
+	ID            uint                      `json:"id"`
+	AccountID     uint                      `json:"account_id"`
+	Type          model.ReceivableEntryType `json:"type"`
+	Amount        float64                   `json:"amount"`
+	PostedOn      string                    `json:"posted_on"`
+	TransactionID *uint                     `json:"transaction_id,omitempty"`
+	BookingID     *string                   `json:"booking_id,omitempty"`
+	PaymentMethod string                    `json:"payment_method,omitempty"`
+	Reference     string                    `json:"reference,omitempty"`
+	Notes         string                    `json:"notes,omitempty"`
+
+	// Open is only set on invoices.
+	Open        *float64                       `json:"open,omitempty"`
+	Allocations []ReceivableAllocationResponse `json:"allocations,omitempty"`
+
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReceivableAllocationResponse struct {
+	InvoiceID uint    `json:"invoice_id"`
+	Amount    float64 `json:"amount"`
+}
+
+type StatementResponse struct {
+	Account        CorporateAccountResponse `json:"account"`
+	From           string                   `json:"from"`
+	To             string                   `json:"to"`
+	OpeningBalance float64                  `json:"opening_balance"`
+	Lines          []StatementLine          `json:"lines"`
+	ClosingBalance float64                  `json:"closing_balance"`
+}
+
+// StatementLine is one ledger entry; invoices are debits, payments and
+// credit notes credits. Balance is the running balance after the line.
+type StatementLine struct {
+	Date        string                    `json:"date"`
+	EntryID     uint                      `json:"entry_id"`
+	Type        model.ReceivableEntryType `json:"type"`
+	Description string                    `json:"description"`
+	Debit       float64                   `json:"debit"`
+	Credit      float64                   `json:"credit"`
+	Balance     float64                   `json:"balance"`
+}
+
+type AgingBuckets struct {
+	Current    float64 `json:"current"`
+	Days30     float64 `json:"30"`
+	Days60     float64 `json:"60"`
+	Days90Plus float64 `json:"90+"`
+	Total      float64 `json:"total"`
+}
+
+type AgingAccount struct {
+	AccountID uint                     `json:"account_id"`
+	Code      string                   `json:"code"`
+	Name      string                   `json:"name"`
+	Type      model.BillingAccountType `json:"type"`
+	AgingBuckets
+}
+
+type AgingResponse struct {
+	AsOf     string         `json:"as_of"`
+	Accounts []AgingAccount `json:"accounts"`
+	Totals   AgingBuckets   `json:"totals"`
+}
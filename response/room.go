@@ -3,16 +3,38 @@ package response
 import "hms-backend/model"
 
 type RoomResponse struct {
-	ID       uint             `json:"id"`
-	Number   string           `json:"number"`
-	Status   model.RoomStatus `json:"status"`
-	RoomType RoomTypeDetail   `json:"room_type"`
+	ID                uint                   `json:"id"`
+	PropertyID        uint                   `json:"property_id"`
+	Number            string                 `json:"number"`
+	Status            model.RoomStatus       `json:"status"`
+	Floor             int                    `json:"floor"`
+	Building          string                 `json:"building"`
+	View              string                 `json:"view"`
+	BedConfiguration  model.BedConfiguration `json:"bed_configuration"`
+	Smoking           bool                   `json:"smoking"`
+	Accessible        bool                   `json:"accessible"`
+	ConnectingRoomIDs []uint                 `json:"connecting_room_ids"`
+	Amenities         []string               `json:"amenities"`
+	RoomType          RoomTypeDetail         `json:"room_type"`
+
+	// PreferenceMatches is only set when searching for a specific guest.
+	PreferenceMatches []string `json:"preference_matches,omitempty"`
 }
 
 type RoomTypeDetail struct {
-	ID          uint    `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Capacity    uint    `json:"capacity"`
-	Price       float64 `json:"price"`
+	ID              uint    `json:"id"`
+	PropertyID      uint    `json:"property_id"`
+	Name            string  `json:"name"`
+	Description     string  `json:"description"`
+	Capacity        uint    `json:"capacity"`
+	Price           float64 `json:"price"`
+	BaseOccupancy   uint    `json:"base_occupancy"`
+	MaxExtraBeds    uint    `json:"max_extra_beds"`
+	ExtraBedPrice   float64 `json:"extra_bed_price"`
+	ExtraAdultPrice float64 `json:"extra_adult_price"`
+	ChildPrice      float64 `json:"child_price"`
+	DepositPercent  float64 `json:"deposit_percent"`
+	DepositDueDays  uint    `json:"deposit_due_days"`
+	BalanceDueDays  uint    `json:"balance_due_days"`
+	Retired         bool    `json:"retired"`
 }
@@ -0,0 +1,49 @@
+package response
+
+import (
+	"hms-backend/model"
+	"time"
+)
+
+type TransactionResponse struct {
+	ID            uint                  `json:"id"`
+	BookingID     string                `json:"booking_id"`
+	Type          model.TransactionType `json:"type"`
+	Amount        float64               `json:"amount"`
+	PaymentMethod string                `json:"payment_method"`
+	Paid          bool                  `json:"paid"`
+
+	// CorporateAccountID is set on charges billed to a company.
+	CorporateAccountID *uint `json:"corporate_account_id,omitempty"`
+
+	// RefundOfID is the payment a refund was made from.
+	RefundOfID *uint  `json:"refund_of_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+
+	// Card payments and refunds made through the payment gateway.
+	GatewayID     string              `json:"gateway_id,omitempty"`
+	GatewayStatus model.GatewayStatus `json:"gateway_status,omitempty"`
+	CardBrand     string              `json:"card_brand,omitempty"`
+	CardLast4     string              `json:"card_last4,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InvoiceResponse struct {
+	Number     string     `json:"number"`
+	BookingID  string     `json:"booking_id"`
+	IssuedOn   string     `json:"issued_on"`
+	Total      float64    `json:"total"`
+	BalanceDue float64    `json:"balance_due"`
+	CreditOf   string     `json:"credit_of,omitempty"`
+	VoidedAt   *time.Time `json:"voided_at,omitempty"`
+	IssuedBy   string     `json:"issued_by"`
+}
+
+// InvoiceFileResponse is a rendered invoice, sent as a download rather than JSON.
+type InvoiceFileResponse struct {
+	Number      string
+	FileName    string
+	ContentType string
+	Content     []byte
+}
@@ -1,63 +1,221 @@
 package routes
 
 import (
+	"hms-backend/config"
 	"hms-backend/handler"
+	"hms-backend/middleware"
+	"hms-backend/model"
 	"hms-backend/repository"
 	"hms-backend/services"
+	"log"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(router *gin.Engine, db *gorm.DB) {
+// RegisterRoutes wires the API. It returns the booking services, which the
+// background jobs started by main share.
+func RegisterRoutes(router *gin.Engine, db *gorm.DB, authConfig config.AuthConfig, paymentConfig config.PaymentConfig, keyring *repository.Keyring) services.BookingServices {
 	// Initialize Repositories, Services, Handlers
+	clock := services.NewSystemClock()
+
+	staffRepository := repository.NewStaffRepository(db)
+	apiKeyRepository := repository.NewAPIKeyRepository(db)
+	authServices := services.NewAuthServices(staffRepository, apiKeyRepository, clock, authConfig.JWTSecret,
+		authConfig.AccessTokenTTL, authConfig.RefreshTokenTTL)
+	authHandler := handler.NewAuthHandler(authServices)
+	requireAuth := middleware.RequireAuth(authServices)
+	can := middleware.RequirePermission
+
+	propertyRepository := repository.NewPropertyRepository(db)
+	propertyServices := services.NewPropertyServices(propertyRepository, clock)
+	propertyHandler := handler.NewPropertyHandler(propertyServices)
+
+	auditRepository := repository.NewAuditRepository(db)
+	auditServices := services.NewAuditServices(auditRepository, clock)
+	auditHandler := handler.NewAuditHandler(auditServices)
+
+	guestRepository := repository.NewGuestRepository(db, keyring)
 	roomRepository := repository.NewRoomRepository(db)
-	roomServices := services.NewRoomServices(roomRepository)
+	roomServices := services.NewRoomServices(roomRepository, guestRepository, propertyServices, auditServices, clock)
 	roomHandler := handler.NewRoomHandler(roomServices)
 
-	guestRepository := repository.NewGuestRepository(db)
-	guestServices := services.NewGuestServices(guestRepository)
+	bookingRepository := repository.NewBookingRepository(db)
+	transactionRepository := repository.NewTransactionRepository(db)
+
+	guestServices := services.NewGuestServices(guestRepository, bookingRepository, transactionRepository, propertyServices, auditServices, clock)
 	guestHandler := handler.NewGuestHandler(guestServices)
 
-	bookingRepository := repository.NewBookingRepository(db)
-	bookingServices := services.NewBookingServices(bookingRepository, roomServices, guestServices)
+	loyaltyRepository := repository.NewLoyaltyRepository(db)
+	loyaltyServices := services.NewLoyaltyServices(loyaltyRepository, guestRepository, bookingRepository, transactionRepository, auditServices, clock)
+	loyaltyHandler := handler.NewLoyaltyHandler(loyaltyServices)
+
+	corporateRepository := repository.NewCorporateRepository(db)
+	receivableRepository := repository.NewReceivableRepository(db)
+	receivableServices := services.NewReceivableServices(receivableRepository, corporateRepository, transactionRepository, auditServices, clock)
+	receivableHandler := handler.NewReceivableHandler(receivableServices)
+
+	corporateServices := services.NewCorporateServices(corporateRepository, guestRepository, transactionRepository, receivableServices, auditServices, clock)
+	corporateHandler := handler.NewCorporateHandler(corporateServices)
+
+	gateway, err := services.NewPaymentGateway(paymentConfig, clock)
+	if err != nil {
+		log.Fatal("⚠️ Invalid payment settings: ", err)
+	}
+	transactionServices := services.NewTransactionServices(transactionRepository, bookingRepository, gateway, auditServices)
+	transactionHandler := handler.NewTransactionHandler(transactionServices)
+
+	bookingServices := services.NewBookingServices(bookingRepository, roomServices, guestServices, loyaltyServices, corporateServices, transactionServices, propertyServices, auditServices, clock)
 	bookingHandler := handler.NewBookingHandler(bookingServices)
-	// Main API group
-	api := router.Group("/api")
+
+	invoiceRepository := repository.NewInvoiceRepository(db)
+	invoiceServices := services.NewInvoiceServices(invoiceRepository, bookingRepository, transactionRepository, corporateRepository, propertyServices, auditServices, clock)
+	invoiceHandler := handler.NewInvoiceHandler(invoiceServices)
+
+	paymentServices := services.NewPaymentServices(transactionRepository, bookingRepository, propertyServices, gateway, auditServices)
+	paymentHandler := handler.NewPaymentHandler(paymentServices)
+	// Auth routes group: /api/auth, login and refresh are public endpoints.
+	authApi := router.Group("/api/auth")
 	{
+		authApi.POST("/login", authHandler.Login)
+		authApi.POST("/refresh", authHandler.Refresh)
+		authApi.POST("/logout", requireAuth, authHandler.Logout)
+	}
+
+	// The payment gateway posts results here; they are checked by signature.
+	router.POST("/api/payment/webhook", paymentHandler.Webhook)
+
+	// Main API group, every route below requires a signed-in staff user or an API key.
+	api := router.Group("/api", requireAuth)
+	{
+		staffApi := api.Group("/staff")
+		{
+			staffApi.POST("/", can(model.PermStaffManage), authHandler.CreateStaff)
+			staffApi.PUT("/role", can(model.PermStaffManage), authHandler.ChangeStaffRole)
+		}
+
+		apiKeyApi := api.Group("/api_key")
+		{
+			apiKeyApi.POST("/", can(model.PermAPIKeyManage), authHandler.CreateAPIKey)
+			apiKeyApi.GET("/", can(model.PermAPIKeyManage), authHandler.GetAllAPIKeys)
+			apiKeyApi.POST("/revoke", can(model.PermAPIKeyManage), authHandler.RevokeAPIKey)
+		}
+
+		propertyApi := api.Group("/property")
+		{
+			propertyApi.POST("/", can(model.PermPropertyManage), propertyHandler.CreateProperty)
+			propertyApi.GET("/", can(model.PermPropertyRead), propertyHandler.GetAllProperties)
+			propertyApi.GET("/:id", can(model.PermPropertyRead), propertyHandler.GetPropertyByID)
+			propertyApi.GET("/:id/business_date", can(model.PermPropertyRead), propertyHandler.GetBusinessDate)
+			propertyApi.PUT("/", can(model.PermPropertyManage), propertyHandler.UpdateProperty)
+		}
+
 		// Room routes group: /api/room
 		roomApi := api.Group("/room")
 		{
-			roomApi.POST("/", roomHandler.CreateRoom)         // POST /api/room
-			roomApi.POST("/type", roomHandler.CreateRoomType) // POST /api/room/type
-			roomApi.GET("/", roomHandler.GetAllRoom)          // GET  /api/room
-			roomApi.GET("/:id", roomHandler.GetRoomByID)      // GET  /api/room/:id  <-- added
-			roomApi.GET("/available", roomHandler.GetAvailableRoom)
-			roomApi.PUT("/", roomHandler.UpdateRoom)
-			roomApi.PUT("/status", roomHandler.ChangeStatus)
-			roomApi.DELETE("/", roomHandler.DeleteRoom)
+			roomApi.POST("/", can(model.PermRoomManage), roomHandler.CreateRoom)             // POST /api/room
+			roomApi.POST("/type", can(model.PermRoomTypeManage), roomHandler.CreateRoomType) // POST /api/room/type
+			roomApi.GET("/", can(model.PermRoomRead), roomHandler.GetAllRoom)                // GET  /api/room
+			roomApi.GET("/:id", can(model.PermRoomRead), roomHandler.GetRoomByID)            // GET  /api/room/:id  <-- added
+			roomApi.GET("/available", can(model.PermRoomRead), roomHandler.GetAvailableRoom)
+			roomApi.PUT("/", can(model.PermRoomManage), roomHandler.UpdateRoom)
+			roomApi.PUT("/status", can(model.PermRoomStatus), roomHandler.ChangeStatus)
+			roomApi.DELETE("/", can(model.PermRoomManage), roomHandler.DeleteRoom)
+			roomApi.GET("/type", can(model.PermRoomRead), roomHandler.GetAllRoomTypes)
+			roomApi.GET("/type/:id", can(model.PermRoomRead), roomHandler.GetRoomTypeByID)
+			roomApi.PUT("/type", can(model.PermRoomTypeManage), roomHandler.UpdateRoomType)
+			roomApi.POST("/type/retire", can(model.PermRoomTypeManage), roomHandler.RetireRoomType)
+			roomApi.POST("/type/reactivate", can(model.PermRoomTypeManage), roomHandler.ReactivateRoomType)
+			roomApi.DELETE("/type/:id", can(model.PermRoomTypeManage), roomHandler.DeleteRoomType)
 		}
 
 		guestApi := api.Group("/guest")
 		{
-			guestApi.POST("/", guestHandler.CreateNewGuest)
-			guestApi.GET("/identity", guestHandler.GetGuestByCredentialID)
-			guestApi.PUT("/", guestHandler.Update)
-			guestApi.DELETE("/:id", guestHandler.Delete)
+			guestApi.POST("/", can(model.PermGuestWrite), guestHandler.CreateNewGuest)
+			guestApi.GET("/", can(model.PermGuestRead), guestHandler.Search)
+			guestApi.GET("/identity", can(model.PermGuestRead), guestHandler.GetGuestByCredentialID)
+			guestApi.GET("/credential_types", can(model.PermGuestRead), guestHandler.GetCredentialTypes)
+			guestApi.PUT("/", can(model.PermGuestWrite), guestHandler.Update)
+			guestApi.DELETE("/:id", can(model.PermGuestDelete), guestHandler.Delete)
+			guestApi.GET("/:id/history", can(model.PermGuestRead), guestHandler.History)
+			guestApi.GET("/:id/export", can(model.PermGuestPII), guestHandler.Export)
+			guestApi.POST("/:id/erase", can(model.PermGuestDelete), guestHandler.Erase)
+			guestApi.GET("/:id/duplicates", can(model.PermGuestRead), guestHandler.FindDuplicates)
+			guestApi.POST("/merge", can(model.PermGuestWrite), guestHandler.Merge)
+			guestApi.GET("/:id/flags", can(model.PermGuestFlagRead), guestHandler.GetFlags)
+			guestApi.POST("/flag", can(model.PermGuestFlagManage), guestHandler.AddFlag)
+			guestApi.DELETE("/flag/:flag_id", can(model.PermGuestFlagManage), guestHandler.RemoveFlag)
 		}
 
 		bookingApi := api.Group("/booking")
 		{
-			bookingApi.POST("/", bookingHandler.CreateBooking)
-			bookingApi.GET("/:id", bookingHandler.GetBookingByReference)
-			bookingApi.GET("/date", bookingHandler.GetBookingByDateRange)
-			bookingApi.POST("/cancel", bookingHandler.CancelBooking)
-			bookingApi.POST("/check_in", bookingHandler.CheckIn)
-			bookingApi.POST("/check_out", bookingHandler.Checkout)
+			bookingApi.POST("/", can(model.PermBookingWrite), bookingHandler.CreateBooking)
+			bookingApi.GET("/:id", can(model.PermBookingRead), bookingHandler.GetBookingByReference)
+			bookingApi.GET("/date", can(model.PermBookingRead), bookingHandler.GetBookingByDateRange)
+			bookingApi.GET("/arrivals", can(model.PermBookingRead), bookingHandler.GetArrivals)
+			bookingApi.PUT("/special_requests", can(model.PermBookingWrite), bookingHandler.UpdateSpecialRequests)
+			bookingApi.POST("/cancel", can(model.PermBookingWrite), bookingHandler.CancelBooking)
+			bookingApi.POST("/check_in", can(model.PermBookingCheckIn), bookingHandler.CheckIn)
+			bookingApi.POST("/check_out", can(model.PermBookingCheckIn), bookingHandler.Checkout)
+			bookingApi.POST("/deposit", can(model.PermTransactionWrite), bookingHandler.RecordDeposit)
+			bookingApi.POST("/deposit/expire", can(model.PermBookingWrite), bookingHandler.ExpireDeposits)
+			bookingApi.POST("/occupant", can(model.PermBookingWrite), bookingHandler.AddOccupant)
+			bookingApi.DELETE("/:id/occupant/:occupant_id", can(model.PermBookingWrite), bookingHandler.RemoveOccupant)
+			bookingApi.GET("/:id/transaction", can(model.PermTransactionRead), transactionHandler.GetTransactionsForBooking)
+			bookingApi.POST("/invoice", can(model.PermTransactionWrite), invoiceHandler.IssueInvoice)
+			bookingApi.POST("/invoice/void", can(model.PermTransactionRefund), invoiceHandler.VoidInvoice)
+			bookingApi.GET("/:id/invoice", can(model.PermTransactionRead), invoiceHandler.DownloadInvoice)
+		}
+
+		transactionApi := api.Group("/transaction")
+		{
+			transactionApi.POST("/", can(model.PermTransactionWrite), transactionHandler.CreateTransaction)
+			transactionApi.POST("/paid", can(model.PermTransactionWrite), transactionHandler.MarkPaid)
+			transactionApi.POST("/refund", can(model.PermTransactionRefund), transactionHandler.Refund)
+		}
+
+		paymentApi := api.Group("/payment")
+		{
+			paymentApi.POST("/card_token", can(model.PermTransactionWrite), paymentHandler.TokenizeCard)
+			paymentApi.POST("/authorize", can(model.PermTransactionWrite), paymentHandler.Authorize)
+			paymentApi.POST("/capture", can(model.PermTransactionWrite), paymentHandler.Capture)
+			paymentApi.POST("/void", can(model.PermTransactionWrite), paymentHandler.Void)
 		}
 
+		loyaltyApi := api.Group("/loyalty")
+		{
+			loyaltyApi.POST("/enroll", can(model.PermLoyaltyManage), loyaltyHandler.Enroll)
+			loyaltyApi.POST("/redeem", can(model.PermLoyaltyManage), loyaltyHandler.Redeem)
+			loyaltyApi.POST("/earn", can(model.PermLoyaltyManage), loyaltyHandler.CreditStay)
+			loyaltyApi.GET("/:guest_id", can(model.PermLoyaltyRead), loyaltyHandler.GetAccount)
+			loyaltyApi.GET("/:guest_id/ledger", can(model.PermLoyaltyRead), loyaltyHandler.GetLedger)
+		}
+
+		corporateApi := api.Group("/corporate")
+		{
+			corporateApi.POST("/", can(model.PermCorporateManage), corporateHandler.Create)
+			corporateApi.PUT("/", can(model.PermCorporateManage), corporateHandler.Update)
+			corporateApi.GET("/", can(model.PermCorporateRead), corporateHandler.GetAll)
+			corporateApi.GET("/:id", can(model.PermCorporateRead), corporateHandler.GetByID)
+			corporateApi.POST("/guest", can(model.PermCorporateManage), corporateHandler.AddGuest)
+			corporateApi.DELETE("/:id/guest/:guest_id", can(model.PermCorporateManage), corporateHandler.RemoveGuest)
+		}
+
+		receivableApi := api.Group("/receivable")
+		{
+			receivableApi.POST("/invoice", can(model.PermReceivableManage), receivableHandler.InvoiceTransaction)
+			receivableApi.POST("/payment", can(model.PermReceivableManage), receivableHandler.RecordPayment)
+			receivableApi.POST("/credit_note", can(model.PermReceivableManage), receivableHandler.IssueCreditNote)
+			receivableApi.GET("/aging", can(model.PermReceivableRead), receivableHandler.GetAging)
+			receivableApi.GET("/:account_id/invoices", can(model.PermReceivableRead), receivableHandler.GetInvoices)
+			receivableApi.GET("/:account_id/statement", can(model.PermReceivableRead), receivableHandler.GetStatement)
+		}
+
+		api.GET("/audit", can(model.PermAuditRead), auditHandler.GetAuditTrail)
+
 		// You can add other groups here, like:
 		// guestApi := api.Group("/guest")
 		// bookingApi := api.Group("/booking")
 	}
+	return bookingServices
 }
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/response"
+	"log"
+	"reflect"
+)
+
+type AuditServices interface {
+	// Record stores the field-level difference between before and after.
+	// Pass nil as before for creates and nil as after for deletes.
+	Record(actor *model.Principal, entityType string, entityID any, action model.AuditAction, before, after any)
+	ListForEntity(entityType, entityID string) ([]*response.AuditLogResponse, error)
+	// Redact replaces the recorded values of fields in the audit history of
+	// an entity, for data that has to be erased.
+	Redact(entityType string, entityID any, fields ...string) error
+}
+
+type auditServices struct {
+	auditRepository repository.AuditRepository
+	clock           Clock
+}
+
+func NewAuditServices(repo repository.AuditRepository, clock Clock) AuditServices {
+	return &auditServices{auditRepository: repo, clock: clock}
+}
+
+// auditIgnoredFields are bookkeeping fields that change on every write.
+var auditIgnoredFields = map[string]bool{
+	"CreatedAt":     true,
+	"UpdatedAt":     true,
+	"IDNumberIndex": true,
+	"PhoneIndex":    true,
+	"EmailIndex":    true,
+}
+
+// auditRedactedFields hold guest PII. The audit log records that they
+// changed but never their values. Entries written before a field was added
+// here still hold its values; erasing a guest redacts them with Redact.
+var auditRedactedFields = map[string]bool{
+	"FullName": true,
+	"IDNumber": true,
+	"Phone":    true,
+	"Email":    true,
+
+	// Preferences include allergies and accessibility needs.
+	"Preferences": true,
+}
+
+const auditRedacted = "[redacted]"
+
+// auditValueFields are stored as a single JSON column, so they are compared
+// as whole values instead of being skipped like relations.
+var auditValueFields = map[string]bool{
+	"Preferences":     true,
+	"SpecialRequests": true,
+	"Permissions":     true,
+
+	"RoomTypeDiscounts": true,
+}
+
+func (s *auditServices) Record(actor *model.Principal, entityType string, entityID any, action model.AuditAction, before, after any) {
+	changes, err := diffFields(before, after)
+	if err != nil {
+		log.Printf("audit: failed to diff %s %v: %v", entityType, entityID, err)
+		return
+	}
+	if action == model.AuditUpdate && len(changes) == 0 {
+		return
+	}
+	entry := model.AuditLog{
+		Actor:      actor.Name(),
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Action:     action,
+		Changes:    changes,
+		CreatedAt:  s.clock.Now(),
+	}
+	// The change itself is already committed, so a failing audit write is logged rather than returned.
+	if err := s.auditRepository.Create(&entry); err != nil {
+		log.Printf("audit: failed to record %s %s %v: %v", action, entityType, entityID, err)
+	}
+}
+
+func (s *auditServices) ListForEntity(entityType, entityID string) ([]*response.AuditLogResponse, error) {
+	logs, err := s.auditRepository.FindByEntity(entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.AuditLogResponse, len(logs))
+	for i, l := range logs {
+		resp[i] = &response.AuditLogResponse{
+			ID:         l.ID,
+			Actor:      l.Actor,
+			EntityType: l.EntityType,
+			EntityID:   l.EntityID,
+			Action:     l.Action,
+			Changes:    l.Changes,
+			CreatedAt:  l.CreatedAt,
+		}
+	}
+	return resp, nil
+}
+
+func (s *auditServices) Redact(entityType string, entityID any, fields ...string) error {
+	logs, err := s.auditRepository.FindByEntity(entityType, fmt.Sprint(entityID))
+	if err != nil {
+		return err
+	}
+	for _, l := range logs {
+		redacted := false
+		for _, name := range fields {
+			change, ok := l.Changes[name]
+			if !ok || (holdsNoValue(change.Before) && holdsNoValue(change.After)) {
+				continue
+			}
+			l.Changes[name] = redactValues(change)
+			redacted = true
+		}
+		if redacted {
+			if err := s.auditRepository.RedactChanges(l.ID, l.Changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func redactValues(c model.FieldChange) model.FieldChange {
+	if !holdsNoValue(c.Before) {
+		c.Before = auditRedacted
+	}
+	if !holdsNoValue(c.After) {
+		c.After = auditRedacted
+	}
+	return c
+}
+
+// holdsNoValue reports whether a recorded value reveals nothing: it is
+// empty or already redacted.
+func holdsNoValue(v any) bool {
+	return v == nil || v == "" || v == auditRedacted
+}
+
+// diffFields compares the scalar fields of two models. Relations (nested
+// structs and slices) are skipped; they are audited on their own.
+func diffFields(before, after any) (map[string]model.FieldChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]model.FieldChange)
+	for name, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[name]) {
+			changes[name] = redactChange(name, value, afterFields[name])
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok && value != nil {
+			changes[name] = redactChange(name, nil, value)
+		}
+	}
+	return changes, nil
+}
+
+func redactChange(name string, before, after any) model.FieldChange {
+	change := model.FieldChange{Before: before, After: after}
+	if auditRedactedFields[name] {
+		return redactValues(change)
+	}
+	return change
+}
+
+func auditFields(m any) (map[string]any, error) {
+	fields := make(map[string]any)
+	if m == nil {
+		return fields, nil
+	}
+	if v := reflect.ValueOf(m); v.Kind() == reflect.Pointer && v.IsNil() {
+		return fields, nil
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]any
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+	for name, value := range all {
+		if auditIgnoredFields[name] || (isRelationValue(value) && !auditValueFields[name]) {
+			continue
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+func isRelationValue(v any) bool {
+	switch v.(type) {
+	case map[string]any, []any:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUnauthenticated    = errors.New("authentication required")
+)
+
+type AuthServices interface {
+	Login(req *request.LoginRequest) (*response.TokenResponse, error)
+	Refresh(refreshToken string) (*response.TokenResponse, error)
+	Logout(sessionID string) error
+	Authenticate(accessToken string) (*model.Principal, error)
+	AuthenticateAPIKey(key string) (*model.Principal, error)
+	CreateStaff(req *request.CreateStaffRequest) (*response.StaffResponse, error)
+	ChangeStaffRole(req *request.ChangeStaffRoleRequest) (*response.StaffResponse, error)
+	CreateAPIKey(creator *model.Principal, req *request.CreateAPIKeyRequest) (*response.APIKeyResponse, error)
+	ListAPIKeys() ([]*response.APIKeyResponse, error)
+	RevokeAPIKey(id uint) error
+}
+
+type authServices struct {
+	staffRepository  repository.StaffRepository
+	apiKeyRepository repository.APIKeyRepository
+	clock            Clock
+	secret           []byte
+	accessTokenTTL   time.Duration
+	refreshTokenTTL  time.Duration
+}
+
+func NewAuthServices(repo repository.StaffRepository, apiKeys repository.APIKeyRepository, clock Clock, secret []byte, accessTTL, refreshTTL time.Duration) AuthServices {
+	return &authServices{
+		staffRepository:  repo,
+		apiKeyRepository: apiKeys,
+		clock:            clock,
+		secret:           secret,
+		accessTokenTTL:   accessTTL,
+		refreshTokenTTL:  refreshTTL,
+	}
+}
+
+func (s *authServices) Login(req *request.LoginRequest) (*response.TokenResponse, error) {
+	user, err := s.staffRepository.FindByUsername(strings.TrimSpace(req.Username))
+	if err != nil || !user.Active {
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	session := model.AuthSession{
+		ID:               generateULID(),
+		StaffUserID:      user.ID,
+		RefreshTokenHash: hashToken(refreshToken),
+		ExpiresAt:        now.Add(s.refreshTokenTTL),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.staffRepository.CreateSession(&session); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(&session, refreshToken)
+}
+
+// Refresh rotates the refresh token, so each one can only be used once. A
+// token that was already rotated has been copied; its session is revoked so
+// neither copy keeps working.
+func (s *authServices) Refresh(refreshToken string) (*response.TokenResponse, error) {
+	oldHash := hashToken(refreshToken)
+	session, err := s.staffRepository.FindSessionByRefreshHash(oldHash)
+	if err != nil {
+		if reused, err := s.staffRepository.FindSessionByRetiredHash(oldHash); err == nil {
+			if err := s.staffRepository.RevokeSession(reused.ID, s.clock.Now()); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrUnauthenticated
+	}
+	if err := s.checkSession(session); err != nil {
+		return nil, err
+	}
+	newRefreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	session.RefreshTokenHash = hashToken(newRefreshToken)
+	session.ExpiresAt = now.Add(s.refreshTokenTTL)
+	session.UpdatedAt = now
+	rotated, err := s.staffRepository.UpdateSession(session, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// A concurrent refresh with the same token won.
+		return nil, ErrUnauthenticated
+	}
+	return s.issueTokens(session, newRefreshToken)
+}
+
+func (s *authServices) Logout(sessionID string) error {
+	return s.staffRepository.RevokeSession(sessionID, s.clock.Now())
+}
+
+func (s *authServices) Authenticate(accessToken string) (*model.Principal, error) {
+	var claims accessClaims
+	if err := parseJWT(accessToken, s.secret, &claims); err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if s.clock.Now().Unix() >= claims.ExpiresAt {
+		return nil, errors.New("access token expired")
+	}
+	session, err := s.staffRepository.FindSessionByID(claims.SessionID)
+	if err != nil || session.StaffUserID != claims.Subject {
+		return nil, ErrUnauthenticated
+	}
+	if err := s.checkSession(session); err != nil {
+		return nil, err
+	}
+	return &model.Principal{StaffUser: session.StaffUser, Session: session}, nil
+}
+
+func (s *authServices) AuthenticateAPIKey(key string) (*model.Principal, error) {
+	prefix, _, found := strings.Cut(key, ".")
+	if !found {
+		return nil, ErrUnauthenticated
+	}
+	apiKey, err := s.apiKeyRepository.FindByPrefix(prefix)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashToken(key))) != 1 {
+		return nil, ErrUnauthenticated
+	}
+	now := s.clock.Now()
+	if apiKey.RevokedAt != nil {
+		return nil, errors.New("api key has been revoked")
+	}
+	if apiKey.ExpiresAt != nil && !now.Before(*apiKey.ExpiresAt) {
+		return nil, errors.New("api key expired")
+	}
+	if err := s.apiKeyRepository.RecordUsage(apiKey.ID, now); err != nil {
+		return nil, err
+	}
+	return &model.Principal{APIKey: apiKey}, nil
+}
+
+// CreateAPIKey returns the plain key once; only its hash is kept.
+// A caller cannot grant a key permissions it does not hold itself.
+func (s *authServices) CreateAPIKey(creator *model.Principal, req *request.CreateAPIKeyRequest) (*response.APIKeyResponse, error) {
+	permissions := make([]model.Permission, 0, len(req.Permissions))
+	for _, p := range req.Permissions {
+		perm := model.Permission(p)
+		if !perm.IsValid() {
+			return nil, errors.New("unknown permission: " + p)
+		}
+		if !creator.Can(perm) {
+			return nil, errors.New("cannot grant a permission you do not have: " + p)
+		}
+		permissions = append(permissions, perm)
+	}
+	apiKey := model.APIKey{
+		Name:        strings.TrimSpace(req.Name),
+		Permissions: permissions,
+		CreatedAt:   s.clock.Now(),
+	}
+	if creator.StaffUser != nil {
+		apiKey.CreatedByID = creator.StaffUser.ID
+	}
+	if req.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, errors.New("invalid expires_at, must use RFC 3339 format")
+		}
+		apiKey.ExpiresAt = &expiresAt
+	}
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey.Prefix = prefix
+	apiKey.KeyHash = hashToken(key)
+	if err := s.apiKeyRepository.Create(&apiKey); err != nil {
+		return nil, err
+	}
+	resp := mapToAPIKeyResponse(&apiKey)
+	resp.Key = key
+	return resp, nil
+}
+
+func (s *authServices) ListAPIKeys() ([]*response.APIKeyResponse, error) {
+	keys, err := s.apiKeyRepository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		resp[i] = mapToAPIKeyResponse(key)
+	}
+	return resp, nil
+}
+
+func (s *authServices) RevokeAPIKey(id uint) error {
+	if _, err := s.apiKeyRepository.FindByID(id); err != nil {
+		return err
+	}
+	return s.apiKeyRepository.Revoke(id, s.clock.Now())
+}
+
+func (s *authServices) CreateStaff(req *request.CreateStaffRequest) (*response.StaffResponse, error) {
+	username := strings.TrimSpace(req.Username)
+	if _, err := s.staffRepository.FindByUsername(username); err == nil {
+		return nil, errors.New("username already taken")
+	}
+	role := model.Role(req.Role)
+	if !role.IsValid() {
+		return nil, errors.New("invalid role provided. must be 'admin', 'front_desk', 'housekeeping', 'revenue' or 'night_auditor'")
+	}
+	user, err := newStaffUser(username, req.FullName, req.Password, role)
+	if err != nil {
+		return nil, err
+	}
+	created, err := s.staffRepository.Create(user)
+	if err != nil {
+		return nil, err
+	}
+	return mapToStaffResponse(created), nil
+}
+
+func (s *authServices) ChangeStaffRole(req *request.ChangeStaffRoleRequest) (*response.StaffResponse, error) {
+	role := model.Role(req.Role)
+	if !role.IsValid() {
+		return nil, errors.New("invalid role provided. must be 'admin', 'front_desk', 'housekeeping', 'revenue' or 'night_auditor'")
+	}
+	user, err := s.staffRepository.FindByID(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == model.RoleAdmin && role != model.RoleAdmin {
+		admins, err := s.staffRepository.CountByRole(model.RoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			return nil, errors.New("cannot demote the last admin")
+		}
+	}
+	user.Role = role
+	if err := s.staffRepository.Update(user); err != nil {
+		return nil, err
+	}
+	return mapToStaffResponse(user), nil
+}
+
+// BootstrapAdmin makes sure an admin account exists, so a fresh installation
+// can sign in at all. When staff exist but none is an admin, the account
+// named by ADMIN_USERNAME is promoted.
+func BootstrapAdmin(repo repository.StaffRepository, username, password string) error {
+	admins, err := repo.CountByRole(model.RoleAdmin)
+	if err != nil || admins > 0 {
+		return err
+	}
+	if username == "" || password == "" {
+		return errors.New("no admin account exists and ADMIN_USERNAME/ADMIN_PASSWORD are not set")
+	}
+	if err := checkBootstrapPassword(username, password); err != nil {
+		return err
+	}
+	if existing, err := repo.FindByUsername(username); err == nil {
+		existing.Role = model.RoleAdmin
+		return repo.Update(existing)
+	}
+	user, err := newStaffUser(username, "Administrator", password, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	_, err = repo.Create(user)
+	return err
+}
+
+// defaultPasswords start the passwords that have been published with the
+// project or are tried first, such as admin12345, so the first admin
+// account may not use them.
+var defaultPasswords = []string{"admin", "password", "changeme", "change-me", "letmein", "qwerty", "123456"}
+
+// checkBootstrapPassword refuses ADMIN_PASSWORD values that would leave the
+// first admin account open to guessing.
+func checkBootstrapPassword(username, password string) error {
+	if len(password) < 12 || strings.EqualFold(password, username) {
+		return errors.New("ADMIN_PASSWORD must be at least 12 characters and differ from ADMIN_USERNAME")
+	}
+	lower := strings.ToLower(password)
+	for _, weak := range defaultPasswords {
+		if strings.HasPrefix(lower, weak) {
+			return errors.New("ADMIN_PASSWORD is a well-known default; choose another one")
+		}
+	}
+	return nil
+}
+
+func (s *authServices) checkSession(session *model.AuthSession) error {
+	if session.RevokedAt != nil {
+		return errors.New("session has been logged out")
+	}
+	if !s.clock.Now().Before(session.ExpiresAt) {
+		return errors.New("session expired, please log in again")
+	}
+	if session.StaffUser == nil || !session.StaffUser.Active {
+		return ErrUnauthenticated
+	}
+	return nil
+}
+
+func (s *authServices) issueTokens(session *model.AuthSession, refreshToken string) (*response.TokenResponse, error) {
+	now := s.clock.Now()
+	accessToken, err := signJWT(accessClaims{
+		Subject:   session.StaffUserID,
+		SessionID: session.ID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.accessTokenTTL).Unix(),
+	}, s.secret)
+	if err != nil {
+		return nil, err
+	}
+	return &response.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+func newStaffUser(username, fullName, password string, role model.Role) (*model.StaffUser, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return &model.StaffUser{
+		Username:     username,
+		FullName:     fullName,
+		Role:         role,
+		PasswordHash: string(hash),
+		Active:       true,
+	}, nil
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// generateAPIKey returns a key of the form "hms_<prefix>.<secret>" and its prefix.
+func generateAPIKey() (string, string, error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secret, err := generateRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix := "hms_" + hex.EncodeToString(prefixBytes)
+	return prefix + "." + secret, prefix, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func mapToAPIKeyResponse(k *model.APIKey) *response.APIKeyResponse {
+	return &response.APIKeyResponse{
+		ID:          k.ID,
+		Name:        k.Name,
+		Prefix:      k.Prefix,
+		Permissions: k.Permissions,
+		LastUsedAt:  k.LastUsedAt,
+		UsageCount:  k.UsageCount,
+		ExpiresAt:   k.ExpiresAt,
+		RevokedAt:   k.RevokedAt,
+		CreatedAt:   k.CreatedAt,
+	}
+}
+
+func mapToStaffResponse(u *model.StaffUser) *response.StaffResponse {
+	return &response.StaffResponse{
+		ID:       u.ID,
+		Username: u.Username,
+		FullName: u.FullName,
+		Role:     u.Role,
+		Active:   u.Active,
+	}
+}
@@ -8,33 +8,45 @@ import (
 	"hms-backend/repository"
 	"hms-backend/request"
 	"hms-backend/response"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
 )
 
 type BookingServices interface {
-	CreateBooking(req *request.CreateBookingRequest) (*response.BookingResponse, error)
+	CreateBooking(actor *model.Principal, req *request.CreateBookingRequest) (*response.BookingResponse, error)
 	GetBookingByReference(ref string) (*response.BookingResponse, error)
 	ListBookingsForGuest(id uint) ([]*response.BookingResponse, error)
-	ListBookingsForDateRange(start, end time.Time) ([]*response.BookingResponse, error)
-	CancelBooking(req *request.CancelBookingRequest) (*response.BookingResponse, error)
-	CheckInGuest(ref string) (*response.BookingResponse, error)
-	CheckOutGuest(ref string) (*response.BookingResponse, error)
+	ListBookingsForDateRange(propertyID uint, start, end time.Time) ([]*response.BookingResponse, error)
+	CancelBooking(actor *model.Principal, req *request.CancelBookingRequest) (*response.BookingResponse, error)
+	CheckInGuest(actor *model.Principal, ref string) (*response.BookingResponse, error)
+	CheckOutGuest(actor *model.Principal, ref string) (*response.BookingResponse, error)
+	AddOccupant(actor *model.Principal, req *request.AddOccupantRequest) (*response.BookingResponse, error)
+	RemoveOccupant(actor *model.Principal, ref string, occupantID uint) (*response.BookingResponse, error)
+	UpdateSpecialRequests(actor *model.Principal, req *request.UpdateSpecialRequestsRequest) (*response.BookingResponse, error)
+	GetArrivals(propertyID uint, date string) ([]*response.BookingResponse, error)
+	RecordDeposit(actor *model.Principal, req *request.DepositPaymentRequest) (*response.BookingResponse, error)
+	ExpireDeposits(actor *model.Principal) (*response.DepositExpiryResponse, error)
 }
 
 type bookingService struct {
 	bookingRepository repository.BookingRepository
 	roomServices      RoomServices
 	guestServices     GuestService
+	loyaltyServices   LoyaltyServices
+	corporateServices CorporateServices
+	transactions      TransactionServices
+	propertyServices  PropertyServices
+	auditServices     AuditServices
+	clock             Clock
 }
 
-func NewBookingServices(repo repository.BookingRepository, room RoomServices, guest GuestService) BookingServices {
-	return &bookingService{bookingRepository: repo, roomServices: room, guestServices: guest}
+func NewBookingServices(repo repository.BookingRepository, room RoomServices, guest GuestService, loyalty LoyaltyServices, corporate CorporateServices, transaction TransactionServices, property PropertyServices, audit AuditServices, clock Clock) BookingServices {
+	return &bookingService{bookingRepository: repo, roomServices: room, guestServices: guest, loyaltyServices: loyalty, corporateServices: corporate, transactions: transaction, propertyServices: property, auditServices: audit, clock: clock}
 }
 
-func (s *bookingService) CreateBooking(req *request.CreateBookingRequest) (*response.BookingResponse, error) {
-	layout := "2006-01-02"
+func (s *bookingService) CreateBooking(actor *model.Principal, req *request.CreateBookingRequest) (*response.BookingResponse, error) {
 	room, err := s.roomServices.GetRoomModelByID(req.RoomID)
 	if err != nil {
 		return nil, err
@@ -42,47 +54,124 @@ func (s *bookingService) CreateBooking(req *request.CreateBookingRequest) (*resp
 	if room.Status != model.StatusAvailable {
 		return nil, errors.New("room not available, please use another room")
 	}
+	if room.RoomType.Retired {
+		return nil, errors.New("room type is retired, please use another room")
+	}
+	if req.Adults == 0 {
+		req.Adults = 1
+	}
+	extraBeds, err := resolveExtraBeds(&room.RoomType, req.Adults, req.Children, req.ExtraBeds)
+	if err != nil {
+		return nil, err
+	}
+	specialRequests, err := normalizeSpecialRequests(req.SpecialRequests)
+	if err != nil {
+		return nil, err
+	}
 	guest, err := s.guestServices.FindByModelID(req.GuestID)
 	if err != nil {
 		return nil, err
 	}
-	book, err := s.bookingRepository.FindByGuestID(req.GuestID)
-	if len(book) > 0 || err != nil {
-		return nil, errors.New("guest already have another booking" + err.Error())
+	if guest.IsErased() {
+		return nil, errors.New("guest data has been erased, please register the guest again")
+	}
+	if guest.IsMerged() {
+		return nil, fmt.Errorf("guest profile was merged into guest %d", *guest.MergedIntoID)
 	}
-	ref, err := generateBookingReference()
+	warnings, err := s.guestServices.CheckFlags(guest.ID, FlagStageBooking)
 	if err != nil {
 		return nil, err
 	}
-	checkInStr, err := time.Parse(layout, req.CheckInDate)
+	property, err := s.propertyServices.GetPropertyModelByID(room.PropertyID)
 	if err != nil {
 		return nil, err
 	}
-	checkoutStr, err := time.Parse(layout, req.CheckOutDate)
+	checkInStr, err := request.ParseDate(req.CheckInDate)
+	if err != nil {
+		return nil, err
+	}
+	checkoutStr, err := request.ParseDate(req.CheckOutDate)
+	if err != nil {
+		return nil, err
+	}
+	if !checkoutStr.After(checkInStr) {
+		return nil, errors.New("check out date must be after check in date")
+	}
+	if checkInStr.Before(businessDate(s.clock, property)) {
+		return nil, errors.New("check in date is before the hotel business date")
+	}
+	// Repeat guests are welcome, but not two rooms for the same nights.
+	book, err := s.bookingRepository.FindByGuestID(req.GuestID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range book {
+		if b.IsActive() && b.Overlaps(checkInStr, checkoutStr) {
+			return nil, fmt.Errorf("guest already has booking %s for these dates", b.BookingReference)
+		}
+	}
+	nightlyRate := calculateNightlyRate(&room.RoomType, req.Adults, req.Children, extraBeds)
+	var discount float64
+	if req.CorporateAccountID != nil {
+		account, err := s.corporateServices.FindForBooking(*req.CorporateAccountID, guest.ID)
+		if err != nil {
+			return nil, err
+		}
+		// The negotiated discount only applies to the room price, not to supplements.
+		discount = account.DiscountFor(room.RoomTypeID)
+		nightlyRate = roundAmount(nightlyRate - room.RoomType.Price*discount/100)
+		if req.DirectBill {
+			nights := checkoutStr.Sub(checkInStr).Hours() / 24
+			if err := s.corporateServices.CheckCredit(account, nightlyRate*nights, ""); err != nil {
+				return nil, err
+			}
+		}
+	} else if req.DirectBill {
+		return nil, errors.New("direct billing needs a corporate account")
+	}
+	schedule := newDepositSchedule(&room.RoomType, nightlyRate*checkoutStr.Sub(checkInStr).Hours()/24,
+		businessDate(s.clock, property), checkInStr, req.DirectBill)
+	ref, err := generateBookingReference(s.clock.Now().In(propertyLocation(property)))
 	if err != nil {
 		return nil, err
 	}
 
 	newBooking := model.Booking{
-		ID:               generateULID(),
-		BookingReference: ref,
-		RoomID:           req.RoomID,
-		GuestID:          req.GuestID,
-		Room:             room,
-		Guest:            guest,
-		CheckInDate:      checkInStr,
-		CheckOutDate:     checkoutStr,
-		Status:           model.StatusPending,
-		Notes:            req.Notes,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		ID:                 generateULID(),
+		BookingReference:   ref,
+		PropertyID:         room.PropertyID,
+		RoomID:             req.RoomID,
+		GuestID:            req.GuestID,
+		Room:               room,
+		Guest:              guest,
+		CheckInDate:        checkInStr,
+		CheckOutDate:       checkoutStr,
+		Status:             schedule.status(),
+		DepositAmount:      schedule.deposit,
+		DepositDueDate:     schedule.depositDue,
+		BalanceDueDate:     schedule.balanceDue,
+		Notes:              req.Notes,
+		SpecialRequests:    specialRequests,
+		Adults:             req.Adults,
+		Children:           req.Children,
+		Infants:            req.Infants,
+		ExtraBeds:          extraBeds,
+		NightlyRate:        nightlyRate,
+		CorporateAccountID: req.CorporateAccountID,
+		CorporateDiscount:  discount,
+		DirectBill:         req.DirectBill,
+		CreatedAt:          s.clock.Now(),
+		UpdatedAt:          s.clock.Now(),
 	}
 	err = s.bookingRepository.Create(&newBooking)
 	if err != nil {
 		return nil, err
 	}
+	s.auditServices.Record(actor, model.EntityBooking, newBooking.ID, model.AuditCreate, nil, &newBooking)
 
-	return mapToBookingResponse(&newBooking), err
+	resp := mapToBookingResponse(&newBooking)
+	resp.Warnings = warnings
+	return resp, err
 }
 
 func (s *bookingService) GetBookingByReference(ref string) (*response.BookingResponse, error) {
@@ -102,84 +191,431 @@ func (s *bookingService) ListBookingsForGuest(id uint) ([]*response.BookingRespo
 
 }
 
-func (s *bookingService) CancelBooking(req *request.CancelBookingRequest) (*response.BookingResponse, error) {
+func (s *bookingService) CancelBooking(actor *model.Principal, req *request.CancelBookingRequest) (*response.BookingResponse, error) {
 	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
 	if err != nil {
 		return nil, errors.New("Booking Not Found")
 	}
+	return s.cancel(actor, booking, req.Reason, req.RefundAmount)
+}
+
+// ErrRefundFailed is returned with the cancelled booking when the booking
+// was cancelled but not all of its refund could be paid out.
+var ErrRefundFailed = errors.New("booking was cancelled but the refund failed")
+
+// cancel releases the booking. Whatever was paid ahead is refunded unless a
+// smaller refund is given, e.g. when the deposit is kept under the
+// cancellation terms. Card refunds go through the gateway, so they cannot be
+// rolled back with the booking; a failed refund is returned as
+// ErrRefundFailed together with the cancelled booking.
+func (s *bookingService) cancel(actor *model.Principal, booking *model.Booking, reason string, refundAmount *float64) (*response.BookingResponse, error) {
+	if booking.Status != model.StatusPending && booking.Status != model.StatusConfirmed {
+		return nil, errors.New("only pending or confirmed bookings can be cancelled")
+	}
+	refundable, err := s.transactions.RefundableFor(booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	refund := refundable
+	if refundAmount != nil {
+		if roundAmount(*refundAmount) > refundable {
+			return nil, fmt.Errorf("refund of %.2f exceeds the %.2f paid for the booking", *refundAmount, refundable)
+		}
+		refund = roundAmount(*refundAmount)
+	}
+	before := *booking
 	booking.Status = model.StatusCancelled
-	booking.Notes = req.Reason
-	booking.UpdatedAt = time.Now()
+	booking.Notes = reason
+	booking.UpdatedAt = s.clock.Now()
 	err = s.bookingRepository.Update(booking)
 	if err != nil {
 		return nil, errors.New("Failed To Cancel Booking")
 	}
-	return mapToBookingResponse(booking), err
+	s.auditServices.Record(actor, model.EntityBooking, booking.ID, model.AuditUpdate, &before, booking)
+	resp := mapToBookingResponse(booking)
+	if refund > 0 {
+		// The booking stays cancelled; what could not be refunded can still
+		// be refunded from the payments by hand.
+		resp.Refunded, err = s.transactions.RefundBooking(actor, booking.ID, refund, "Cancelled: "+reason)
+		if err != nil {
+			return resp, fmt.Errorf("%w, %.2f of %.2f was refunded: %v", ErrRefundFailed, resp.Refunded, refund, err)
+		}
+	}
+	return resp, nil
 }
 
-func (s *bookingService) ListBookingsForDateRange(start, end time.Time) ([]*response.BookingResponse, error) {
-	bookings, err := s.bookingRepository.FindForDateRange(start, end)
+func (s *bookingService) ListBookingsForDateRange(propertyID uint, start, end time.Time) ([]*response.BookingResponse, error) {
+	bookings, err := s.bookingRepository.FindForDateRange(propertyID, start, end)
 	if err != nil {
 		return nil, err
 	}
 	return mapToBookingResponseSlice(bookings), err
 }
 
-func (s *bookingService) CheckInGuest(ref string) (*response.BookingResponse, error) {
+func (s *bookingService) CheckInGuest(actor *model.Principal, ref string) (*response.BookingResponse, error) {
 	booking, err := s.bookingRepository.FindByReferenceID(ref)
 	if err != nil {
 		return nil, errors.New("Booking Not Found")
 	}
+	if booking.Status == model.StatusPending {
+		// The deposit may have been paid through the transaction endpoints.
+		if err := s.confirmIfDepositPaid(actor, booking); err != nil {
+			return nil, err
+		}
+	}
 	if booking.Status != model.StatusConfirmed {
 		return nil, errors.New("Booking Status Not Confirmed")
 	}
-	if booking.CheckInDate.Day() != time.Now().Day() {
+	property, err := s.propertyServices.GetPropertyModelByID(booking.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	today := businessDate(s.clock, property)
+	if !booking.CheckInDate.Equal(today) {
 		return nil, errors.New("Cannot Checkin Before/After Day Checkin")
 	}
+	if err := validateOccupantIdentities(booking); err != nil {
+		return nil, err
+	}
+	if err := checkDocumentExpiry(booking, today); err != nil {
+		return nil, err
+	}
+	guestIDs := []uint{booking.GuestID}
+	for _, o := range booking.Occupants {
+		if o.GuestID != nil {
+			guestIDs = append(guestIDs, *o.GuestID)
+		}
+	}
+	var warnings []string
+	for _, id := range guestIDs {
+		w, err := s.guestServices.CheckFlags(id, FlagStageCheckIn)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, w...)
+	}
 
+	before := *booking
 	booking.Status = model.StatusCheckedIn
-	booking.UpdatedAt = time.Now()
+	booking.UpdatedAt = s.clock.Now()
 	err = s.bookingRepository.Update(booking)
 	if err != nil {
 		return nil, errors.New("Checkin Failed!")
 	}
-	return mapToBookingResponse(booking), nil
+	s.auditServices.Record(actor, model.EntityBooking, booking.ID, model.AuditUpdate, &before, booking)
+	resp := mapToBookingResponse(booking)
+	resp.Warnings = warnings
+	return resp, nil
 }
-func (s *bookingService) CheckOutGuest(ref string) (*response.BookingResponse, error) {
+func (s *bookingService) CheckOutGuest(actor *model.Principal, ref string) (*response.BookingResponse, error) {
 	booking, err := s.bookingRepository.FindByReferenceID(ref)
 	if err != nil {
 		return nil, errors.New("Booking Not Found")
 	}
-	if booking.Status != model.StatusConfirmed {
-		return nil, errors.New("Booking Status Not Confirmed")
+	if booking.Status != model.StatusCheckedIn {
+		return nil, errors.New("Booking Status Not Checked In")
+	}
+	property, err := s.propertyServices.GetPropertyModelByID(booking.PropertyID)
+	if err != nil {
+		return nil, err
 	}
-	if booking.CheckOutDate.Day() > time.Now().Day() {
+	before := *booking
+	// Leaving after the property's check-out time on the departure day is late.
+	deadline, err := propertyTimeOn(booking.CheckOutDate, property.CheckOutTime, property)
+	if err != nil {
+		return nil, err
+	}
+	if s.clock.Now().After(deadline) {
 		booking.Notes = "Late Checkout, Must Be Charged for Extra"
 	}
 	booking.Status = model.StatusCheckedOut
-	booking.UpdatedAt = time.Now()
+	booking.UpdatedAt = s.clock.Now()
 	err = s.bookingRepository.Update(booking)
 	if err != nil {
 		return nil, errors.New("Checkout Failed!")
 	}
-	err = s.roomServices.ChangeStatus(booking.RoomID, string(model.StatusAvailable))
+	s.auditServices.Record(actor, model.EntityBooking, booking.ID, model.AuditUpdate, &before, booking)
+	err = s.roomServices.ChangeStatus(actor, booking.RoomID, string(model.StatusAvailable))
 	if err != nil {
 		//should do something
 	}
+	// The guest has left either way; what cannot be billed to the company
+	// is left for the desk to collect, missing points are credited later
+	// through POST /loyalty/earn.
+	var warnings []string
+	if booking.DirectBill {
+		if _, err := s.corporateServices.BillStay(actor, booking); err != nil {
+			warnings = append(warnings, fmt.Sprintf("room charges were not billed to the company: %v, please collect payment from the guest", err))
+		}
+	}
+	points, err := s.loyaltyServices.EarnForStay(actor, booking)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("crediting loyalty points failed: %v, credit them once the problem is fixed", err))
+	}
+	resp := mapToBookingResponse(booking)
+	resp.PointsEarned = points
+	resp.Warnings = warnings
+	return resp, nil
+}
+
+func (s *bookingService) AddOccupant(actor *model.Principal, req *request.AddOccupantRequest) (*response.BookingResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if booking.Status == model.StatusCancelled || booking.Status == model.StatusCheckedOut {
+		return nil, errors.New("cannot add occupants to a cancelled or checked out booking")
+	}
+	category := model.AgeCategory(req.AgeCategory)
+	if !isValidAgeCategory(category) {
+		return nil, errors.New("invalid age category provided. must be 'adult', 'child' or 'infant'")
+	}
+
+	// The main guest takes one of the adult places.
+	limits := map[model.AgeCategory]uint{
+		model.AgeChild:  booking.Children,
+		model.AgeInfant: booking.Infants,
+	}
+	if booking.Adults > 1 {
+		limits[model.AgeAdult] = booking.Adults - 1
+	}
+	var registered uint
+	for _, o := range booking.Occupants {
+		if o.AgeCategory == category {
+			registered++
+		}
+	}
+	if registered >= limits[category] {
+		return nil, fmt.Errorf("booking has no free %s place left", category)
+	}
+
+	occupant := model.BookingOccupant{
+		BookingID:   booking.ID,
+		AgeCategory: category,
+		CreatedAt:   s.clock.Now(),
+	}
+	if req.GuestID != nil {
+		if *req.GuestID == booking.GuestID {
+			return nil, errors.New("main guest is already registered on the booking")
+		}
+		for _, o := range booking.Occupants {
+			if o.GuestID != nil && *o.GuestID == *req.GuestID {
+				return nil, errors.New("guest is already an occupant of this booking")
+			}
+		}
+		guest, err := s.guestServices.FindByModelID(*req.GuestID)
+		if err != nil {
+			return nil, err
+		}
+		if guest.IsErased() {
+			return nil, errors.New("guest data has been erased, please register the guest again")
+		}
+		if guest.IsMerged() {
+			return nil, fmt.Errorf("guest profile was merged into guest %d", *guest.MergedIntoID)
+		}
+		occupant.GuestID = &guest.ID
+		occupant.Guest = guest
+	} else {
+		if req.FullName == "" {
+			return nil, errors.New("full name is required when guest_id is not provided")
+		}
+		occupant.FullName = req.FullName
+		// Identity data is optional here, children often travel without it.
+		if req.CredentialType != "" || req.IDNumber != "" {
+			property, err := s.propertyServices.GetPropertyModelByID(booking.PropertyID)
+			if err != nil {
+				return nil, err
+			}
+			doc, err := parseIdentityDocument(req.CredentialType, req.IDNumber, req.IssuingCountry, req.DocumentExpiry, businessDate(s.clock, property))
+			if err != nil {
+				return nil, err
+			}
+			occupant.CredentialType = doc.CredentialType
+			occupant.IDNumber = doc.IDNumber
+			occupant.IssuingCountry = doc.IssuingCountry
+			occupant.DocumentExpiry = doc.Expiry
+		}
+	}
+	if err := s.bookingRepository.AddOccupant(&occupant); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityOccupant, occupant.ID, model.AuditCreate, nil, &occupant)
+	booking.Occupants = append(booking.Occupants, occupant)
 	return mapToBookingResponse(booking), nil
 }
 
+func (s *bookingService) RemoveOccupant(actor *model.Principal, ref string, occupantID uint) (*response.BookingResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(ref)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if booking.Status == model.StatusCancelled || booking.Status == model.StatusCheckedOut {
+		return nil, errors.New("cannot remove occupants from a cancelled or checked out booking")
+	}
+	index := -1
+	for i, o := range booking.Occupants {
+		if o.ID == occupantID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("occupant not found on this booking")
+	}
+	if err := s.bookingRepository.DeleteOccupant(occupantID); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityOccupant, occupantID, model.AuditDelete, &booking.Occupants[index], nil)
+	booking.Occupants = append(booking.Occupants[:index], booking.Occupants[index+1:]...)
+	return mapToBookingResponse(booking), nil
+}
+
+func (s *bookingService) UpdateSpecialRequests(actor *model.Principal, req *request.UpdateSpecialRequestsRequest) (*response.BookingResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if !booking.IsActive() {
+		return nil, errors.New("special requests can only be changed before check out")
+	}
+	specialRequests, err := normalizeSpecialRequests(req.SpecialRequests)
+	if err != nil {
+		return nil, err
+	}
+	before := *booking
+	booking.SpecialRequests = specialRequests
+	if err := s.bookingRepository.Update(booking); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityBooking, booking.ID, model.AuditUpdate, &before, booking)
+	return mapToBookingResponse(booking), nil
+}
+
+// GetArrivals lists the bookings expected on date, the property's business
+// date when date is empty, with guest preferences and special requests.
+func (s *bookingService) GetArrivals(propertyID uint, date string) ([]*response.BookingResponse, error) {
+	property, err := s.propertyServices.GetPropertyModelByID(propertyID)
+	if err != nil {
+		return nil, err
+	}
+	day := businessDate(s.clock, property)
+	if date != "" {
+		if day, err = request.ParseDate(date); err != nil {
+			return nil, err
+		}
+	}
+	bookings, err := s.bookingRepository.FindArrivals(propertyID, day)
+	if err != nil {
+		return nil, err
+	}
+	return mapToBookingResponseSlice(bookings), nil
+}
+
+func normalizeSpecialRequests(requests []model.SpecialRequest) ([]model.SpecialRequest, error) {
+	normalized := make([]model.SpecialRequest, 0, len(requests))
+	for _, r := range requests {
+		r.Type = model.SpecialRequestType(strings.ToLower(strings.TrimSpace(string(r.Type))))
+		r.Detail = strings.TrimSpace(r.Detail)
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, r)
+	}
+	return normalized, nil
+}
+
+// validateOccupantIdentities makes sure ID data is on file for every adult
+// in the party, as required for police registration at check-in.
+func validateOccupantIdentities(booking *model.Booking) error {
+	if booking.Guest == nil || booking.Guest.CredentialType == "" || booking.Guest.IDNumber == "" {
+		return errors.New("main guest has no identity document on file")
+	}
+	identified := uint(1)
+	for i := range booking.Occupants {
+		o := &booking.Occupants[i]
+		if o.AgeCategory != model.AgeAdult {
+			continue
+		}
+		if !o.HasIdentity() {
+			return fmt.Errorf("occupant %d has no identity document on file", o.ID)
+		}
+		identified++
+	}
+	if identified < booking.Adults {
+		return fmt.Errorf("identity documents registered for %d of %d adult(s)", identified, booking.Adults)
+	}
+	return nil
+}
+
+func isValidAgeCategory(category model.AgeCategory) bool {
+	switch category {
+	case model.AgeAdult, model.AgeChild, model.AgeInfant:
+		return true
+	default:
+		return false
+	}
+}
+
+// resolveExtraBeds returns the number of extra beds needed for the party,
+// which is at least the requested amount and never more than the room type allows.
+func resolveExtraBeds(roomType *model.RoomType, adults, children, requested uint) (uint, error) {
+	guests := adults + children
+	extraBeds := requested
+	if guests > roomType.Capacity && guests-roomType.Capacity > extraBeds {
+		extraBeds = guests - roomType.Capacity
+	}
+	if extraBeds > roomType.MaxExtraBeds {
+		return 0, fmt.Errorf("party of %d exceeds room capacity of %d with at most %d extra bed(s)",
+			guests, roomType.Capacity, roomType.MaxExtraBeds)
+	}
+	return extraBeds, nil
+}
+
+// calculateNightlyRate adds extra bed charges and supplements for guests above
+// the base occupancy to the room type price. Adults fill the base occupancy first.
+func calculateNightlyRate(roomType *model.RoomType, adults, children, extraBeds uint) float64 {
+	rate := roomType.Price + float64(extraBeds)*roomType.ExtraBedPrice
+	base := roomType.BaseOccupancy
+	if base == 0 {
+		base = roomType.Capacity
+	}
+	if adults > base {
+		rate += float64(adults-base) * roomType.ExtraAdultPrice
+		base = 0
+	} else {
+		base -= adults
+	}
+	if children > base {
+		rate += float64(children-base) * roomType.ChildPrice
+	}
+	return rate
+}
+
 func mapToBookingResponse(booking *model.Booking) *response.BookingResponse {
 	if booking == nil {
 		return nil
 	}
-	layout := "2006-01-02"
 	resp := &response.BookingResponse{
-		BookingID:    booking.BookingReference,
-		CheckInDate:  booking.CheckInDate.Format(layout),
-		CheckOutDate: booking.CheckOutDate.Format(layout),
-		Status:       booking.Status,
-		Notes:        booking.Notes,
+		BookingID:       booking.BookingReference,
+		PropertyID:      booking.PropertyID,
+		CheckInDate:     booking.CheckInDate.Format(request.DateLayout),
+		CheckOutDate:    booking.CheckOutDate.Format(request.DateLayout),
+		Status:          booking.Status,
+		Notes:           booking.Notes,
+		SpecialRequests: booking.SpecialRequests,
+		Adults:          booking.Adults,
+		Children:        booking.Children,
+		Infants:         booking.Infants,
+		ExtraBeds:       booking.ExtraBeds,
+		NightlyRate:     booking.NightlyRate,
+
+		CorporateAccountID: booking.CorporateAccountID,
+		CorporateDiscount:  booking.CorporateDiscount,
+		DirectBill:         booking.DirectBill,
+
+		DepositAmount:  booking.DepositAmount,
+		DepositDueDate: formatOptionalDate(booking.DepositDueDate),
+		BalanceDueDate: formatOptionalDate(booking.BalanceDueDate),
 	}
 	if booking.Room != nil {
 		resp.AdditionalInfo.Room = *mapToRoomDetail(booking.Room)
@@ -187,6 +623,10 @@ func mapToBookingResponse(booking *model.Booking) *response.BookingResponse {
 	if booking.Guest != nil {
 		resp.AdditionalInfo.Guest = *mapToGuestDetail(booking.Guest)
 	}
+	resp.AdditionalInfo.Occupants = make([]response.OccupantResponse, len(booking.Occupants))
+	for i := range booking.Occupants {
+		resp.AdditionalInfo.Occupants[i] = mapToOccupantResponse(&booking.Occupants[i])
+	}
 	return resp
 }
 
@@ -195,18 +635,43 @@ func mapToRoomDetail(room *model.Room) *response.RoomResponse {
 		return nil
 	}
 	resp := &response.RoomResponse{
-		ID:     room.ID,
-		Number: room.Number,
-		Status: room.Status,
+		ID:                room.ID,
+		PropertyID:        room.PropertyID,
+		Number:            room.Number,
+		Status:            room.Status,
+		Floor:             room.Floor,
+		Building:          room.Building,
+		View:              room.View,
+		BedConfiguration:  room.BedConfiguration,
+		Smoking:           room.Smoking,
+		Accessible:        room.Accessible,
+		ConnectingRoomIDs: mapToConnectingRoomIDs(room.ConnectingRooms),
+		Amenities:         mapToAmenityNames(room.Amenities),
 	}
 	if &room.RoomType != nil {
-		resp.RoomType = response.RoomTypeDetail{
-			ID:          room.RoomType.ID,
-			Name:        room.RoomType.Name,
-			Description: room.RoomType.Description,
-			Capacity:    room.RoomType.Capacity,
-			Price:       room.RoomType.Price,
-		}
+		resp.RoomType = *mapToRoomTypeResponse(&room.RoomType)
+	}
+	return resp
+}
+
+func mapToOccupantResponse(o *model.BookingOccupant) response.OccupantResponse {
+	resp := response.OccupantResponse{
+		ID:             o.ID,
+		GuestID:        o.GuestID,
+		FullName:       o.FullName,
+		CredentialType: o.CredentialType,
+		IDNumber:       o.IDNumber,
+		IssuingCountry: o.IssuingCountry,
+		DocumentExpiry: formatOptionalDate(o.DocumentExpiry),
+		AgeCategory:    o.AgeCategory,
+	}
+	// Linked profiles are the source of truth for identity data.
+	if o.Guest != nil {
+		resp.FullName = o.Guest.FullName
+		resp.CredentialType = o.Guest.CredentialType
+		resp.IDNumber = o.Guest.IDNumber
+		resp.IssuingCountry = o.Guest.IssuingCountry
+		resp.DocumentExpiry = formatOptionalDate(o.Guest.DocumentExpiry)
 	}
 	return resp
 }
@@ -218,10 +683,13 @@ func mapToGuestDetail(guest *model.Guest) *response.GuestResponse {
 	return &response.GuestResponse{
 		CredentialType: guest.CredentialType,
 		IDNumber:       guest.IDNumber,
+		IssuingCountry: guest.IssuingCountry,
+		DocumentExpiry: formatOptionalDate(guest.DocumentExpiry),
 		FullName:       guest.FullName,
 		Email:          guest.Email,
 		Phone:          guest.Phone,
 		ID:             guest.ID,
+		Preferences:    guest.Preferences,
 	}
 }
 
@@ -244,10 +712,10 @@ func generateULID() string {
 	entropy := ulid.Monotonic(rand.Reader, 0)
 	return ulid.MustNew(ulid.Timestamp(time.Now()), entropy).String()
 }
-func generateBookingReference() (string, error) {
+func generateBookingReference(now time.Time) (string, error) {
 	// 1. Get the prefix and date
 	prefix := "BK"
-	date := time.Now().Format("20060102") // YYYYMMDD format
+	date := now.Format("20060102") // YYYYMMDD format
 
 	// 2. Generate the random part
 	randomPart := make([]byte, idLength)
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"hms-backend/model"
+	"time"
+)
+
+// Clock tells the services what time it is, so tests can simulate any day.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func NewSystemClock() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+// NewFixedClock returns a clock that is always at the given instant.
+func NewFixedClock(now time.Time) Clock {
+	return fixedClock{now}
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+// propertyLocation loads the property timezone, falling back to UTC.
+func propertyLocation(p *model.Property) *time.Location {
+	if p == nil {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// businessDate is the property's current calendar date, expressed like every
+// other calendar date as midnight UTC.
+func businessDate(clock Clock, p *model.Property) time.Time {
+	y, m, d := clock.Now().In(propertyLocation(p)).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// propertyTimeOn returns the instant of an "HH:MM" property time on a calendar date.
+func propertyTimeOn(date time.Time, hhmm string, p *model.Property) (time.Time, error) {
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	y, m, d := date.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, propertyLocation(p)), nil
+}
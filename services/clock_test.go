@@ -0,0 +1,62 @@
+package services
+
+import (
+	"hms-backend/model"
+	"testing"
+	"time"
+)
+
+func TestBusinessDateFollowsPropertyTimezone(t *testing.T) {
+	// 23:30 UTC on 1 March is already 2 March in Jakarta and still 1 March in New York.
+	clock := NewFixedClock(time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC))
+	tests := []struct {
+		timezone string
+		want     time.Time
+	}{
+		{"UTC", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"Asia/Jakarta", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"America/New_York", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// Unknown zones fall back to UTC.
+		{"Mars/Olympus_Mons", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got := businessDate(clock, &model.Property{Timezone: tt.timezone})
+		if !got.Equal(tt.want) {
+			t.Errorf("businessDate in %s = %s, want %s", tt.timezone, got, tt.want)
+		}
+	}
+}
+
+func TestBusinessDateWithoutProperty(t *testing.T) {
+	clock := NewFixedClock(time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC))
+	want := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	if got := businessDate(clock, nil); !got.Equal(want) {
+		t.Errorf("businessDate = %s, want %s", got, want)
+	}
+}
+
+func TestFixedClockDoesNotMove(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	clock := NewFixedClock(now)
+	time.Sleep(time.Millisecond)
+	if !clock.Now().Equal(now) {
+		t.Errorf("Now = %s, want %s", clock.Now(), now)
+	}
+}
+
+func TestPropertyTimeOn(t *testing.T) {
+	property := &model.Property{Timezone: "Europe/Amsterdam"}
+	date := time.Date(2026, 3, 29, 0, 0, 0, 0, time.UTC)
+	// Clocks in Amsterdam moved to summer time (UTC+2) that morning.
+	got, err := propertyTimeOn(date, "14:00", property)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 3, 29, 12, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("propertyTimeOn = %s, want %s", got.UTC(), want)
+	}
+	if _, err := propertyTimeOn(date, "2pm", property); err == nil {
+		t.Error("propertyTimeOn accepted an invalid time")
+	}
+}
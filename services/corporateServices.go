@@ -0,0 +1,326 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"log"
+	"strconv"
+	"strings"
+)
+
+type CorporateServices interface {
+	Create(actor *model.Principal, req *request.CorporateAccountRequest) (*response.CorporateAccountResponse, error)
+	Update(actor *model.Principal, req *request.UpdateCorporateAccountRequest) (*response.CorporateAccountResponse, error)
+	GetAll() ([]*response.CorporateAccountResponse, error)
+	GetByID(id uint) (*response.CorporateAccountResponse, error)
+	AddGuest(actor *model.Principal, req *request.CorporateGuestRequest) (*response.CorporateAccountResponse, error)
+	RemoveGuest(actor *model.Principal, accountID, guestID uint) (*response.CorporateAccountResponse, error)
+	FindForBooking(accountID, guestID uint) (*model.CorporateAccount, error)
+	CheckCredit(account *model.CorporateAccount, amount float64, excludeBookingID string) error
+	BillStay(actor *model.Principal, booking *model.Booking) (*model.Transaction, error)
+}
+
+// ErrCreditLimitExceeded is returned when direct billing would take a
+// company over its credit limit.
+var ErrCreditLimitExceeded = errors.New("charges exceed the company's available credit")
+
+type corporateServices struct {
+	corporateRepository   repository.CorporateRepository
+	guestRepository       repository.GuestRepository
+	transactionRepository repository.TransactionRepository
+	receivableServices    ReceivableServices
+	auditServices         AuditServices
+	clock                 Clock
+}
+
+func NewCorporateServices(repo repository.CorporateRepository, guest repository.GuestRepository, transaction repository.TransactionRepository, receivable ReceivableServices, audit AuditServices, clock Clock) CorporateServices {
+	return &corporateServices{
+		corporateRepository:   repo,
+		guestRepository:       guest,
+		transactionRepository: transaction,
+		receivableServices:    receivable,
+		auditServices:         audit,
+		clock:                 clock,
+	}
+}
+
+func (s *corporateServices) Create(actor *model.Principal, req *request.CorporateAccountRequest) (*response.CorporateAccountResponse, error) {
+	if err := validateCorporateDiscounts(req); err != nil {
+		return nil, err
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if _, err := s.corporateRepository.FindByCode(code); err == nil {
+		return nil, errors.New("corporate account with this code already exists")
+	}
+	account := model.CorporateAccount{Code: code, Active: true, CreatedAt: s.clock.Now()}
+	applyCorporateSettings(&account, req)
+	account.UpdatedAt = account.CreatedAt
+	if err := s.corporateRepository.Create(&account); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityCorporate, account.ID, model.AuditCreate, nil, &account)
+	return mapToCorporateResponse(&account), nil
+}
+
+func (s *corporateServices) Update(actor *model.Principal, req *request.UpdateCorporateAccountRequest) (*response.CorporateAccountResponse, error) {
+	account, err := s.corporateRepository.FindByID(req.ID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	if err := validateCorporateDiscounts(&req.CorporateAccountRequest); err != nil {
+		return nil, err
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if existing, err := s.corporateRepository.FindByCode(code); err == nil && existing.ID != account.ID {
+		return nil, errors.New("corporate account with this code already exists")
+	}
+	before := *account
+	account.Code = code
+	applyCorporateSettings(account, &req.CorporateAccountRequest)
+	account.UpdatedAt = s.clock.Now()
+	if err := s.corporateRepository.Update(account); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityCorporate, account.ID, model.AuditUpdate, &before, account)
+	return s.mapToCorporateDetail(account)
+}
+
+func (s *corporateServices) GetAll() ([]*response.CorporateAccountResponse, error) {
+	accounts, err := s.corporateRepository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.CorporateAccountResponse, len(accounts))
+	for i, account := range accounts {
+		resp[i] = mapToCorporateResponse(account)
+	}
+	return resp, nil
+}
+
+func (s *corporateServices) GetByID(id uint) (*response.CorporateAccountResponse, error) {
+	account, err := s.corporateRepository.FindByID(id)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	return s.mapToCorporateDetail(account)
+}
+
+func (s *corporateServices) AddGuest(actor *model.Principal, req *request.CorporateGuestRequest) (*response.CorporateAccountResponse, error) {
+	account, err := s.corporateRepository.FindByID(req.AccountID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	guest, err := s.guestRepository.FindByID(req.GuestID)
+	if err != nil {
+		return nil, errors.New("Guest Not Found")
+	}
+	if guest.IsErased() || guest.IsMerged() {
+		return nil, errors.New("guest profile is no longer in use")
+	}
+	if account.Allows(guest.ID) {
+		return nil, errors.New("guest is already allowed on this account")
+	}
+	link := model.CorporateGuest{AccountID: account.ID, GuestID: guest.ID, CreatedAt: s.clock.Now()}
+	if err := s.corporateRepository.AddGuest(&link); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityCorporateGuest, link.ID, model.AuditCreate, nil, &link)
+	link.Guest = guest
+	account.Guests = append(account.Guests, link)
+	return s.mapToCorporateDetail(account)
+}
+
+func (s *corporateServices) RemoveGuest(actor *model.Principal, accountID, guestID uint) (*response.CorporateAccountResponse, error) {
+	account, err := s.corporateRepository.FindByID(accountID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	index := -1
+	for i, g := range account.Guests {
+		if g.GuestID == guestID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("guest is not on this account")
+	}
+	if err := s.corporateRepository.RemoveGuest(accountID, guestID); err != nil {
+		return nil, err
+	}
+	link := account.Guests[index]
+	link.Guest = nil
+	s.auditServices.Record(actor, model.EntityCorporateGuest, link.ID, model.AuditDelete, &link, nil)
+	account.Guests = append(account.Guests[:index], account.Guests[index+1:]...)
+	return s.mapToCorporateDetail(account)
+}
+
+// FindForBooking returns the account if the guest may book under it.
+func (s *corporateServices) FindForBooking(accountID, guestID uint) (*model.CorporateAccount, error) {
+	account, err := s.corporateRepository.FindByID(accountID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	if !account.Active {
+		return nil, fmt.Errorf("corporate account %s is not active", account.Code)
+	}
+	if !account.Allows(guestID) {
+		return nil, fmt.Errorf("guest is not allowed to book under corporate account %s", account.Code)
+	}
+	return account, nil
+}
+
+// CheckCredit makes sure the company can take amount more in direct billing.
+// The booking being billed is left out of the held credit so it is not
+// counted twice.
+func (s *corporateServices) CheckCredit(account *model.CorporateAccount, amount float64, excludeBookingID string) error {
+	if account.CreditLimit <= 0 {
+		return fmt.Errorf("corporate account %s is not set up for direct billing", account.Code)
+	}
+	credit, err := s.credit(account, excludeBookingID)
+	if err != nil {
+		return err
+	}
+	if roundAmount(amount) > credit.Available {
+		return fmt.Errorf("%w: %.2f needed, %.2f available", ErrCreditLimitExceeded, amount, credit.Available)
+	}
+	return nil
+}
+
+// BillStay bills the booking's outstanding room charges to its company as an
+// unpaid direct-bill transaction and invoices it on the city ledger. Nothing
+// is billed when the guest already paid in full.
+func (s *corporateServices) BillStay(actor *model.Principal, booking *model.Booking) (*model.Transaction, error) {
+	if !booking.DirectBill || booking.CorporateAccountID == nil {
+		return nil, errors.New("booking is not billed to a company")
+	}
+	account, err := s.corporateRepository.FindByID(*booking.CorporateAccountID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	transactions, err := s.transactionRepository.FindByBookingID(booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := booking.RoomCharges()
+	for _, t := range transactions {
+		if t.PaymentMethod == model.PaymentMethodDirectBill {
+			outstanding -= t.Amount
+		} else {
+			outstanding -= t.Received()
+		}
+	}
+	outstanding = roundAmount(outstanding)
+	if outstanding <= 0 {
+		return nil, nil
+	}
+	if err := s.CheckCredit(account, outstanding, booking.ID); err != nil {
+		return nil, err
+	}
+	t := model.Transaction{
+		BookingID:          booking.ID,
+		Type:               model.TransactionPayment,
+		Amount:             outstanding,
+		PaymentMethod:      model.PaymentMethodDirectBill,
+		CorporateAccountID: &account.ID,
+		CreatedAt:          s.clock.Now(),
+	}
+	if err := s.transactionRepository.Create(&t); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditCreate, nil, &t)
+	// The charge goes straight to the company's city ledger. It is billed
+	// either way and can still be invoiced by hand if this fails.
+	t.Booking = booking
+	if _, err := s.receivableServices.PostInvoice(actor, &t); err != nil {
+		log.Printf("receivable: invoicing transaction %d failed: %v", t.Id, err)
+	}
+	return &t, nil
+}
+
+func (s *corporateServices) credit(account *model.CorporateAccount, excludeBookingID string) (*response.CorporateCreditResponse, error) {
+	billed, err := s.corporateRepository.UnpaidBalance(account.ID)
+	if err != nil {
+		return nil, err
+	}
+	open, err := s.corporateRepository.FindOpenDirectBills(account.ID)
+	if err != nil {
+		return nil, err
+	}
+	var pending float64
+	for _, b := range open {
+		if b.ID != excludeBookingID {
+			pending += b.RoomCharges()
+		}
+	}
+	return &response.CorporateCreditResponse{
+		Limit:     account.CreditLimit,
+		Billed:    roundAmount(billed),
+		Pending:   roundAmount(pending),
+		Available: roundAmount(account.CreditLimit - billed - pending),
+	}, nil
+}
+
+func validateCorporateDiscounts(req *request.CorporateAccountRequest) error {
+	for roomTypeID, discount := range req.RoomTypeDiscounts {
+		if discount < 0 || discount >= 100 {
+			return fmt.Errorf("invalid discount %.2f for room type %d, must be at least 0 and below 100", discount, roomTypeID)
+		}
+	}
+	return nil
+}
+
+func applyCorporateSettings(account *model.CorporateAccount, req *request.CorporateAccountRequest) {
+	account.Name = req.Name
+	account.Type = model.BillingAccountType(req.Type)
+	if account.Type == "" {
+		account.Type = model.BillingCompany
+	}
+	account.ContactEmail = req.ContactEmail
+	account.BillingAddress = req.BillingAddress
+	account.DiscountPercent = req.DiscountPercent
+	account.RoomTypeDiscounts = make(map[string]float64, len(req.RoomTypeDiscounts))
+	for roomTypeID, discount := range req.RoomTypeDiscounts {
+		account.RoomTypeDiscounts[strconv.FormatUint(uint64(roomTypeID), 10)] = discount
+	}
+	account.CreditLimit = req.CreditLimit
+	if req.Active != nil {
+		account.Active = *req.Active
+	}
+}
+
+func (s *corporateServices) mapToCorporateDetail(account *model.CorporateAccount) (*response.CorporateAccountResponse, error) {
+	resp := mapToCorporateResponse(account)
+	credit, err := s.credit(account, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.Credit = credit
+	resp.Guests = make([]response.CorporateGuestResponse, len(account.Guests))
+	for i, g := range account.Guests {
+		resp.Guests[i] = response.CorporateGuestResponse{GuestID: g.GuestID}
+		if g.Guest != nil {
+			resp.Guests[i].FullName = g.Guest.FullName
+		}
+	}
+	return resp, nil
+}
+
+func mapToCorporateResponse(account *model.CorporateAccount) *response.CorporateAccountResponse {
+	return &response.CorporateAccountResponse{
+		ID:                account.ID,
+		Code:              account.Code,
+		Name:              account.Name,
+		Type:              account.Type,
+		ContactEmail:      account.ContactEmail,
+		BillingAddress:    account.BillingAddress,
+		DiscountPercent:   account.DiscountPercent,
+		RoomTypeDiscounts: account.RoomTypeDiscounts,
+		CreditLimit:       account.CreditLimit,
+		Active:            account.Active,
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"log"
+	"time"
+)
+
+// depositJob is the background task that cancels bookings whose deposit
+// was not paid in time.
+const depositJob = "deposit-expiry"
+
+// depositSchedule is what a new booking must prepay, and by when. policy is
+// set when the room type asks for a deposit.
+type depositSchedule struct {
+	policy     bool
+	deposit    float64
+	depositDue *time.Time
+	balanceDue *time.Time
+}
+
+// newDepositSchedule applies the room type's deposit policy to a stay
+// booked on today. Deadlines never fall after arrival, and a balance due
+// before the deposit is due with it. Direct-billed stays are guaranteed by
+// the company and need no deposit.
+func newDepositSchedule(roomType *model.RoomType, total float64, today, checkIn time.Time, directBill bool) depositSchedule {
+	schedule := depositSchedule{policy: roomType.DepositPercent > 0}
+	if directBill {
+		return schedule
+	}
+	if roomType.DepositPercent > 0 {
+		due := today.AddDate(0, 0, int(roomType.DepositDueDays))
+		if due.After(checkIn) {
+			due = checkIn
+		}
+		schedule.deposit = roundAmount(total * roomType.DepositPercent / 100)
+		schedule.depositDue = &due
+	}
+	if roomType.BalanceDueDays > 0 {
+		due := checkIn.AddDate(0, 0, -int(roomType.BalanceDueDays))
+		if schedule.depositDue != nil && due.Before(*schedule.depositDue) {
+			due = *schedule.depositDue
+		} else if due.Before(today) {
+			due = today
+		}
+		schedule.balanceDue = &due
+	}
+	return schedule
+}
+
+// status is pending while a deposit is owed. Under a deposit policy a
+// booking that owes none, such as a direct-billed stay, is confirmed at
+// once. Without a policy bookings stay pending as before, and are confirmed
+// at check-in.
+func (d depositSchedule) status() model.BookingStatus {
+	if !d.policy || d.deposit > 0 {
+		return model.StatusPending
+	}
+	return model.StatusConfirmed
+}
+
+// RecordDeposit takes a payment towards the booking's prepayment. Without an
+// amount, whatever is due next is taken: the deposit while the booking is
+// pending, the rest of the stay after that. The booking is confirmed once
+// its deposit is paid.
+func (s *bookingService) RecordDeposit(actor *model.Principal, req *request.DepositPaymentRequest) (*response.BookingResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if booking.Status != model.StatusPending && booking.Status != model.StatusConfirmed {
+		return nil, errors.New("deposits are only taken for pending or confirmed bookings")
+	}
+	if booking.DirectBill {
+		return nil, errors.New("booking is billed to a company")
+	}
+	if err := s.confirmIfDepositPaid(actor, booking); err != nil {
+		return nil, err
+	}
+	paid, err := s.transactions.PaidFor(booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := roundAmount(booking.RoomCharges() - paid)
+	amount := req.Amount
+	if amount == 0 {
+		amount = outstanding
+		if booking.Status == model.StatusPending {
+			amount = roundAmount(booking.DepositAmount - paid)
+		}
+	}
+	if amount <= 0 {
+		return nil, errors.New("nothing is left to prepay for this booking")
+	}
+	if roundAmount(amount) > outstanding {
+		return nil, fmt.Errorf("payment of %.2f exceeds the %.2f left to pay for the stay", amount, outstanding)
+	}
+	if _, err := s.transactions.RecordPayment(actor, booking.ID, amount, req.PaymentMethod, "Deposit"); err != nil {
+		return nil, err
+	}
+	if err := s.confirmIfDepositPaid(actor, booking); err != nil {
+		return nil, err
+	}
+	return mapToBookingResponse(booking), nil
+}
+
+// ExpireDeposits confirms pending bookings whose deposit has been paid in
+// the meantime and cancels those whose deposit is overdue. It runs in the
+// background and can also be started from the night audit.
+func (s *bookingService) ExpireDeposits(actor *model.Principal) (*response.DepositExpiryResponse, error) {
+	// Every property has reached at least this date.
+	dueBefore := businessDate(s.clock, nil).AddDate(0, 0, 1)
+	bookings, err := s.bookingRepository.FindPendingDeposits(dueBefore)
+	if err != nil {
+		return nil, err
+	}
+	resp := &response.DepositExpiryResponse{Confirmed: []string{}, Cancelled: []string{}}
+	for _, booking := range bookings {
+		// One booking failing must not hold up the others.
+		if err := s.confirmIfDepositPaid(actor, booking); err != nil {
+			log.Printf("deposit: checking booking %s failed: %v", booking.BookingReference, err)
+			continue
+		}
+		if booking.Status == model.StatusConfirmed {
+			resp.Confirmed = append(resp.Confirmed, booking.BookingReference)
+			continue
+		}
+		property, err := s.propertyServices.GetPropertyModelByID(booking.PropertyID)
+		if err != nil {
+			log.Printf("deposit: checking booking %s failed: %v", booking.BookingReference, err)
+			continue
+		}
+		if !booking.DepositDueDate.Before(businessDate(s.clock, property)) {
+			continue
+		}
+		reason := fmt.Sprintf("Deposit due %s not paid", booking.DepositDueDate.Format(request.DateLayout))
+		if _, err := s.cancel(actor, booking, reason, nil); err != nil {
+			log.Printf("deposit: cancelling booking %s failed: %v", booking.BookingReference, err)
+			if !errors.Is(err, ErrRefundFailed) {
+				continue
+			}
+		}
+		resp.Cancelled = append(resp.Cancelled, booking.BookingReference)
+	}
+	return resp, nil
+}
+
+// RunDepositExpiry checks for overdue deposits now and then at every
+// interval, until ctx is done. Every instance of the API runs it; the lock
+// lets only one of them check at a time.
+func RunDepositExpiry(ctx context.Context, bookings BookingServices, lock repository.JobLock, interval time.Duration) {
+	actor := model.JobPrincipal(depositJob)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expireDeposits(ctx, bookings, lock, actor)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func expireDeposits(ctx context.Context, bookings BookingServices, lock repository.JobLock, actor *model.Principal) {
+	unlock, ok, err := lock.TryLock(ctx, depositJob)
+	if err != nil {
+		log.Printf("deposit: taking the job lock failed: %v", err)
+		return
+	}
+	if !ok {
+		// Another instance is checking.
+		return
+	}
+	defer unlock()
+	if resp, err := bookings.ExpireDeposits(actor); err != nil {
+		log.Printf("deposit: expiring overdue deposits failed: %v", err)
+	} else if len(resp.Cancelled) > 0 {
+		log.Printf("deposit: cancelled %d booking(s) with overdue deposits", len(resp.Cancelled))
+	}
+}
+
+// confirmIfDepositPaid confirms a pending booking once the money held for
+// it covers the deposit.
+func (s *bookingService) confirmIfDepositPaid(actor *model.Principal, booking *model.Booking) error {
+	if booking.Status != model.StatusPending {
+		return nil
+	}
+	paid, err := s.transactions.PaidFor(booking.ID)
+	if err != nil {
+		return err
+	}
+	if roundAmount(paid) < booking.DepositAmount {
+		return nil
+	}
+	before := *booking
+	booking.Status = model.StatusConfirmed
+	booking.UpdatedAt = s.clock.Now()
+	if err := s.bookingRepository.Update(booking); err != nil {
+		return err
+	}
+	s.auditServices.Record(actor, model.EntityBooking, booking.ID, model.AuditUpdate, &before, booking)
+	return nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"strings"
+	"sync"
+)
+
+// Cards with these last four digits behave differently at the fake gateway;
+// any other valid number is approved.
+const (
+	FakeCardDeclined          = "0002"
+	FakeCardInsufficientFunds = "9995"
+	// The authorization stays pending until a webhook with the result is posted.
+	FakeCardPending = "3220"
+)
+
+// FakeGateway is an in-process PaymentGateway for development and tests. It
+// keeps its payments in memory and numbers its IDs in order, so the same
+// calls always give the same results. Webhooks for it are signed with
+// SignWebhook.
+type FakeGateway struct {
+	secret []byte
+	clock  Clock
+
+	mu       sync.Mutex
+	seq      int
+	tokens   map[string]*CardToken
+	payments map[string]*fakePayment
+	// results holds the answer given to each idempotency key.
+	results map[string]GatewayPayment
+}
+
+type fakePayment struct {
+	status   model.GatewayStatus
+	amount   float64
+	refunded float64
+}
+
+func NewFakeGateway(webhookSecret []byte, clock Clock) *FakeGateway {
+	return &FakeGateway{
+		secret:   webhookSecret,
+		clock:    clock,
+		tokens:   make(map[string]*CardToken),
+		payments: make(map[string]*fakePayment),
+		results:  make(map[string]GatewayPayment),
+	}
+}
+
+// idempotent answers a repeated key with the first result and remembers
+// the result of a new one. Errors are not remembered.
+func (g *FakeGateway) idempotent(key string, call func() (*GatewayPayment, error)) (*GatewayPayment, error) {
+	if res, ok := g.results[key]; ok {
+		return &res, nil
+	}
+	res, err := call()
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		g.results[key] = *res
+	}
+	return res, nil
+}
+
+func (g *FakeGateway) nextID(prefix string) string {
+	g.seq++
+	return fmt.Sprintf("fake_%s_%06d", prefix, g.seq)
+}
+
+func (g *FakeGateway) TokenizeCard(card CardDetails) (*CardToken, error) {
+	number := strings.ReplaceAll(strings.ReplaceAll(card.Number, " ", ""), "-", "")
+	if !luhnValid(number) {
+		return nil, errors.New("invalid card number")
+	}
+	if card.ExpMonth < 1 || card.ExpMonth > 12 || cardExpired(card.ExpMonth, card.ExpYear, g.clock.Now()) {
+		return nil, errors.New("card has expired")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	token := &CardToken{
+		Token:    g.nextID("tok"),
+		Brand:    cardBrand(number),
+		Last4:    number[len(number)-4:],
+		ExpMonth: card.ExpMonth,
+		ExpYear:  card.ExpYear,
+	}
+	g.tokens[token.Token] = token
+	return token, nil
+}
+
+func (g *FakeGateway) Authorize(token string, amount float64, currency, reference, idempotencyKey string) (*GatewayPayment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.idempotent(idempotencyKey, func() (*GatewayPayment, error) {
+		return g.authorize(token, amount)
+	})
+}
+
+func (g *FakeGateway) authorize(token string, amount float64) (*GatewayPayment, error) {
+	card, ok := g.tokens[token]
+	if !ok {
+		return nil, errors.New("unknown card token")
+	}
+	res := &GatewayPayment{ID: g.nextID("pay"), Amount: amount, CardBrand: card.Brand, CardLast4: card.Last4}
+	switch card.Last4 {
+	case FakeCardDeclined:
+		res.Status, res.Message = model.GatewayFailed, "card declined"
+		return res, nil
+	case FakeCardInsufficientFunds:
+		res.Status, res.Message = model.GatewayFailed, "insufficient funds"
+		return res, nil
+	case FakeCardPending:
+		res.Status = model.GatewayPending
+	default:
+		res.Status = model.GatewayAuthorized
+	}
+	g.payments[res.ID] = &fakePayment{status: res.Status, amount: amount}
+	return res, nil
+}
+
+func (g *FakeGateway) Capture(paymentID string, amount float64, idempotencyKey string) (*GatewayPayment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.idempotent(idempotencyKey, func() (*GatewayPayment, error) {
+		return g.capture(paymentID, amount)
+	})
+}
+
+func (g *FakeGateway) capture(paymentID string, amount float64) (*GatewayPayment, error) {
+	payment, ok := g.payments[paymentID]
+	if !ok {
+		return nil, errors.New("unknown payment")
+	}
+	if payment.status != model.GatewayAuthorized {
+		return nil, fmt.Errorf("payment is %s and cannot be captured", payment.status)
+	}
+	if amount > payment.amount {
+		return nil, errors.New("capture exceeds the authorized amount")
+	}
+	payment.status = model.GatewayCaptured
+	payment.amount = amount
+	return &GatewayPayment{ID: paymentID, Status: payment.status, Amount: amount}, nil
+}
+
+func (g *FakeGateway) Void(paymentID string, idempotencyKey string) (*GatewayPayment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.idempotent(idempotencyKey, func() (*GatewayPayment, error) {
+		return g.void(paymentID)
+	})
+}
+
+func (g *FakeGateway) void(paymentID string) (*GatewayPayment, error) {
+	payment, ok := g.payments[paymentID]
+	if !ok {
+		return nil, errors.New("unknown payment")
+	}
+	if payment.status != model.GatewayAuthorized && payment.status != model.GatewayPending {
+		return nil, fmt.Errorf("payment is %s and cannot be voided", payment.status)
+	}
+	payment.status = model.GatewayVoided
+	return &GatewayPayment{ID: paymentID, Status: payment.status, Amount: payment.amount}, nil
+}
+
+func (g *FakeGateway) Refund(paymentID string, amount float64, idempotencyKey string) (*GatewayPayment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.idempotent(idempotencyKey, func() (*GatewayPayment, error) {
+		return g.refund(paymentID, amount)
+	})
+}
+
+func (g *FakeGateway) refund(paymentID string, amount float64) (*GatewayPayment, error) {
+	payment, ok := g.payments[paymentID]
+	if !ok {
+		return nil, errors.New("unknown payment")
+	}
+	if payment.status != model.GatewayCaptured {
+		return nil, fmt.Errorf("payment is %s and cannot be refunded", payment.status)
+	}
+	if roundAmount(payment.refunded+amount) > payment.amount {
+		return nil, errors.New("refund exceeds the captured amount")
+	}
+	payment.refunded = roundAmount(payment.refunded + amount)
+	return &GatewayPayment{ID: g.nextID("re"), Status: model.GatewayRefunded, Amount: amount}, nil
+}
+
+func (g *FakeGateway) Payment(paymentID string) (*GatewayPayment, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	payment, ok := g.payments[paymentID]
+	if !ok {
+		return nil, errors.New("unknown payment")
+	}
+	return &GatewayPayment{ID: paymentID, Status: payment.status, Amount: payment.amount}, nil
+}
+
+// Settle finishes a pending authorization with status and returns the signed
+// webhook the gateway would post for it, e.g. to try the webhook locally.
+func (g *FakeGateway) Settle(paymentID string, status model.GatewayStatus) (payload []byte, signature string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	payment, ok := g.payments[paymentID]
+	if !ok {
+		return nil, "", errors.New("unknown payment")
+	}
+	if payment.status != model.GatewayPending {
+		return nil, "", fmt.Errorf("payment is %s, not pending", payment.status)
+	}
+	payment.status = status
+	payload, err = json.Marshal(GatewayEvent{
+		ID:        g.nextID("evt"),
+		PaymentID: paymentID,
+		Status:    status,
+		Amount:    payment.amount,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, g.SignWebhook(payload), nil
+}
+
+// SignWebhook returns the signature the fake gateway puts on a webhook payload.
+func (g *FakeGateway) SignWebhook(payload []byte) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (g *FakeGateway) ParseWebhook(payload []byte, signature string) (*GatewayEvent, error) {
+	if !hmac.Equal([]byte(g.SignWebhook(payload)), []byte(strings.ToLower(signature))) {
+		return nil, ErrInvalidWebhookSignature
+	}
+	var event GatewayEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.PaymentID == "" || event.Status == "" {
+		return nil, errors.New("webhook payload needs a payment_id and a status")
+	}
+	return &event, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"hms-backend/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test cards for the fake gateway; all pass the Luhn check.
+const (
+	testCardApproved          = "4242424242424242"
+	testCardDeclined          = "4000000000000002"
+	testCardInsufficientFunds = "4000000000009995"
+	testCardPending           = "4000000000003220"
+)
+
+func newTestGateway() *FakeGateway {
+	return NewFakeGateway([]byte("test-webhook-secret"), NewFixedClock(time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)))
+}
+
+func tokenize(t *testing.T, g *FakeGateway, number string) string {
+	t.Helper()
+	token, err := g.TokenizeCard(CardDetails{Number: number, HolderName: "Test Guest", ExpMonth: 12, ExpYear: 2030, CVC: "123"})
+	if err != nil {
+		t.Fatalf("TokenizeCard(%s): %v", number, err)
+	}
+	return token.Token
+}
+
+// authorize holds amount on an approved card and returns the payment ID.
+func authorize(t *testing.T, g *FakeGateway, amount float64) string {
+	t.Helper()
+	res, err := g.Authorize(tokenize(t, g, testCardApproved), amount, "EUR", "BK1", "")
+	if err != nil || res.Status != model.GatewayAuthorized {
+		t.Fatalf("Authorize = %+v, %v", res, err)
+	}
+	return res.ID
+}
+
+func TestFakeGatewayTokenizeCard(t *testing.T) {
+	g := newTestGateway()
+	token, err := g.TokenizeCard(CardDetails{Number: "4242 4242 4242 4242", ExpMonth: 12, ExpYear: 2030})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Brand != "visa" || token.Last4 != "4242" {
+		t.Errorf("token = %+v, want a visa ending in 4242", token)
+	}
+	if _, err := g.TokenizeCard(CardDetails{Number: "4242424242424241", ExpMonth: 12, ExpYear: 2030}); err == nil {
+		t.Error("TokenizeCard accepted a number failing the Luhn check")
+	}
+	if _, err := g.TokenizeCard(CardDetails{Number: testCardApproved, ExpMonth: 5, ExpYear: 2026}); err == nil {
+		t.Error("TokenizeCard accepted an expired card")
+	}
+}
+
+func TestFakeGatewayAuthorize(t *testing.T) {
+	tests := []struct {
+		card    string
+		status  model.GatewayStatus
+		message string
+	}{
+		{testCardApproved, model.GatewayAuthorized, ""},
+		{testCardDeclined, model.GatewayFailed, "card declined"},
+		{testCardInsufficientFunds, model.GatewayFailed, "insufficient funds"},
+		{testCardPending, model.GatewayPending, ""},
+	}
+	g := newTestGateway()
+	for _, tt := range tests {
+		res, err := g.Authorize(tokenize(t, g, tt.card), 100, "EUR", "BK1", "")
+		if err != nil {
+			t.Fatalf("Authorize with %s: %v", tt.card, err)
+		}
+		if res.Status != tt.status || res.Message != tt.message {
+			t.Errorf("Authorize with %s = %s %q, want %s %q", tt.card, res.Status, res.Message, tt.status, tt.message)
+		}
+		if res.CardLast4 != tt.card[len(tt.card)-4:] {
+			t.Errorf("Authorize with %s charged card ending in %s", tt.card, res.CardLast4)
+		}
+	}
+	if _, err := g.Authorize("fake_tok_unknown", 100, "EUR", "BK1", ""); err == nil {
+		t.Error("Authorize accepted an unknown token")
+	}
+}
+
+func TestFakeGatewayAuthorizeIsIdempotent(t *testing.T) {
+	g := newTestGateway()
+	token := tokenize(t, g, testCardApproved)
+	first, err := g.Authorize(token, 100, "EUR", "BK1", "authorize-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := g.Authorize(token, 100, "EUR", "BK1", "authorize-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("repeated key authorized %s, want the first payment %s", again.ID, first.ID)
+	}
+	other, err := g.Authorize(token, 100, "EUR", "BK1", "authorize-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == first.ID {
+		t.Error("a new key returned the payment of another key")
+	}
+}
+
+func TestFakeGatewayCapture(t *testing.T) {
+	g := newTestGateway()
+	id := authorize(t, g, 100)
+	if _, err := g.Capture(id, 120, ""); err == nil {
+		t.Error("Capture took more than was authorized")
+	}
+	res, err := g.Capture(id, 80, "capture-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != model.GatewayCaptured || res.Amount != 80 {
+		t.Errorf("Capture = %s %.2f, want captured 80.00", res.Status, res.Amount)
+	}
+	// A retry with the same key gets the same answer instead of an error.
+	if again, err := g.Capture(id, 80, "capture-1"); err != nil || again.Amount != 80 {
+		t.Errorf("repeated capture = %+v, %v, want the first result", again, err)
+	}
+	if _, err := g.Capture(id, 80, "capture-2"); err == nil {
+		t.Error("a captured payment was captured again")
+	}
+}
+
+func TestFakeGatewayVoid(t *testing.T) {
+	g := newTestGateway()
+	id := authorize(t, g, 100)
+	res, err := g.Void(id, "void-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != model.GatewayVoided {
+		t.Errorf("Void = %s, want voided", res.Status)
+	}
+	if again, err := g.Void(id, "void-1"); err != nil || again.Status != model.GatewayVoided {
+		t.Errorf("repeated void = %+v, %v, want the first result", again, err)
+	}
+	if _, err := g.Void(id, "void-2"); err == nil {
+		t.Error("a voided payment was voided again")
+	}
+	if _, err := g.Capture(id, 100, ""); err == nil {
+		t.Error("a voided payment was captured")
+	}
+
+	captured := authorize(t, g, 100)
+	if _, err := g.Capture(captured, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Void(captured, ""); err == nil {
+		t.Error("a captured payment was voided")
+	}
+}
+
+func TestFakeGatewayRefund(t *testing.T) {
+	g := newTestGateway()
+	id := authorize(t, g, 100)
+	if _, err := g.Refund(id, 10, ""); err == nil {
+		t.Error("an authorized payment was refunded before capture")
+	}
+	if _, err := g.Capture(id, 100, ""); err != nil {
+		t.Fatal(err)
+	}
+	first, err := g.Refund(id, 60, "refund-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != model.GatewayRefunded || first.Amount != 60 || first.ID == id {
+		t.Errorf("Refund = %+v, want a new refunded refund of 60.00", first)
+	}
+	// Repeating the key does not refund twice: the other 40 are still there.
+	if again, err := g.Refund(id, 60, "refund-1"); err != nil || again.ID != first.ID {
+		t.Errorf("repeated refund = %+v, %v, want refund %s", again, err, first.ID)
+	}
+	if _, err := g.Refund(id, 50, "refund-2"); err == nil {
+		t.Error("refunds exceeded the captured amount")
+	}
+	if _, err := g.Refund(id, 40, "refund-3"); err != nil {
+		t.Errorf("refunding the rest: %v", err)
+	}
+}
+
+func TestFakeGatewaySettleSignsWebhook(t *testing.T) {
+	g := newTestGateway()
+	res, err := g.Authorize(tokenize(t, g, testCardPending), 100, "EUR", "BK1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, err := g.Settle(res.ID, model.GatewayAuthorized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := g.ParseWebhook(payload, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.PaymentID != res.ID || event.Status != model.GatewayAuthorized || event.Amount != 100 {
+		t.Errorf("event = %+v, want payment %s authorized for 100.00", event, res.ID)
+	}
+	// The settled payment behaves like any authorized one.
+	if _, err := g.Capture(res.ID, 100, ""); err != nil {
+		t.Errorf("capturing the settled payment: %v", err)
+	}
+	if _, _, err := g.Settle(res.ID, model.GatewayCaptured); err == nil {
+		t.Error("a payment that is not pending was settled")
+	}
+}
+
+func TestFakeGatewayWebhookSignature(t *testing.T) {
+	g := newTestGateway()
+	payload := []byte(`{"id":"evt_1","payment_id":"fake_pay_000001","status":"captured","amount":100}`)
+	signature := g.SignWebhook(payload)
+
+	if _, err := g.ParseWebhook(payload, strings.ToUpper(signature)); err != nil {
+		t.Errorf("upper case signature rejected: %v", err)
+	}
+	tampered := []byte(strings.Replace(string(payload), "100", "1000", 1))
+	if _, err := g.ParseWebhook(tampered, signature); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("tampered payload: err = %v, want ErrInvalidWebhookSignature", err)
+	}
+	other := NewFakeGateway([]byte("another-webhook-secret"), NewFixedClock(time.Now()))
+	if _, err := other.ParseWebhook(payload, signature); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("other secret: err = %v, want ErrInvalidWebhookSignature", err)
+	}
+	incomplete := []byte(`{"id":"evt_2"}`)
+	if _, err := g.ParseWebhook(incomplete, g.SignWebhook(incomplete)); err == nil {
+		t.Error("webhook without payment and status accepted")
+	}
+}
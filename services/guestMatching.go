@@ -0,0 +1,162 @@
+package services
+
+import (
+	"hms-backend/model"
+	"sort"
+	"strings"
+)
+
+// duplicateThreshold is the score from which a profile is reported as a
+// likely duplicate.
+const duplicateThreshold = 40
+
+type guestMatch struct {
+	guest   *model.Guest
+	score   int
+	reasons []string
+}
+
+// scoreDuplicate rates how likely candidate is the same person as g.
+// Contact details weigh most; names alone are only a weak signal.
+func scoreDuplicate(g, candidate *model.Guest) guestMatch {
+	m := guestMatch{guest: candidate}
+	add := func(points int, reason string) {
+		m.score += points
+		m.reasons = append(m.reasons, reason)
+	}
+
+	if email := model.NormalizeEmail(g.Email); email != "" && email == model.NormalizeEmail(candidate.Email) {
+		add(35, "same email")
+	}
+	if phone := model.NormalizePhone(g.Phone); phone != "" && phone == model.NormalizePhone(candidate.Phone) {
+		add(30, "same phone number")
+	}
+	if a, b := normalizeIDNumber(g.IDNumber), normalizeIDNumber(candidate.IDNumber); a != "" && a == b {
+		add(50, "same identity number")
+	} else if a != "" && editDistance(a, b) == 1 {
+		add(25, "identity number differs by one character")
+	}
+
+	a, b := nameTokens(g.FullName), nameTokens(candidate.FullName)
+	switch {
+	case strings.Join(a, " ") == strings.Join(b, " "):
+		add(30, "same name")
+	case sameTokens(a, b):
+		add(25, "same name in a different order")
+	case tokenOverlap(a, b) >= 0.5:
+		add(15, "similar name")
+	}
+	return m
+}
+
+// rankDuplicates scores every candidate and keeps those above the
+// threshold, best match first.
+func rankDuplicates(g *model.Guest, candidates []*model.Guest) []guestMatch {
+	var matches []guestMatch
+	for _, c := range candidates {
+		if m := scoreDuplicate(g, c); m.score >= duplicateThreshold {
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	return matches
+}
+
+func normalizeIDNumber(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+func nameTokens(name string) []string {
+	return strings.Fields(strings.ToLower(name))
+}
+
+func sameTokens(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return strings.Join(sortedA, " ") == strings.Join(sortedB, " ")
+}
+
+// tokenOverlap is the Jaccard similarity of two token sets.
+func tokenOverlap(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	union := len(set)
+	shared := 0
+	for _, t := range b {
+		if set[t] {
+			shared++
+			delete(set, t)
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// nameSearchScore rates how well a guest name answers a search query.
+// Terms that only match with a typo count half.
+func nameSearchScore(query, name string) int {
+	terms, tokens := nameTokens(query), nameTokens(name)
+	if len(terms) == 0 || len(tokens) == 0 {
+		return 0
+	}
+	if strings.Join(terms, " ") == strings.Join(tokens, " ") {
+		return 80
+	}
+	points := 0
+	for _, term := range terms {
+		best := 0
+		for _, token := range tokens {
+			switch {
+			case strings.HasPrefix(token, term):
+				best = max(best, 60)
+			case strings.Contains(token, term):
+				best = max(best, 40)
+			case editDistance(term, token) <= allowedTypos(term):
+				best = max(best, 30)
+			}
+		}
+		points += best
+	}
+	return points / len(terms)
+}
+
+func allowedTypos(term string) int {
+	if len([]rune(term)) <= 4 {
+		return 1
+	}
+	return 2
+}
@@ -1,28 +1,68 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"hms-backend/model"
 	"hms-backend/repository"
 	"hms-backend/request"
 	"hms-backend/response"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 )
 
 type GuestService interface {
-	Create(r *request.GuestRequest) (*response.GuestResponse, error)
+	Create(actor *model.Principal, r *request.GuestRequest) (*response.GuestResponse, error)
 	FindByCredentialID(credType, credID string) (*response.GuestResponse, error)
 	FindByID(id uint) (*response.GuestResponse, error)
 	FindByModelID(id uint) (*model.Guest, error)
-	Update(r *request.GuestRequest) (*response.GuestResponse, error)
-	Delete(credType, credID string) error
+	Update(actor *model.Principal, r *request.GuestRequest) (*response.GuestResponse, error)
+	Delete(actor *model.Principal, credType, credID string) error
+	Export(id uint) (*response.GuestDataExport, error)
+	Erase(actor *model.Principal, id uint) error
+	FindDuplicates(id uint) ([]*response.GuestDuplicateResponse, error)
+	Merge(actor *model.Principal, req *request.MergeGuestRequest) (*response.GuestResponse, error)
+	Search(params *request.GuestSearchParams) (*response.GuestSearchResponse, error)
+	History(id uint) (*response.GuestHistoryResponse, error)
+	AddFlag(actor *model.Principal, req *request.CreateGuestFlagRequest) (*response.GuestFlagResponse, error)
+	ListFlags(guestID uint) ([]*response.GuestFlagResponse, error)
+	RemoveFlag(actor *model.Principal, flagID uint) (*response.GuestFlagResponse, error)
+	CheckFlags(guestID uint, stage FlagStage) ([]string, error)
+	CredentialTypes() []response.CredentialTypeResponse
 }
 
+// FlagStage is the point in the guest journey at which flags are checked.
+type FlagStage string
+
+const (
+	FlagStageBooking FlagStage = "booking"
+	FlagStageCheckIn FlagStage = "check_in"
+)
+
+// ErrGuestRefused does not say which flag applies; staff without flag
+// access only learn that a manager has to be involved.
+var ErrGuestRefused = errors.New("guest cannot be accepted under the hotel's guest policy, please contact a manager")
+
 type guestService struct {
-	guestRepository repository.GuestRepository
+	guestRepository       repository.GuestRepository
+	bookingRepository     repository.BookingRepository
+	transactionRepository repository.TransactionRepository
+	propertyServices      PropertyServices
+	auditServices         AuditServices
+	clock                 Clock
 }
 
-func NewGuestServices(repo repository.GuestRepository) GuestService {
-	return &guestService{guestRepository: repo}
+func NewGuestServices(repo repository.GuestRepository, booking repository.BookingRepository, transaction repository.TransactionRepository, property PropertyServices, audit AuditServices, clock Clock) GuestService {
+	return &guestService{
+		guestRepository:       repo,
+		bookingRepository:     booking,
+		transactionRepository: transaction,
+		propertyServices:      property,
+		auditServices:         audit,
+		clock:                 clock,
+	}
 }
 
 func (s *guestService) FindByModelID(id uint) (*model.Guest, error) {
@@ -37,69 +77,518 @@ func (s *guestService) FindByID(id uint) (*response.GuestResponse, error) {
 	return mapGuestResponse(guest), err
 }
 
-func (s *guestService) Create(r *request.GuestRequest) (*response.GuestResponse, error) {
+func (s *guestService) Create(actor *model.Principal, r *request.GuestRequest) (*response.GuestResponse, error) {
+	doc, err := parseIdentityDocument(r.CredentialType, r.IDNumber, r.IssuingCountry, r.DocumentExpiry, businessDate(s.clock, nil))
+	if err != nil {
+		return nil, err
+	}
+	var preferences model.GuestPreferences
+	if r.Preferences != nil {
+		preferences = r.Preferences.Normalize()
+		if err := preferences.Validate(); err != nil {
+			return nil, err
+		}
+	}
 	guest := model.Guest{
-		CredentialType: r.CredentialType,
-		IDNumber:       r.IDNumber,
+		CredentialType: doc.CredentialType,
+		IDNumber:       doc.IDNumber,
+		IssuingCountry: doc.IssuingCountry,
+		DocumentExpiry: doc.Expiry,
 		FullName:       r.FullName,
 		Email:          r.Email,
 		Phone:          r.Phone,
-		CreatedAt:      time.Now(),
+		Preferences:    preferences,
+		CreatedAt:      s.clock.Now(),
 	}
 	newGuest, err := s.guestRepository.Create(&guest)
 	if err != nil {
 		return nil, err
 	}
+	s.auditServices.Record(actor, model.EntityGuest, newGuest.ID, model.AuditCreate, nil, newGuest)
 	return mapGuestResponse(newGuest), err
 }
 
 func (s *guestService) FindByCredentialID(credType, credID string) (*response.GuestResponse, error) {
-	guest, err := s.guestRepository.FindByCredentialID(credType, credID)
+	guest, err := s.guestRepository.FindByCredentialID(model.NormalizeCredentialType(credType), model.NormalizeIDNumber(credID))
 	if err != nil {
 		return nil, err
 	}
-	return &response.GuestResponse{
-		IDNumber:       guest.IDNumber,
-		CredentialType: guest.CredentialType,
-		FullName:       guest.FullName,
-		Email:          guest.Email,
-		Phone:          guest.Phone,
-	}, err
+	// A merged duplicate is still found by its own credential, but the
+	// surviving profile is the one to use.
+	if guest.IsMerged() {
+		guest, err = s.guestRepository.FindByID(*guest.MergedIntoID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mapGuestResponse(guest), err
 }
 
-func (s *guestService) Update(r *request.GuestRequest) (*response.GuestResponse, error) {
-	guest, err := s.guestRepository.FindByCredentialID(r.CredentialType, r.IDNumber)
+func (s *guestService) Update(actor *model.Principal, r *request.GuestRequest) (*response.GuestResponse, error) {
+	doc, err := parseIdentityDocument(r.CredentialType, r.IDNumber, r.IssuingCountry, r.DocumentExpiry, businessDate(s.clock, nil))
 	if err != nil {
 		return nil, err
 	}
+	guest, err := s.guestRepository.FindByCredentialID(doc.CredentialType, doc.IDNumber)
+	if err != nil {
+		return nil, err
+	}
+	before := *guest
 	guest.FullName = r.FullName
 	guest.Email = r.Email
 	guest.Phone = r.Phone
-	guest.IDNumber = r.IDNumber
+	guest.CredentialType = doc.CredentialType
+	guest.IDNumber = doc.IDNumber
+	guest.IssuingCountry = doc.IssuingCountry
+	guest.DocumentExpiry = doc.Expiry
+	if r.Preferences != nil {
+		preferences := r.Preferences.Normalize()
+		if err := preferences.Validate(); err != nil {
+			return nil, err
+		}
+		guest.Preferences = preferences
+	}
 	err = s.guestRepository.Update(guest)
 	if err != nil {
 		return nil, err
 	}
-	return &response.GuestResponse{
-		IDNumber:       guest.IDNumber,
-		CredentialType: guest.CredentialType,
-		FullName:       guest.FullName,
-		Email:          guest.Email,
-		Phone:          guest.Phone,
-	}, err
+	s.auditServices.Record(actor, model.EntityGuest, guest.ID, model.AuditUpdate, &before, guest)
+	return mapGuestResponse(guest), err
 }
 
-func (s *guestService) Delete(credType, credID string) error {
-	return s.guestRepository.Delete(credType, credID)
+// Delete erases the guest rather than removing the row, bookings and
+// transactions still reference it.
+func (s *guestService) Delete(actor *model.Principal, credType, credID string) error {
+	guest, err := s.guestRepository.FindByCredentialID(model.NormalizeCredentialType(credType), model.NormalizeIDNumber(credID))
+	if err != nil {
+		return err
+	}
+	return s.erase(actor, guest)
+}
+
+func (s *guestService) Erase(actor *model.Principal, id uint) error {
+	guest, err := s.guestRepository.FindByID(id)
+	if err != nil {
+		return errors.New("Guest Not Found")
+	}
+	if guest.IsErased() {
+		return errors.New("guest data has already been erased")
+	}
+	return s.erase(actor, guest)
+}
+
+// erasedGuestFields are the guest fields cleared by an erasure.
+var erasedGuestFields = []string{"FullName", "CredentialType", "IDNumber", "IssuingCountry", "DocumentExpiry", "Phone", "Email", "Preferences"}
+
+func (s *guestService) erase(actor *model.Principal, guest *model.Guest) error {
+	bookings, err := s.bookingRepository.FindByGuestID(guest.ID)
+	if err != nil {
+		return err
+	}
+	for _, b := range bookings {
+		if b.IsActive() {
+			return fmt.Errorf("booking %s is still active, cancel or check it out before erasing the guest", b.BookingReference)
+		}
+	}
+	// Older audit entries may still hold values of these fields.
+	if err := s.auditServices.Redact(model.EntityGuest, guest.ID, erasedGuestFields...); err != nil {
+		return err
+	}
+	before := *guest
+	if err := s.guestRepository.Erase(guest, s.clock.Now()); err != nil {
+		return err
+	}
+	s.auditServices.Record(actor, model.EntityGuest, guest.ID, model.AuditUpdate, &before, guest)
+	return nil
+}
+
+func (s *guestService) Export(id uint) (*response.GuestDataExport, error) {
+	guest, err := s.guestRepository.FindByID(id)
+	if err != nil {
+		return nil, errors.New("Guest Not Found")
+	}
+	bookings, err := s.bookingRepository.FindByGuestID(guest.ID)
+	if err != nil {
+		return nil, err
+	}
+	export := &response.GuestDataExport{
+		ExportedAt:   s.clock.Now(),
+		Guest:        *mapGuestResponse(guest),
+		Bookings:     mapToBookingResponseSlice(bookings),
+		Transactions: []*response.TransactionResponse{},
+	}
+	for i, b := range bookings {
+		// Companions are other people; their data is not part of this guest's export.
+		export.Bookings[i].AdditionalInfo.Occupants = nil
+		transactions, err := s.transactionRepository.FindByBookingID(b.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range transactions {
+			export.Transactions = append(export.Transactions, mapToTransactionResponse(t))
+		}
+	}
+	return export, nil
 }
 
 func mapGuestResponse(g *model.Guest) *response.GuestResponse {
 	return &response.GuestResponse{
 		IDNumber:       g.IDNumber,
 		CredentialType: g.CredentialType,
+		IssuingCountry: g.IssuingCountry,
+		DocumentExpiry: formatOptionalDate(g.DocumentExpiry),
 		FullName:       g.FullName,
 		Email:          g.Email,
 		Phone:          g.Phone,
 		ID:             g.ID,
+		Preferences:    g.Preferences,
+	}
+}
+
+func (s *guestService) FindDuplicates(id uint) ([]*response.GuestDuplicateResponse, error) {
+	guest, err := s.guestRepository.FindByID(id)
+	if err != nil {
+		return nil, errors.New("Guest Not Found")
+	}
+	candidates, err := s.guestRepository.FindDuplicateCandidates(guest)
+	if err != nil {
+		return nil, err
+	}
+	matches := rankDuplicates(guest, candidates)
+	resp := make([]*response.GuestDuplicateResponse, len(matches))
+	for i, m := range matches {
+		resp[i] = &response.GuestDuplicateResponse{
+			Guest:   *mapGuestResponse(m.guest),
+			Score:   m.score,
+			Reasons: m.reasons,
+		}
+	}
+	return resp, nil
+}
+
+// Merge folds the duplicate profile into the survivor. Bookings and
+// occupancies move to the survivor, missing contact details are copied
+// over, and the duplicate is kept, marked as merged, so its credential
+// still resolves to the survivor.
+func (s *guestService) Merge(actor *model.Principal, req *request.MergeGuestRequest) (*response.GuestResponse, error) {
+	survivor, err := s.guestRepository.FindByID(req.SurvivorID)
+	if err != nil {
+		return nil, errors.New("Surviving Guest Not Found")
+	}
+	duplicate, err := s.guestRepository.FindByID(req.DuplicateID)
+	if err != nil {
+		return nil, errors.New("Duplicate Guest Not Found")
+	}
+	for _, g := range []*model.Guest{survivor, duplicate} {
+		if g.IsErased() || g.IsMerged() {
+			return nil, fmt.Errorf("guest %d has been erased or merged already", g.ID)
+		}
+	}
+
+	// The snapshots carry the merged id so the survivor's audit entry names
+	// the profile it absorbed.
+	type mergeSnapshot struct {
+		model.Guest
+		MergedGuestID *uint `json:",omitempty"`
+	}
+	survivorBefore := mergeSnapshot{Guest: *survivor}
+	duplicateBefore := *duplicate
+	if survivor.Phone == "" {
+		survivor.Phone = duplicate.Phone
+	}
+	if survivor.Email == "" {
+		survivor.Email = duplicate.Email
+	}
+	if reflect.DeepEqual(survivor.Preferences, model.GuestPreferences{}) {
+		survivor.Preferences = duplicate.Preferences
+	}
+	if err := s.guestRepository.Merge(survivor, duplicate); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityGuest, survivor.ID, model.AuditMerge,
+		&survivorBefore, &mergeSnapshot{Guest: *survivor, MergedGuestID: &duplicate.ID})
+	s.auditServices.Record(actor, model.EntityGuest, duplicate.ID, model.AuditMerge, &duplicateBefore, duplicate)
+	return mapGuestResponse(survivor), nil
+}
+
+const (
+	defaultSearchPageSize = 20
+	recentStaysShown      = 3
+)
+
+// Search matches the query against names (partially and with typos),
+// booking references, and exact phone numbers and emails. Matches are
+// ranked and paged by the repository.
+func (s *guestService) Search(params *request.GuestSearchParams) (*response.GuestSearchResponse, error) {
+	q := strings.TrimSpace(params.Query)
+	page, pageSize := params.Page, params.PageSize
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = defaultSearchPageSize
+	}
+	guests, total, err := s.guestRepository.Search(q, nameTokens(q), params.Sort, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(guests))
+	for i, g := range guests {
+		ids[i] = g.ID
+	}
+	stays, err := s.bookingRepository.FindByGuestIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	staysByGuest := make(map[uint][]*model.Booking)
+	for _, b := range stays {
+		staysByGuest[b.GuestID] = append(staysByGuest[b.GuestID], b)
+	}
+	resp := &response.GuestSearchResponse{
+		Results:  []response.GuestSearchResult{},
+		Page:     page,
+		PageSize: pageSize,
+		Total:    int(total),
+	}
+	for _, g := range guests {
+		guestStays := staysByGuest[g.ID]
+		result := response.GuestSearchResult{
+			Guest:       *mapGuestResponse(g),
+			MatchedOn:   searchMatchedOn(q, g, guestStays),
+			RecentStays: []response.StaySummary{},
+		}
+		for _, b := range guestStays[:min(len(guestStays), recentStaysShown)] {
+			result.RecentStays = append(result.RecentStays, mapToStaySummary(b))
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// searchMatchedOn tells staff why a guest was found. Names are the only
+// other way to match, so a guest found for no other reason matched on the
+// name, possibly by sound.
+func searchMatchedOn(q string, g *model.Guest, stays []*model.Booking) []string {
+	var matched []string
+	if phone := model.NormalizePhone(q); phone != "" && phone == model.NormalizePhone(g.Phone) {
+		matched = append(matched, "phone")
+	}
+	if email := model.NormalizeEmail(q); email != "" && email == model.NormalizeEmail(g.Email) {
+		matched = append(matched, "email")
+	}
+	for _, b := range stays {
+		if strings.Contains(strings.ToLower(b.BookingReference), strings.ToLower(q)) {
+			matched = append(matched, "booking_reference")
+			break
+		}
+	}
+	if nameSearchScore(q, g.FullName) > 0 || len(matched) == 0 {
+		matched = append(matched, "name")
+	}
+	return matched
+}
+
+func mapToStaySummary(b *model.Booking) response.StaySummary {
+	stay := response.StaySummary{
+		BookingID:    b.BookingReference,
+		PropertyID:   b.PropertyID,
+		CheckInDate:  b.CheckInDate.Format(request.DateLayout),
+		CheckOutDate: b.CheckOutDate.Format(request.DateLayout),
+		Status:       b.Status,
+	}
+	if b.Room != nil {
+		stay.RoomNumber = b.Room.Number
+	}
+	return stay
+}
+
+// History summarises every stay of the guest so staff can spot repeat and
+// high-value guests. Revenue is the room revenue of completed and current
+// stays, totalled per currency of the properties stayed at.
+func (s *guestService) History(id uint) (*response.GuestHistoryResponse, error) {
+	guest, err := s.guestRepository.FindByID(id)
+	if err != nil {
+		return nil, errors.New("Guest Not Found")
+	}
+	bookings, err := s.bookingRepository.FindByGuestID(guest.ID)
+	if err != nil {
+		return nil, err
+	}
+	history := &response.GuestHistoryResponse{
+		Guest:    *mapGuestResponse(guest),
+		Bookings: mapToBookingResponseSlice(bookings),
+		Revenue:  []response.RevenueResponse{},
+	}
+	properties := make(map[uint]*model.Property)
+	property := func(id uint) (*model.Property, error) {
+		if p, ok := properties[id]; ok {
+			return p, nil
+		}
+		p, err := s.propertyServices.GetPropertyModelByID(id)
+		if err != nil {
+			return nil, err
+		}
+		properties[id] = p
+		return p, nil
+	}
+	revenue := make(map[string]float64)
+	var lastStay time.Time
+	for _, b := range bookings {
+		switch b.Status {
+		case model.StatusCheckedIn, model.StatusCheckedOut:
+			p, err := property(b.PropertyID)
+			if err != nil {
+				return nil, err
+			}
+			history.TotalStays++
+			history.TotalNights += b.Nights()
+			revenue[p.Currency] += b.NightlyRate * float64(b.Nights())
+			if b.CheckInDate.After(lastStay) {
+				lastStay = b.CheckInDate
+			}
+		case model.StatusCancelled:
+			history.Cancellations++
+		case model.StatusPending, model.StatusConfirmed:
+			// Never checked in although the arrival day has passed.
+			p, err := property(b.PropertyID)
+			if err != nil {
+				return nil, err
+			}
+			if b.CheckInDate.Before(businessDate(s.clock, p)) {
+				history.NoShows++
+			}
+		}
+	}
+	for currency, amount := range revenue {
+		history.Revenue = append(history.Revenue, response.RevenueResponse{Currency: currency, Amount: roundAmount(amount)})
+	}
+	sort.Slice(history.Revenue, func(i, j int) bool { return history.Revenue[i].Currency < history.Revenue[j].Currency })
+	if !lastStay.IsZero() {
+		history.LastStayDate = lastStay.Format(request.DateLayout)
+	}
+	history.RepeatGuest = history.TotalStays > 1
+	return history, nil
+}
+
+func (s *guestService) AddFlag(actor *model.Principal, req *request.CreateGuestFlagRequest) (*response.GuestFlagResponse, error) {
+	flagType := model.GuestFlagType(strings.ToLower(strings.TrimSpace(req.Type)))
+	if !flagType.IsValid() {
+		return nil, errors.New("invalid flag type, must be 'do_not_rent', 'vip', 'requires_deposit' or 'fraud_suspicion'")
+	}
+	guest, err := s.guestRepository.FindByID(req.GuestID)
+	if err != nil {
+		return nil, errors.New("Guest Not Found")
+	}
+	if guest.IsErased() || guest.IsMerged() {
+		return nil, fmt.Errorf("guest %d has been erased or merged", guest.ID)
+	}
+	now := s.clock.Now()
+	flag := model.GuestFlag{
+		GuestID:   guest.ID,
+		Type:      flagType,
+		Reason:    strings.TrimSpace(req.Reason),
+		CreatedBy: actor.Name(),
+		CreatedAt: now,
+	}
+	if req.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, errors.New("invalid expires_at, must use RFC 3339 format")
+		}
+		if !expiresAt.After(now) {
+			return nil, errors.New("expires_at must be in the future")
+		}
+		flag.ExpiresAt = &expiresAt
+	}
+	if err := s.guestRepository.CreateFlag(&flag); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityGuestFlag, flag.ID, model.AuditCreate, nil, &flag)
+	return mapToGuestFlagResponse(&flag, now), nil
+}
+
+func (s *guestService) ListFlags(guestID uint) ([]*response.GuestFlagResponse, error) {
+	flags, err := s.guestRepository.FindFlags(guestID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	resp := make([]*response.GuestFlagResponse, len(flags))
+	for i, f := range flags {
+		resp[i] = mapToGuestFlagResponse(f, now)
+	}
+	return resp, nil
+}
+
+func (s *guestService) RemoveFlag(actor *model.Principal, flagID uint) (*response.GuestFlagResponse, error) {
+	flag, err := s.guestRepository.FindFlagByID(flagID)
+	if err != nil {
+		return nil, errors.New("Flag Not Found")
+	}
+	if flag.RemovedAt != nil {
+		return nil, errors.New("flag has already been removed")
+	}
+	before := *flag
+	now := s.clock.Now()
+	flag.RemovedAt = &now
+	flag.RemovedBy = actor.Name()
+	if err := s.guestRepository.UpdateFlag(flag); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityGuestFlag, flag.ID, model.AuditUpdate, &before, flag)
+	return mapToGuestFlagResponse(flag, now), nil
+}
+
+// CheckFlags applies the flag policy of the guest's active flags at the
+// given stage. It fails with ErrGuestRefused when any flag refuses, and
+// otherwise returns one warning per flag that asks for one.
+func (s *guestService) CheckFlags(guestID uint, stage FlagStage) ([]string, error) {
+	flags, err := s.guestRepository.FindFlags(guestID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	var warnings []string
+	for _, f := range flags {
+		if !f.IsActive(now) {
+			continue
+		}
+		policy := model.FlagPolicies[f.Type]
+		action := policy.Booking
+		if stage == FlagStageCheckIn {
+			action = policy.CheckIn
+		}
+		switch action {
+		case model.FlagActionRefuse:
+			return nil, ErrGuestRefused
+		case model.FlagActionWarn:
+			warnings = append(warnings, fmt.Sprintf("%s: %s", f.Type, f.Reason))
+		}
+	}
+	return warnings, nil
+}
+
+func mapToGuestFlagResponse(f *model.GuestFlag, now time.Time) *response.GuestFlagResponse {
+	return &response.GuestFlagResponse{
+		ID:        f.ID,
+		GuestID:   f.GuestID,
+		Type:      f.Type,
+		Reason:    f.Reason,
+		Active:    f.IsActive(now),
+		ExpiresAt: f.ExpiresAt,
+		CreatedBy: f.CreatedBy,
+		CreatedAt: f.CreatedAt,
+		RemovedAt: f.RemovedAt,
+		RemovedBy: f.RemovedBy,
+	}
+}
+
+// CredentialTypes lists the identity documents accepted at registration.
+func (s *guestService) CredentialTypes() []response.CredentialTypeResponse {
+	specs := model.CredentialSpecs()
+	types := make([]response.CredentialTypeResponse, len(specs))
+	for i, spec := range specs {
+		types[i] = mapToCredentialTypeResponse(spec)
 	}
+	return types
 }
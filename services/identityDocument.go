@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/request"
+	"hms-backend/response"
+	"strings"
+	"time"
+)
+
+// identityDocument is an identity document as submitted, once normalised and
+// checked against the credential registry.
+type identityDocument struct {
+	CredentialType string
+	IDNumber       string
+	IssuingCountry string
+	Expiry         *time.Time
+}
+
+func parseIdentityDocument(credentialType, idNumber, issuingCountry, expiry string, today time.Time) (*identityDocument, error) {
+	spec, ok := model.LookupCredentialSpec(credentialType)
+	if !ok {
+		return nil, fmt.Errorf("unknown credential type %q", credentialType)
+	}
+	doc := identityDocument{
+		CredentialType: string(spec.Type),
+		IDNumber:       model.NormalizeIDNumber(idNumber),
+		IssuingCountry: strings.ToUpper(strings.TrimSpace(issuingCountry)),
+	}
+	if expiry != "" {
+		t, err := request.ParseDate(expiry)
+		if err != nil {
+			return nil, errors.New("invalid document expiry, must be YYYY-MM-DD")
+		}
+		doc.Expiry = &t
+	}
+	if err := spec.Validate(doc.IDNumber, doc.IssuingCountry, doc.Expiry, today); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// checkDocumentExpiry rejects check-in when a document on file has expired.
+// Documents without an expiry date are left to the front desk.
+func checkDocumentExpiry(booking *model.Booking, today time.Time) error {
+	if booking.Guest != nil && model.IsDocumentExpired(booking.Guest.DocumentExpiry, today) {
+		return fmt.Errorf("main guest's identity document expired on %s", formatOptionalDate(booking.Guest.DocumentExpiry))
+	}
+	for i := range booking.Occupants {
+		o := &booking.Occupants[i]
+		if expiry := o.IdentityExpiry(); model.IsDocumentExpired(expiry, today) {
+			return fmt.Errorf("identity document of occupant %d expired on %s", o.ID, formatOptionalDate(expiry))
+		}
+	}
+	return nil
+}
+
+// formatOptionalDate formats a date that may be unset as an empty string.
+func formatOptionalDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(request.DateLayout)
+}
+
+func mapToCredentialTypeResponse(spec model.CredentialSpec) response.CredentialTypeResponse {
+	return response.CredentialTypeResponse{
+		Type:            spec.Type,
+		Name:            spec.Name,
+		Format:          spec.Format,
+		RequiresExpiry:  spec.RequiresExpiry,
+		RequiresCountry: spec.RequiresCountry,
+	}
+}
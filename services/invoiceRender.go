@@ -0,0 +1,337 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"hms-backend/model"
+	"html/template"
+	"strconv"
+	"strings"
+)
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount": formatInvoiceAmount,
+	"negate": func(v float64) float64 { return -v },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{if .CreditOf}}Credit note{{else}}Invoice{{end}} {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 13px; color: #222; margin: 40px; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+table { border-collapse: collapse; width: 100%; margin-top: 16px; }
+th, td { padding: 4px 6px; text-align: left; }
+th { border-bottom: 1px solid #999; }
+.num { text-align: right; font-family: Courier, monospace; }
+.parties td { vertical-align: top; width: 50%; padding: 0; }
+.totals td { border-top: 1px solid #ddd; }
+.due td { font-weight: bold; border-top: 1px solid #999; }
+</style>
+</head>
+<body>
+<h1>{{if .CreditOf}}Credit note{{else}}Invoice{{end}} {{.Number}}</h1>
+<div>Issued on {{.IssuedOn}}</div>
+{{with .CreditOf}}<div>Cancels invoice {{.}}{{with $.Reason}}: {{.}}{{end}}</div>{{end}}
+<table class="parties">
+<tr>
+<td>
+<strong>{{.Hotel.Name}}</strong><br>
+{{with .Hotel.Address}}{{.}}<br>{{end}}
+{{with .Hotel.TaxNumber}}Tax number: {{.}}{{end}}
+</td>
+<td>
+<strong>Bill to</strong><br>
+{{.BillTo.Name}}<br>
+{{with .BillTo.Address}}{{.}}<br>{{end}}
+{{with .BillTo.Email}}{{.}}{{end}}
+</td>
+</tr>
+</table>
+<table>
+<tr><th>Guest</th><th>Booking</th><th>Room</th><th>Arrival</th><th>Departure</th></tr>
+<tr><td>{{.GuestName}}</td><td>{{.BookingReference}}</td><td>{{.RoomNumber}} {{.RoomType}}</td><td>{{.CheckInDate}}</td><td>{{.CheckOutDate}}</td></tr>
+</table>
+<table>
+<tr><th>Date</th><th>Description</th><th class="num">Qty</th><th class="num">Unit price</th><th class="num">Amount ({{.Currency}})</th></tr>
+{{range .Lines}}<tr><td>{{.Date}}</td><td>{{.Description}}</td><td class="num">{{.Quantity}}</td><td class="num">{{amount .UnitPrice}}</td><td class="num">{{amount .Amount}}</td></tr>
+{{end}}
+<tr class="totals"><td colspan="4">Subtotal before tax</td><td class="num">{{amount .Subtotal}}</td></tr>
+{{range .Taxes}}<tr><td colspan="4">{{.Name}} {{.Rate}}% included on {{amount .Base}}</td><td class="num">{{amount .Amount}}</td></tr>
+{{end}}
+<tr class="totals"><td colspan="4"><strong>Total</strong></td><td class="num"><strong>{{amount .Total}}</strong></td></tr>
+{{range .Payments}}{{if not .Pending}}<tr><td>{{.Date}}</td><td colspan="3">{{if .Refund}}Refund{{else}}Payment{{end}}, {{.Method}}</td><td class="num">{{amount (negate .Amount)}}</td></tr>
+{{end}}{{end}}
+{{if not .CreditOf}}<tr class="due"><td colspan="4">Balance due ({{.Currency}})</td><td class="num">{{amount .BalanceDue}}</td></tr>
+{{range .Payments}}{{if .Pending}}<tr><td>{{.Date}}</td><td colspan="3">Not yet received, {{.Method}}</td><td class="num">{{amount .Amount}}</td></tr>
+{{end}}{{end}}{{end}}
+</table>
+</body>
+</html>
+`))
+
+func renderInvoiceHTML(doc *model.InvoiceDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := invoiceTemplate.Execute(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatInvoiceAmount prints an amount with two decimals and thousands
+// separators, e.g. 1,250,000.00.
+func formatInvoiceAmount(amount float64) string {
+	s := strconv.FormatFloat(amount, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + frac
+}
+
+// --- PDF ---
+
+// The PDF is written by hand: A4 pages with the standard Helvetica and
+// Courier fonts, which every reader ships, so no font is embedded. Amounts
+// use the monospaced Courier so they can be right-aligned without metrics.
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+	pdfLineHeight = 14.0
+)
+
+type pdfFont string
+
+const (
+	pdfRegular pdfFont = "F1"
+	pdfBold    pdfFont = "F2"
+	pdfMono    pdfFont = "F3"
+)
+
+type invoicePDF struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func (p *invoicePDF) newPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// line moves down by one line, starting a new page when the current one is full.
+func (p *invoicePDF) line() {
+	p.y -= pdfLineHeight
+	if p.y < pdfMargin {
+		p.newPage()
+		p.y -= pdfLineHeight
+	}
+}
+
+func (p *invoicePDF) text(x float64, font pdfFont, size float64, s string) {
+	fmt.Fprintf(p.pages[len(p.pages)-1], "BT /%s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, p.y, pdfEscape(s))
+}
+
+// amount writes s in Courier so that it ends at right.
+func (p *invoicePDF) amount(right float64, size float64, s string) {
+	p.text(right-0.6*size*float64(len(s)), pdfMono, size, s)
+}
+
+func (p *invoicePDF) rule() {
+	fmt.Fprintf(p.pages[len(p.pages)-1], "%.2f %.2f m %.2f %.2f l 0.5 w S\n",
+		pdfMargin, p.y-4, pdfPageWidth-pdfMargin, p.y-4)
+}
+
+func renderInvoicePDF(doc *model.InvoiceDocument) []byte {
+	const right = pdfPageWidth - pdfMargin
+	p := &invoicePDF{}
+	p.newPage()
+
+	title := "Invoice "
+	if doc.CreditOf != "" {
+		title = "Credit note "
+	}
+	p.text(pdfMargin, pdfBold, 18, title+doc.Number)
+	p.line()
+	p.text(pdfMargin, pdfRegular, 10, "Issued on "+doc.IssuedOn)
+	p.line()
+	if doc.CreditOf != "" {
+		cancels := "Cancels invoice " + doc.CreditOf
+		if doc.Reason != "" {
+			cancels += ": " + doc.Reason
+		}
+		p.text(pdfMargin, pdfRegular, 10, cancels)
+		p.line()
+	}
+	p.line()
+
+	hotel := invoicePartyLines(&doc.Hotel)
+	billTo := invoicePartyLines(&doc.BillTo)
+	p.text(pdfMargin, pdfBold, 10, hotel[0])
+	p.text(320, pdfBold, 10, "Bill to")
+	for i := 1; i < len(hotel) || i-1 < len(billTo); i++ {
+		p.line()
+		if i < len(hotel) {
+			p.text(pdfMargin, pdfRegular, 10, hotel[i])
+		}
+		if i-1 < len(billTo) {
+			p.text(320, pdfRegular, 10, billTo[i-1])
+		}
+	}
+	p.line()
+	p.line()
+
+	p.text(pdfMargin, pdfRegular, 10, "Guest: "+doc.GuestName)
+	p.text(320, pdfRegular, 10, "Booking: "+doc.BookingReference)
+	p.line()
+	p.text(pdfMargin, pdfRegular, 10, strings.TrimSpace("Room: "+doc.RoomNumber+" "+doc.RoomType))
+	p.text(320, pdfRegular, 10, "Stay: "+doc.CheckInDate+" to "+doc.CheckOutDate)
+	p.line()
+	p.line()
+
+	p.text(pdfMargin, pdfBold, 10, "Date")
+	p.text(130, pdfBold, 10, "Description")
+	p.text(350, pdfBold, 10, "Qty")
+	p.text(400, pdfBold, 10, "Unit price")
+	p.text(right-70, pdfBold, 10, "Amount "+doc.Currency)
+	p.rule()
+	for _, l := range doc.Lines {
+		p.line()
+		p.text(pdfMargin, pdfRegular, 10, l.Date)
+		p.text(130, pdfRegular, 10, l.Description)
+		p.amount(370, 10, strconv.Itoa(l.Quantity))
+		p.amount(460, 10, formatInvoiceAmount(l.UnitPrice))
+		p.amount(right, 10, formatInvoiceAmount(l.Amount))
+	}
+	p.rule()
+	p.line()
+	p.text(130, pdfRegular, 10, "Subtotal before tax")
+	p.amount(right, 10, formatInvoiceAmount(doc.Subtotal))
+	for _, t := range doc.Taxes {
+		p.line()
+		p.text(130, pdfRegular, 10, fmt.Sprintf("%s %g%% included on %s", t.Name, t.Rate, formatInvoiceAmount(t.Base)))
+		p.amount(right, 10, formatInvoiceAmount(t.Amount))
+	}
+	p.line()
+	p.text(130, pdfBold, 10, "Total")
+	p.amount(right, 10, formatInvoiceAmount(doc.Total))
+	for _, pay := range doc.Payments {
+		if pay.Pending {
+			continue
+		}
+		p.line()
+		p.text(pdfMargin, pdfRegular, 10, pay.Date)
+		label := "Payment, "
+		if pay.Refund {
+			label = "Refund, "
+		}
+		p.text(130, pdfRegular, 10, label+pay.Method)
+		p.amount(right, 10, formatInvoiceAmount(-pay.Amount))
+	}
+	if doc.CreditOf != "" {
+		return p.bytes()
+	}
+	p.rule()
+	p.line()
+	p.text(130, pdfBold, 10, "Balance due "+doc.Currency)
+	p.amount(right, 10, formatInvoiceAmount(doc.BalanceDue))
+	for _, pay := range doc.Payments {
+		if !pay.Pending {
+			continue
+		}
+		p.line()
+		p.text(pdfMargin, pdfRegular, 10, pay.Date)
+		p.text(130, pdfRegular, 10, "Not yet received, "+pay.Method)
+		p.amount(right, 10, formatInvoiceAmount(pay.Amount))
+	}
+
+	return p.bytes()
+}
+
+func invoicePartyLines(party *model.InvoiceParty) []string {
+	lines := []string{party.Name}
+	for _, l := range strings.Split(party.Address, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	if party.Email != "" {
+		lines = append(lines, party.Email)
+	}
+	if party.TaxNumber != "" {
+		lines = append(lines, "Tax number: "+party.TaxNumber)
+	}
+	return lines
+}
+
+// bytes assembles the document: catalog, page tree, fonts, then a page and
+// content stream per page, followed by the cross-reference table.
+func (p *invoicePDF) bytes() []byte {
+	var objects []string
+	add := func(obj string) int {
+		objects = append(objects, obj)
+		return len(objects)
+	}
+	add("<< /Type /Catalog /Pages 2 0 R >>")
+	add("") // page tree, filled in once the page objects are numbered
+	fonts := map[pdfFont]string{pdfRegular: "Helvetica", pdfBold: "Helvetica-Bold", pdfMono: "Courier"}
+	fontRefs := make([]string, 0, len(fonts))
+	for _, f := range []pdfFont{pdfRegular, pdfBold, pdfMono} {
+		id := add(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fonts[f]))
+		fontRefs = append(fontRefs, fmt.Sprintf("/%s %d 0 R", f, id))
+	}
+	resources := "<< /Font << " + strings.Join(fontRefs, " ") + " >> >>"
+	kids := make([]string, len(p.pages))
+	for i, page := range p.pages {
+		content := add(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+		id := add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources %s /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, resources, content))
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdfEscape converts s to WinAnsi for a PDF string literal. Characters the
+// standard fonts cannot show are replaced with a question mark.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case r == '€':
+			b.WriteString("\\200")
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
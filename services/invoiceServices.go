@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+)
+
+type InvoiceServices interface {
+	Issue(actor *model.Principal, req *request.IssueInvoiceRequest) (*response.InvoiceResponse, error)
+	Void(actor *model.Principal, req *request.VoidInvoiceRequest) (*response.InvoiceResponse, error)
+	GetInvoice(ref, number, format string) (*response.InvoiceFileResponse, error)
+}
+
+// Invoice formats that can be downloaded.
+const (
+	InvoiceFormatPDF  = "pdf"
+	InvoiceFormatHTML = "html"
+)
+
+type invoiceServices struct {
+	invoiceRepository     repository.InvoiceRepository
+	bookingRepository     repository.BookingRepository
+	transactionRepository repository.TransactionRepository
+	corporateRepository   repository.CorporateRepository
+	propertyServices      PropertyServices
+	auditServices         AuditServices
+	clock                 Clock
+}
+
+func NewInvoiceServices(repo repository.InvoiceRepository, booking repository.BookingRepository, transaction repository.TransactionRepository, corporate repository.CorporateRepository, property PropertyServices, audit AuditServices, clock Clock) InvoiceServices {
+	return &invoiceServices{
+		invoiceRepository:     repo,
+		bookingRepository:     booking,
+		transactionRepository: transaction,
+		corporateRepository:   corporate,
+		propertyServices:      property,
+		auditServices:         audit,
+		clock:                 clock,
+	}
+}
+
+// Issue invoices a checked-out booking under the next number of its
+// property. A booking has one invoice at a time; to change it, it is voided
+// and issued again.
+func (s *invoiceServices) Issue(actor *model.Principal, req *request.IssueInvoiceRequest) (*response.InvoiceResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if booking.Status != model.StatusCheckedOut {
+		return nil, errors.New("invoices are issued once the guest has checked out")
+	}
+	if inv, err := s.invoiceRepository.FindActiveByBookingID(booking.ID); err == nil {
+		return nil, fmt.Errorf("booking already has invoice %s, void it to issue a new one", inv.Number)
+	}
+	property, err := s.propertyServices.GetPropertyModelByID(booking.PropertyID)
+	if err != nil {
+		return nil, errors.New("Property Not Found")
+	}
+	transactions, err := s.transactionRepository.FindByBookingID(booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	doc := model.InvoiceDocument{
+		IssuedOn: businessDate(s.clock, property).Format(request.DateLayout),
+		Currency: property.Currency,
+		Hotel: model.InvoiceParty{
+			Name:      property.Name,
+			Address:   property.Address,
+			TaxNumber: property.TaxNumber,
+		},
+		BookingReference: booking.BookingReference,
+		CheckInDate:      booking.CheckInDate.Format(request.DateLayout),
+		CheckOutDate:     booking.CheckOutDate.Format(request.DateLayout),
+	}
+	if booking.Guest != nil {
+		doc.GuestName = booking.Guest.FullName
+	}
+	doc.BillTo = model.InvoiceParty{Name: doc.GuestName}
+	if booking.DirectBill && booking.CorporateAccountID != nil {
+		account, err := s.corporateRepository.FindByID(*booking.CorporateAccountID)
+		if err != nil {
+			return nil, errors.New("Corporate Account Not Found")
+		}
+		doc.BillTo = model.InvoiceParty{Name: account.Name, Address: account.BillingAddress, Email: account.ContactEmail}
+	}
+	if booking.Room != nil {
+		doc.RoomNumber = booking.Room.Number
+		doc.RoomType = booking.Room.RoomType.Name
+	}
+
+	doc.Lines = invoiceLines(booking)
+	for _, line := range doc.Lines {
+		doc.Total += line.Amount
+	}
+	doc.Total = roundAmount(doc.Total)
+	doc.Subtotal = doc.Total
+	if property.TaxRate > 0 {
+		// Prices include tax, so the tax is taken out of the total.
+		base := roundAmount(doc.Total / (1 + property.TaxRate/100))
+		doc.Taxes = []model.InvoiceTax{{
+			Name:   property.TaxName,
+			Rate:   property.TaxRate,
+			Base:   base,
+			Amount: roundAmount(doc.Total - base),
+		}}
+		doc.Subtotal = base
+	}
+	applyInvoicePayments(&doc, transactions, property)
+
+	inv := model.Invoice{
+		PropertyID: property.ID,
+		BookingID:  booking.ID,
+		Total:      doc.Total,
+		BalanceDue: doc.BalanceDue,
+		Document:   doc,
+		IssuedBy:   actor.Name(),
+		CreatedAt:  s.clock.Now(),
+	}
+	if err := s.invoiceRepository.CreateNumbered(&inv, invoiceNumber(property)); err != nil {
+		if errors.Is(err, repository.ErrInvoiceIssued) {
+			return nil, errors.New("booking was invoiced in the meantime")
+		}
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityInvoice, inv.ID, model.AuditCreate, nil, &inv)
+	return mapToInvoiceResponse(&inv), nil
+}
+
+// Void cancels the booking's invoice with a credit note for its full amount.
+// The invoice keeps its number; the credit note takes the next one.
+func (s *invoiceServices) Void(actor *model.Principal, req *request.VoidInvoiceRequest) (*response.InvoiceResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	inv, err := s.invoiceRepository.FindActiveByBookingID(booking.ID)
+	if err != nil {
+		return nil, errors.New("booking has no invoice to void")
+	}
+	property, err := s.propertyServices.GetPropertyModelByID(inv.PropertyID)
+	if err != nil {
+		return nil, errors.New("Property Not Found")
+	}
+	doc := creditNoteDocument(&inv.Document)
+	doc.IssuedOn = businessDate(s.clock, property).Format(request.DateLayout)
+	doc.Reason = req.Reason
+	note := model.Invoice{
+		PropertyID: inv.PropertyID,
+		BookingID:  inv.BookingID,
+		Total:      doc.Total,
+		Document:   doc,
+		CreditOfID: &inv.ID,
+		IssuedBy:   actor.Name(),
+		CreatedAt:  s.clock.Now(),
+	}
+	before := *inv
+	if err := s.invoiceRepository.Void(inv, &note, invoiceNumber(property)); err != nil {
+		if errors.Is(err, repository.ErrInvoiceVoided) {
+			return nil, errors.New("invoice was voided in the meantime")
+		}
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityInvoice, inv.ID, model.AuditUpdate, &before, inv)
+	s.auditServices.Record(actor, model.EntityInvoice, note.ID, model.AuditCreate, nil, &note)
+	return mapToInvoiceResponse(&note), nil
+}
+
+// GetInvoice renders the booking's current invoice, or the invoice or credit
+// note with the given number. The charges are rendered as issued; the
+// payments of an invoice are those recorded up to now.
+func (s *invoiceServices) GetInvoice(ref, number, format string) (*response.InvoiceFileResponse, error) {
+	if format != InvoiceFormatPDF && format != InvoiceFormatHTML {
+		return nil, errors.New("invoice format must be pdf or html")
+	}
+	booking, err := s.bookingRepository.FindByReferenceID(ref)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	var inv *model.Invoice
+	if number == "" {
+		if inv, err = s.invoiceRepository.FindActiveByBookingID(booking.ID); err != nil {
+			return nil, errors.New("booking has not been invoiced")
+		}
+	} else if inv, err = s.invoiceRepository.FindByNumber(number); err != nil || inv.BookingID != booking.ID {
+		return nil, errors.New("Invoice Not Found")
+	}
+	doc := inv.Document
+	if !inv.IsCreditNote() {
+		property, err := s.propertyServices.GetPropertyModelByID(inv.PropertyID)
+		if err != nil {
+			return nil, errors.New("Property Not Found")
+		}
+		transactions, err := s.transactionRepository.FindByBookingID(booking.ID)
+		if err != nil {
+			return nil, err
+		}
+		applyInvoicePayments(&doc, transactions, property)
+	}
+	if format == InvoiceFormatHTML {
+		content, err := renderInvoiceHTML(&doc)
+		if err != nil {
+			return nil, err
+		}
+		return &response.InvoiceFileResponse{
+			Number:      inv.Number,
+			FileName:    inv.Number + ".html",
+			ContentType: "text/html; charset=utf-8",
+			Content:     content,
+		}, nil
+	}
+	return &response.InvoiceFileResponse{
+		Number:      inv.Number,
+		FileName:    inv.Number + ".pdf",
+		ContentType: "application/pdf",
+		Content:     renderInvoicePDF(&doc),
+	}, nil
+}
+
+func invoiceNumber(property *model.Property) func(sequence uint) string {
+	return func(sequence uint) string {
+		return fmt.Sprintf("%s-%06d", property.Code, sequence)
+	}
+}
+
+// applyInvoicePayments lists every payment and refund of the booking on the
+// invoice. Those not received yet, such as charges billed to a company or
+// card payments not captured, are shown as pending and not deducted.
+func applyInvoicePayments(doc *model.InvoiceDocument, transactions []*model.Transaction, property *model.Property) {
+	doc.Payments = nil
+	doc.Paid = 0
+	for _, t := range transactions {
+		if t.GatewayStatus == model.GatewayFailed || t.GatewayStatus == model.GatewayVoided {
+			continue
+		}
+		payment := model.InvoicePayment{
+			Date:    t.CreatedAt.In(propertyLocation(property)).Format(request.DateLayout),
+			Method:  t.PaymentMethod,
+			Refund:  t.IsRefund(),
+			Pending: !t.Paid,
+			Amount:  t.Amount,
+		}
+		if t.IsRefund() {
+			// Refunds are listed as negative payments.
+			payment.Amount = -t.Amount
+		}
+		doc.Payments = append(doc.Payments, payment)
+		doc.Paid += t.Received()
+	}
+	doc.Paid = roundAmount(doc.Paid)
+	doc.BalanceDue = roundAmount(doc.Total - doc.Paid)
+}
+
+// creditNoteDocument copies an invoice with its amounts negated.
+func creditNoteDocument(inv *model.InvoiceDocument) model.InvoiceDocument {
+	doc := *inv
+	doc.CreditOf = inv.Number
+	doc.Lines = make([]model.InvoiceLine, len(inv.Lines))
+	for i, l := range inv.Lines {
+		l.UnitPrice, l.Amount = -l.UnitPrice, -l.Amount
+		doc.Lines[i] = l
+	}
+	doc.Taxes = make([]model.InvoiceTax, len(inv.Taxes))
+	for i, t := range inv.Taxes {
+		t.Base, t.Amount = -t.Base, -t.Amount
+		doc.Taxes[i] = t
+	}
+	doc.Subtotal, doc.Total = -inv.Subtotal, -inv.Total
+	doc.Payments, doc.Paid, doc.BalanceDue = nil, 0, 0
+	return doc
+}
+
+func mapToInvoiceResponse(inv *model.Invoice) *response.InvoiceResponse {
+	return &response.InvoiceResponse{
+		Number:     inv.Number,
+		BookingID:  inv.Document.BookingReference,
+		IssuedOn:   inv.Document.IssuedOn,
+		Total:      inv.Total,
+		BalanceDue: inv.BalanceDue,
+		CreditOf:   inv.Document.CreditOf,
+		VoidedAt:   inv.VoidedAt,
+		IssuedBy:   inv.IssuedBy,
+	}
+}
+
+// invoiceLines bills every night at the rate captured on the booking, which
+// already holds the supplements and the corporate discount, so a later change
+// of the room type price does not change what the guest agreed to pay.
+func invoiceLines(booking *model.Booking) []model.InvoiceLine {
+	description := "Accommodation"
+	if booking.Room != nil {
+		description += ", " + booking.Room.RoomType.Name
+	}
+	if booking.CorporateDiscount > 0 {
+		description += fmt.Sprintf(", corporate rate %g%% off", booking.CorporateDiscount)
+	}
+	nights := booking.Nights()
+	lines := make([]model.InvoiceLine, 0, nights)
+	for i := 0; i < nights; i++ {
+		lines = append(lines, model.InvoiceLine{
+			Date:        booking.CheckInDate.AddDate(0, 0, i).Format(request.DateLayout),
+			Description: description,
+			Quantity:    1,
+			UnitPrice:   booking.NightlyRate,
+			Amount:      booking.NightlyRate,
+		})
+	}
+	return lines
+}
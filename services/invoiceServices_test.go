@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"testing"
+	"time"
+)
+
+// memoryInvoices numbers invoices from one sequence per property and, like
+// the SQL repository, only takes a number when the invoice is stored.
+type memoryInvoices struct {
+	repository.InvoiceRepository
+	rows     []*model.Invoice
+	sequence map[uint]uint
+}
+
+func (r *memoryInvoices) FindActiveByBookingID(bookingID string) (*model.Invoice, error) {
+	for _, inv := range r.rows {
+		if inv.BookingID == bookingID && inv.CreditOfID == nil && inv.VoidedAt == nil {
+			i := *inv
+			return &i, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memoryInvoices) FindByNumber(number string) (*model.Invoice, error) {
+	for _, inv := range r.rows {
+		if inv.Number == number {
+			i := *inv
+			return &i, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memoryInvoices) CreateNumbered(inv *model.Invoice, number func(sequence uint) string) error {
+	if _, err := r.FindActiveByBookingID(inv.BookingID); err == nil {
+		return repository.ErrInvoiceIssued
+	}
+	r.store(inv, number)
+	return nil
+}
+
+func (r *memoryInvoices) Void(inv *model.Invoice, note *model.Invoice, number func(sequence uint) string) error {
+	for _, row := range r.rows {
+		if row.ID != inv.ID {
+			continue
+		}
+		if row.VoidedAt != nil {
+			return repository.ErrInvoiceVoided
+		}
+		row.VoidedAt = &note.CreatedAt
+		inv.VoidedAt = &note.CreatedAt
+		r.store(note, number)
+		return nil
+	}
+	return errors.New("record not found")
+}
+
+func (r *memoryInvoices) store(inv *model.Invoice, number func(sequence uint) string) {
+	r.sequence[inv.PropertyID]++
+	inv.Sequence = r.sequence[inv.PropertyID]
+	inv.Number = number(inv.Sequence)
+	inv.Document.Number = inv.Number
+	inv.ID = uint(len(r.rows) + 1)
+	i := *inv
+	r.rows = append(r.rows, &i)
+}
+
+type invoiceTest struct {
+	booking  *model.Booking
+	invoices *memoryInvoices
+	services InvoiceServices
+}
+
+func newInvoiceTest() *invoiceTest {
+	checkIn := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	booking := &model.Booking{
+		ID:               testBookingID,
+		BookingReference: "BK1",
+		Status:           model.StatusCheckedOut,
+		PropertyID:       1,
+		CheckInDate:      checkIn,
+		CheckOutDate:     checkIn.AddDate(0, 0, 2),
+		NightlyRate:      80,
+	}
+	invoices := &memoryInvoices{sequence: make(map[uint]uint)}
+	clock := NewFixedClock(time.Date(2026, 5, 3, 12, 0, 0, 0, time.UTC))
+	return &invoiceTest{
+		booking:  booking,
+		invoices: invoices,
+		services: NewInvoiceServices(invoices, &oneBooking{booking: booking}, newMemoryTransactions(), nil, oneProperty{}, noAudit{}, clock),
+	}
+}
+
+func (r *invoiceTest) issue(t *testing.T) string {
+	t.Helper()
+	res, err := r.services.Issue(testActor, &request.IssueInvoiceRequest{BookingReference: "BK1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.Number
+}
+
+func TestIssueNumbersInvoicesWithoutGaps(t *testing.T) {
+	r := newInvoiceTest()
+	r.booking.Status = model.StatusCheckedIn
+	if _, err := r.services.Issue(testActor, &request.IssueInvoiceRequest{BookingReference: "BK1"}); err == nil {
+		t.Fatal("a booking was invoiced before check-out")
+	}
+	r.booking.Status = model.StatusCheckedOut
+
+	if number := r.issue(t); number != "HQ-000001" {
+		t.Errorf("first invoice numbered %s, want HQ-000001", number)
+	}
+	if _, err := r.services.Issue(testActor, &request.IssueInvoiceRequest{BookingReference: "BK1"}); err == nil {
+		t.Error("a booking was invoiced twice")
+	}
+	if got := r.invoices.sequence[1]; got != 1 {
+		t.Errorf("sequence at %d after rejected issues, want 1", got)
+	}
+}
+
+func TestVoidIssuesCreditNote(t *testing.T) {
+	r := newInvoiceTest()
+	number := r.issue(t)
+
+	note, err := r.services.Void(testActor, &request.VoidInvoiceRequest{BookingReference: "BK1", Reason: "Wrong rate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Number != "HQ-000002" || note.Total != -160 || note.CreditOf != number {
+		t.Errorf("credit note = %+v, want HQ-000002 of -160.00 crediting %s", note, number)
+	}
+	voided, _ := r.invoices.FindByNumber(number)
+	if voided.VoidedAt == nil {
+		t.Error("voided invoice has no void date")
+	}
+	if _, err := r.services.Void(testActor, &request.VoidInvoiceRequest{BookingReference: "BK1", Reason: "Again"}); err == nil {
+		t.Error("an invoice was voided twice")
+	}
+
+	if number := r.issue(t); number != "HQ-000003" {
+		t.Errorf("reissued invoice numbered %s, want HQ-000003", number)
+	}
+}
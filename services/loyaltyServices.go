@@ -0,0 +1,448 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"math"
+	"time"
+)
+
+type LoyaltyServices interface {
+	Enroll(actor *model.Principal, req *request.EnrollLoyaltyRequest) (*response.LoyaltyAccountResponse, error)
+	GetAccount(guestID uint) (*response.LoyaltyAccountResponse, error)
+	GetLedger(guestID uint) ([]*response.LoyaltyEntryResponse, error)
+	Redeem(actor *model.Principal, req *request.RedeemPointsRequest) (*response.LoyaltyRedemptionResponse, error)
+	EarnForStay(actor *model.Principal, booking *model.Booking) (int, error)
+	CreditStay(actor *model.Principal, req *request.CreditStayRequest) (*response.LoyaltyEarningResponse, error)
+}
+
+// tierJob is recorded as the actor of tier changes found when an account is
+// read.
+const tierJob = "loyalty-tiers"
+
+type loyaltyServices struct {
+	loyaltyRepository     repository.LoyaltyRepository
+	guestRepository       repository.GuestRepository
+	bookingRepository     repository.BookingRepository
+	transactionRepository repository.TransactionRepository
+	auditServices         AuditServices
+	clock                 Clock
+}
+
+func NewLoyaltyServices(repo repository.LoyaltyRepository, guest repository.GuestRepository, booking repository.BookingRepository, transaction repository.TransactionRepository, audit AuditServices, clock Clock) LoyaltyServices {
+	return &loyaltyServices{
+		loyaltyRepository:     repo,
+		guestRepository:       guest,
+		bookingRepository:     booking,
+		transactionRepository: transaction,
+		auditServices:         audit,
+		clock:                 clock,
+	}
+}
+
+func (s *loyaltyServices) Enroll(actor *model.Principal, req *request.EnrollLoyaltyRequest) (*response.LoyaltyAccountResponse, error) {
+	guest, err := s.guestRepository.FindByID(req.GuestID)
+	if err != nil {
+		return nil, errors.New("Guest Not Found")
+	}
+	if guest.IsErased() {
+		return nil, errors.New("guest data has been erased")
+	}
+	if guest.IsMerged() {
+		return nil, fmt.Errorf("guest profile was merged into guest %d", *guest.MergedIntoID)
+	}
+	if _, err := s.loyaltyRepository.FindAccountByGuestID(guest.ID); err == nil {
+		return nil, errors.New("guest is already enrolled")
+	}
+	now := s.clock.Now()
+	account := model.LoyaltyAccount{
+		GuestID:       guest.ID,
+		MemberNumber:  fmt.Sprintf("M%09d", guest.ID),
+		Tier:          model.TierMember,
+		TierReachedAt: now,
+		CreatedAt:     now,
+	}
+	// Stays from the qualifying period before enrolment count towards a tier.
+	if _, err := s.evaluateTier(&account); err != nil {
+		return nil, err
+	}
+	if err := s.loyaltyRepository.CreateAccount(&account); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityLoyaltyAccount, account.ID, model.AuditCreate, nil, &account)
+	return s.mapToAccountResponse(&account)
+}
+
+// GetAccount returns the guest's account. A tier the guest no longer
+// qualifies for, or newly qualifies for, is applied first.
+func (s *loyaltyServices) GetAccount(guestID uint) (*response.LoyaltyAccountResponse, error) {
+	account, err := s.loyaltyRepository.FindAccountByGuestID(guestID)
+	if err != nil {
+		return nil, errors.New("guest is not enrolled in the loyalty program")
+	}
+	if err := s.refreshTier(model.JobPrincipal(tierJob), account); err != nil {
+		return nil, err
+	}
+	return s.mapToAccountResponse(account)
+}
+
+func (s *loyaltyServices) GetLedger(guestID uint) ([]*response.LoyaltyEntryResponse, error) {
+	if _, err := s.loyaltyRepository.FindAccountByGuestID(guestID); err != nil {
+		return nil, errors.New("guest is not enrolled in the loyalty program")
+	}
+	entries, err := s.loyaltyRepository.FindEntries(guestID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.LoyaltyEntryResponse, len(entries))
+	for i, e := range entries {
+		resp[i] = mapToLoyaltyEntryResponse(e)
+	}
+	return resp, nil
+}
+
+// Redeem pays part of a booking's room charges with points. The payment is
+// recorded as a paid transaction with the loyalty payment method.
+func (s *loyaltyServices) Redeem(actor *model.Principal, req *request.RedeemPointsRequest) (*response.LoyaltyRedemptionResponse, error) {
+	if req.Points < model.MinRedemption {
+		return nil, fmt.Errorf("at least %d points must be redeemed", model.MinRedemption)
+	}
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if !booking.IsActive() {
+		return nil, errors.New("points can only be redeemed on an active booking")
+	}
+	account, err := s.loyaltyRepository.FindAccountByGuestID(booking.GuestID)
+	if err != nil {
+		return nil, errors.New("guest is not enrolled in the loyalty program")
+	}
+	if err := s.refreshTier(actor, account); err != nil {
+		return nil, err
+	}
+	if err := s.expirePoints(booking.GuestID); err != nil {
+		return nil, pointsPostError(err)
+	}
+	earnings, err := s.loyaltyRepository.FindOpenEarnings(booking.GuestID)
+	if err != nil {
+		return nil, err
+	}
+	earnings = unexpired(earnings, businessDate(s.clock, nil))
+	if balance := sumRemaining(earnings); req.Points > balance {
+		return nil, fmt.Errorf("not enough points, balance is %d", balance)
+	}
+
+	amount := roundAmount(float64(req.Points) * model.PointValue)
+	transactions, err := s.transactionRepository.FindByBookingID(booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := booking.RoomCharges()
+	for _, t := range transactions {
+		outstanding -= t.Received()
+	}
+	if amount > roundAmount(outstanding) {
+		return nil, fmt.Errorf("redemption of %.2f exceeds the outstanding room charges of %.2f", amount, math.Max(outstanding, 0))
+	}
+
+	now := s.clock.Now()
+	payment := model.Transaction{
+		BookingID:     booking.ID,
+		Type:          model.TransactionPayment,
+		Amount:        amount,
+		PaymentMethod: model.PaymentMethodLoyalty,
+		Paid:          true,
+		CreatedAt:     now,
+	}
+	entry := model.LoyaltyEntry{
+		GuestID:     booking.GuestID,
+		Type:        model.LoyaltyRedeem,
+		Points:      -req.Points,
+		BookingID:   &booking.ID,
+		Description: fmt.Sprintf("Redeemed on booking %s", booking.BookingReference),
+		CreatedAt:   now,
+	}
+	draws := drawDown(earnings, req.Points)
+	if err := s.loyaltyRepository.Post([]*model.LoyaltyEntry{&entry}, draws, &payment); err != nil {
+		return nil, pointsPostError(err)
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, payment.Id, model.AuditCreate, nil, &payment)
+	s.auditServices.Record(actor, model.EntityLoyaltyEntry, entry.ID, model.AuditCreate, nil, &entry)
+
+	accountResp, err := s.mapToAccountResponse(account)
+	if err != nil {
+		return nil, err
+	}
+	return &response.LoyaltyRedemptionResponse{
+		Transaction: *mapToTransactionResponse(&payment),
+		Account:     *accountResp,
+	}, nil
+}
+
+// EarnForStay credits points for a checked-out stay and re-evaluates the
+// guest's tier. Guests who are not enrolled earn nothing. It returns the
+// points earned.
+func (s *loyaltyServices) EarnForStay(actor *model.Principal, booking *model.Booking) (int, error) {
+	if booking.Status != model.StatusCheckedOut {
+		return 0, errors.New("points are only earned on checked out stays")
+	}
+	account, err := s.loyaltyRepository.FindAccountByGuestID(booking.GuestID)
+	if err != nil {
+		return 0, nil
+	}
+	if earned, err := s.loyaltyRepository.HasEarning(booking.ID); err != nil || earned {
+		return 0, err
+	}
+	revenue := booking.RoomCharges()
+	rule := model.TierRuleFor(account.Tier)
+	points := int(math.Floor(revenue * model.PointsPerUnit * rule.EarnMultiplier))
+	if points > 0 {
+		expiresAt := booking.CheckOutDate.AddDate(0, model.PointsValidityMonths, 0)
+		entry := model.LoyaltyEntry{
+			GuestID:     booking.GuestID,
+			Type:        model.LoyaltyEarn,
+			Points:      points,
+			Remaining:   points,
+			ExpiresAt:   &expiresAt,
+			BookingID:   &booking.ID,
+			Description: fmt.Sprintf("Stay %s, %d night(s) as %s", booking.BookingReference, booking.Nights(), account.Tier),
+			CreatedAt:   s.clock.Now(),
+		}
+		if err := s.loyaltyRepository.Earn(&entry); err != nil {
+			if errors.Is(err, repository.ErrStayEarned) {
+				return 0, nil
+			}
+			return 0, err
+		}
+		s.auditServices.Record(actor, model.EntityLoyaltyEntry, entry.ID, model.AuditCreate, nil, &entry)
+	}
+	return points, s.refreshTier(actor, account)
+}
+
+// CreditStay credits the points of a checked-out stay that were not credited
+// at check-out, for example because the ledger could not be written then. A
+// stay only ever earns once.
+func (s *loyaltyServices) CreditStay(actor *model.Principal, req *request.CreditStayRequest) (*response.LoyaltyEarningResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if _, err := s.loyaltyRepository.FindAccountByGuestID(booking.GuestID); err != nil {
+		return nil, errors.New("guest is not enrolled in the loyalty program")
+	}
+	if earned, err := s.loyaltyRepository.HasEarning(booking.ID); err != nil {
+		return nil, err
+	} else if earned {
+		return nil, repository.ErrStayEarned
+	}
+	points, err := s.EarnForStay(actor, booking)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.loyaltyRepository.FindAccountByGuestID(booking.GuestID)
+	if err != nil {
+		return nil, err
+	}
+	accountResp, err := s.mapToAccountResponse(account)
+	if err != nil {
+		return nil, err
+	}
+	return &response.LoyaltyEarningResponse{PointsEarned: points, Account: *accountResp}, nil
+}
+
+// refreshTier applies the tier the account qualifies for now and records
+// the change.
+func (s *loyaltyServices) refreshTier(actor *model.Principal, account *model.LoyaltyAccount) error {
+	before := *account
+	changed, err := s.evaluateTier(account)
+	if err != nil || !changed {
+		return err
+	}
+	if err := s.loyaltyRepository.UpdateAccount(account); err != nil {
+		return err
+	}
+	s.auditServices.Record(actor, model.EntityLoyaltyAccount, account.ID, model.AuditUpdate, &before, account)
+	return nil
+}
+
+// qualifyingActivity sums the nights and room revenue of the guest's stays
+// checked out within the qualifying period.
+func (s *loyaltyServices) qualifyingActivity(guestID uint) (int, float64, error) {
+	bookings, err := s.bookingRepository.FindByGuestID(guestID)
+	if err != nil {
+		return 0, 0, err
+	}
+	since := businessDate(s.clock, nil).AddDate(0, -model.TierQualifyingMonths, 0)
+	var nights int
+	var spend float64
+	for _, b := range bookings {
+		if b.Status != model.StatusCheckedOut || b.CheckOutDate.Before(since) {
+			continue
+		}
+		nights += b.Nights()
+		spend += b.RoomCharges()
+	}
+	return nights, spend, nil
+}
+
+// evaluateTier moves the account to the tier its recent activity qualifies
+// for. Upgrades apply at once; a lower tier only applies once the current
+// one has been held for a full qualifying period.
+func (s *loyaltyServices) evaluateTier(account *model.LoyaltyAccount) (bool, error) {
+	nights, spend, err := s.qualifyingActivity(account.GuestID)
+	if err != nil {
+		return false, err
+	}
+	qualified := model.QualifiedTier(nights, spend)
+	now := s.clock.Now()
+	switch {
+	case model.TierRank(qualified) > model.TierRank(account.Tier):
+	case model.TierRank(qualified) < model.TierRank(account.Tier) &&
+		now.After(account.TierReachedAt.AddDate(0, model.TierQualifyingMonths, 0)):
+	default:
+		return false, nil
+	}
+	account.Tier = qualified
+	account.TierReachedAt = now
+	return true, nil
+}
+
+// expirePoints writes off the unused part of every lapsed earning.
+func (s *loyaltyServices) expirePoints(guestID uint) error {
+	earnings, err := s.loyaltyRepository.FindOpenEarnings(guestID)
+	if err != nil {
+		return err
+	}
+	today := businessDate(s.clock, nil)
+	now := s.clock.Now()
+	var entries []*model.LoyaltyEntry
+	var draws []repository.PointsDraw
+	for _, e := range earnings {
+		if !e.IsExpired(today) {
+			continue
+		}
+		entries = append(entries, &model.LoyaltyEntry{
+			GuestID:     guestID,
+			Type:        model.LoyaltyExpire,
+			Points:      -e.Remaining,
+			BookingID:   e.BookingID,
+			Description: fmt.Sprintf("Points earned on %s expired", e.CreatedAt.Format(request.DateLayout)),
+			CreatedAt:   now,
+		})
+		draws = append(draws, repository.PointsDraw{EntryID: e.ID, Points: e.Remaining})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return s.loyaltyRepository.Post(entries, draws, nil)
+}
+
+// drawDown takes points from the earnings in order.
+func drawDown(earnings []*model.LoyaltyEntry, points int) []repository.PointsDraw {
+	var draws []repository.PointsDraw
+	for _, e := range earnings {
+		if points == 0 {
+			break
+		}
+		take := min(e.Remaining, points)
+		points -= take
+		draws = append(draws, repository.PointsDraw{EntryID: e.ID, Points: take})
+	}
+	return draws
+}
+
+// unexpired drops the earnings that have lapsed on today. Lapsed points are
+// only written off when the guest next redeems, so reads leave them out.
+func unexpired(earnings []*model.LoyaltyEntry, today time.Time) []*model.LoyaltyEntry {
+	var open []*model.LoyaltyEntry
+	for _, e := range earnings {
+		if !e.IsExpired(today) {
+			open = append(open, e)
+		}
+	}
+	return open
+}
+
+// pointsPostError reports a redemption that lost a race for the same points.
+func pointsPostError(err error) error {
+	if errors.Is(err, repository.ErrPointsDrawn) {
+		return errors.New("points balance changed, please try again")
+	}
+	return err
+}
+
+func sumRemaining(earnings []*model.LoyaltyEntry) int {
+	var total int
+	for _, e := range earnings {
+		total += e.Remaining
+	}
+	return total
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func (s *loyaltyServices) mapToAccountResponse(account *model.LoyaltyAccount) (*response.LoyaltyAccountResponse, error) {
+	earnings, err := s.loyaltyRepository.FindOpenEarnings(account.GuestID)
+	if err != nil {
+		return nil, err
+	}
+	earnings = unexpired(earnings, businessDate(s.clock, nil))
+	nights, spend, err := s.qualifyingActivity(account.GuestID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &response.LoyaltyAccountResponse{
+		GuestID:          account.GuestID,
+		MemberNumber:     account.MemberNumber,
+		Tier:             account.Tier,
+		TierReachedAt:    account.TierReachedAt,
+		Balance:          sumRemaining(earnings),
+		QualifyingNights: nights,
+		QualifyingSpend:  roundAmount(spend),
+		EnrolledAt:       account.CreatedAt,
+	}
+	resp.BalanceValue = roundAmount(float64(resp.Balance) * model.PointValue)
+	if len(earnings) > 0 && earnings[0].ExpiresAt != nil {
+		// Earnings are ordered by expiry, so the first ones lapse next.
+		next := *earnings[0].ExpiresAt
+		resp.NextExpiry = next.Format(request.DateLayout)
+		for _, e := range earnings {
+			if e.ExpiresAt != nil && e.ExpiresAt.Equal(next) {
+				resp.PointsExpiring += e.Remaining
+			}
+		}
+	}
+	if rank := model.TierRank(account.Tier); rank+1 < len(model.LoyaltyTiers) {
+		next := model.LoyaltyTiers[rank+1]
+		resp.NextTier = &response.NextTierResponse{
+			Tier:         next.Tier,
+			NightsNeeded: max(next.MinNights-nights, 0),
+			SpendNeeded:  roundAmount(math.Max(next.MinSpend-spend, 0)),
+		}
+	}
+	return resp, nil
+}
+
+func mapToLoyaltyEntryResponse(e *model.LoyaltyEntry) *response.LoyaltyEntryResponse {
+	resp := &response.LoyaltyEntryResponse{
+		ID:            e.ID,
+		Type:          e.Type,
+		Points:        e.Points,
+		BookingID:     e.BookingID,
+		TransactionID: e.TransactionID,
+		Description:   e.Description,
+		CreatedAt:     e.CreatedAt,
+	}
+	if e.Type == model.LoyaltyEarn && e.ExpiresAt != nil {
+		resp.Remaining = &e.Remaining
+		resp.ExpiresAt = e.ExpiresAt.Format(request.DateLayout)
+	}
+	return resp
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/config"
+	"hms-backend/model"
+	"time"
+)
+
+// PaymentGateway takes card payments from a payment provider. Card numbers
+// are only handled by TokenizeCard; everything else works with the token or
+// the gateway's payment ID. A payment is authorized first, then captured or
+// voided, and captured payments may be refunded in part or in full.
+//
+// Calls that move money carry an idempotency key. The gateway answers a
+// repeated key with the result of the first call, so a call can be retried
+// after a lost response without charging or refunding twice.
+type PaymentGateway interface {
+	TokenizeCard(card CardDetails) (*CardToken, error)
+	Authorize(token string, amount float64, currency, reference, idempotencyKey string) (*GatewayPayment, error)
+	Capture(paymentID string, amount float64, idempotencyKey string) (*GatewayPayment, error)
+	Void(paymentID string, idempotencyKey string) (*GatewayPayment, error)
+	Refund(paymentID string, amount float64, idempotencyKey string) (*GatewayPayment, error)
+
+	// Payment returns the current state of a payment at the gateway.
+	Payment(paymentID string) (*GatewayPayment, error)
+
+	// ParseWebhook checks the signature of a result the gateway posted
+	// back and decodes it.
+	ParseWebhook(payload []byte, signature string) (*GatewayEvent, error)
+}
+
+// ErrInvalidWebhookSignature is returned for webhooks not signed by the gateway.
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+type CardDetails struct {
+	Number     string
+	HolderName string
+	ExpMonth   int
+	ExpYear    int
+	CVC        string
+}
+
+type CardToken struct {
+	Token    string
+	Brand    string
+	Last4    string
+	ExpMonth int
+	ExpYear  int
+}
+
+// GatewayPayment is the outcome of a gateway call. A declined card is not an
+// error: it comes back with GatewayFailed and the reason in Message.
+type GatewayPayment struct {
+	ID      string
+	Status  model.GatewayStatus
+	Amount  float64
+	Message string
+
+	// The card charged, set on authorizations.
+	CardBrand string
+	CardLast4 string
+}
+
+// GatewayEvent is an asynchronous result for a payment or refund.
+type GatewayEvent struct {
+	ID        string              `json:"id"`
+	PaymentID string              `json:"payment_id"`
+	Status    model.GatewayStatus `json:"status"`
+	Amount    float64             `json:"amount"`
+	Message   string              `json:"message,omitempty"`
+}
+
+// callGateway makes a gateway call and repeats it once if it fails, e.g.
+// when the response was lost. The call must carry an idempotency key.
+func callGateway(call func() (*GatewayPayment, error)) (*GatewayPayment, error) {
+	res, err := call()
+	if err != nil {
+		res, err = call()
+	}
+	return res, err
+}
+
+// NewPaymentGateway returns the gateway named in the configuration.
+func NewPaymentGateway(cfg config.PaymentConfig, clock Clock) (PaymentGateway, error) {
+	switch cfg.Provider {
+	case "fake":
+		return NewFakeGateway(cfg.WebhookSecret, clock), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+	}
+}
+
+// cardBrand guesses the card network from the number's prefix.
+func cardBrand(number string) string {
+	switch {
+	case len(number) > 0 && number[0] == '4':
+		return "visa"
+	case len(number) > 1 && number[0] == '5' && number[1] >= '1' && number[1] <= '5':
+		return "mastercard"
+	case len(number) > 1 && number[0] == '3' && (number[1] == '4' || number[1] == '7'):
+		return "amex"
+	default:
+		return "card"
+	}
+}
+
+// luhnValid checks the card number's check digit.
+func luhnValid(number string) bool {
+	if len(number) < 12 || len(number) > 19 {
+		return false
+	}
+	var sum int
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if d < 0 || d > 9 {
+			return false
+		}
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// cardExpired reports whether a card is past the end of its expiry month.
+func cardExpired(month, year int, now time.Time) bool {
+	return !now.Before(time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC))
+}
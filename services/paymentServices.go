@@ -0,0 +1,291 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"log"
+)
+
+type PaymentServices interface {
+	TokenizeCard(req *request.CardRequest) (*response.CardTokenResponse, error)
+	Authorize(actor *model.Principal, req *request.AuthorizePaymentRequest) (*response.TransactionResponse, error)
+	Capture(actor *model.Principal, req *request.CapturePaymentRequest) (*response.TransactionResponse, error)
+	Void(actor *model.Principal, id uint) (*response.TransactionResponse, error)
+	HandleWebhook(payload []byte, signature string) error
+}
+
+// webhookJob is recorded as the actor of changes made by gateway webhooks.
+const webhookJob = "payment-webhook"
+
+type paymentServices struct {
+	transactionRepository repository.TransactionRepository
+	bookingRepository     repository.BookingRepository
+	propertyServices      PropertyServices
+	gateway               PaymentGateway
+	auditServices         AuditServices
+}
+
+func NewPaymentServices(transaction repository.TransactionRepository, booking repository.BookingRepository, property PropertyServices, gateway PaymentGateway, audit AuditServices) PaymentServices {
+	return &paymentServices{
+		transactionRepository: transaction,
+		bookingRepository:     booking,
+		propertyServices:      property,
+		gateway:               gateway,
+		auditServices:         audit,
+	}
+}
+
+func (s *paymentServices) TokenizeCard(req *request.CardRequest) (*response.CardTokenResponse, error) {
+	token, err := s.gateway.TokenizeCard(CardDetails{
+		Number:     req.Number,
+		HolderName: req.HolderName,
+		ExpMonth:   req.ExpMonth,
+		ExpYear:    req.ExpYear,
+		CVC:        req.CVC,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response.CardTokenResponse{
+		Token:    token.Token,
+		Brand:    token.Brand,
+		Last4:    token.Last4,
+		ExpMonth: token.ExpMonth,
+		ExpYear:  token.ExpYear,
+	}, nil
+}
+
+// Authorize holds the amount on the card and records it as an unpaid card
+// payment of the booking, which is paid once captured. The payment is
+// recorded as pending before the gateway is called, so a hold is never taken
+// without a transaction; a declined card leaves it failed.
+func (s *paymentServices) Authorize(actor *model.Principal, req *request.AuthorizePaymentRequest) (*response.TransactionResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if booking.Status == model.StatusCancelled || booking.Status == model.StatusCheckedOut {
+		return nil, errors.New("cannot take payments for a cancelled or checked out booking")
+	}
+	property, err := s.propertyServices.GetPropertyModelByID(booking.PropertyID)
+	if err != nil {
+		return nil, err
+	}
+	t := model.Transaction{
+		BookingID:     booking.ID,
+		Type:          model.TransactionPayment,
+		Amount:        roundAmount(req.Amount),
+		PaymentMethod: model.PaymentMethodCard,
+		GatewayStatus: model.GatewayPending,
+	}
+	if err := s.transactionRepository.Create(&t); err != nil {
+		return nil, err
+	}
+	res, err := callGateway(func() (*GatewayPayment, error) {
+		return s.gateway.Authorize(req.CardToken, t.Amount, property.Currency, booking.BookingReference, fmt.Sprintf("authorize-%d", t.Id))
+	})
+	if err != nil {
+		res = &GatewayPayment{Status: model.GatewayFailed, Message: err.Error()}
+	}
+	t.GatewayID = res.ID
+	t.GatewayStatus = res.Status
+	t.CardBrand = res.CardBrand
+	t.CardLast4 = res.CardLast4
+	t.Paid = res.Status == model.GatewayCaptured
+	if err := s.transactionRepository.Update(&t); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditCreate, nil, &t)
+	if res.Status == model.GatewayFailed {
+		return nil, fmt.Errorf("payment failed: %s", res.Message)
+	}
+	if req.Capture && t.GatewayStatus == model.GatewayAuthorized {
+		return s.capture(actor, &t, 0)
+	}
+	return mapToTransactionResponse(&t), nil
+}
+
+func (s *paymentServices) Capture(actor *model.Principal, req *request.CapturePaymentRequest) (*response.TransactionResponse, error) {
+	t, err := s.findCardPayment(req.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.capture(actor, t, req.Amount)
+}
+
+// capture takes amount of an authorized payment, or all of it when amount
+// is zero. The rest of the authorization is released. The payment is marked
+// pending while the gateway is called, so it is only captured once; it goes
+// back to authorized if the capture fails.
+func (s *paymentServices) capture(actor *model.Principal, t *model.Transaction, amount float64) (*response.TransactionResponse, error) {
+	if t.GatewayStatus != model.GatewayAuthorized {
+		return nil, fmt.Errorf("payment is %s and cannot be captured", t.GatewayStatus)
+	}
+	if amount == 0 {
+		amount = t.Amount
+	}
+	if roundAmount(amount) > t.Amount {
+		return nil, fmt.Errorf("capture of %.2f exceeds the %.2f authorized", amount, t.Amount)
+	}
+	claimed, err := s.transactionRepository.SwapGatewayStatus(t.Id, model.GatewayAuthorized, model.GatewayPending)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, errors.New("payment has changed in the meantime, please try again")
+	}
+	res, err := callGateway(func() (*GatewayPayment, error) {
+		return s.gateway.Capture(t.GatewayID, roundAmount(amount), fmt.Sprintf("capture-%d", t.Id))
+	})
+	if err == nil && res.Status == model.GatewayFailed {
+		err = fmt.Errorf("capture failed: %s", res.Message)
+	}
+	if err != nil {
+		if _, revertErr := s.transactionRepository.SwapGatewayStatus(t.Id, model.GatewayPending, model.GatewayAuthorized); revertErr != nil {
+			log.Printf("payment: releasing payment %d after a failed capture failed: %v", t.Id, revertErr)
+		}
+		return nil, err
+	}
+	before := *t
+	t.Amount = res.Amount
+	t.GatewayStatus = res.Status
+	t.Paid = res.Status == model.GatewayCaptured
+	if err := s.transactionRepository.Update(t); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditUpdate, &before, t)
+	return mapToTransactionResponse(t), nil
+}
+
+// Void releases an authorization that has not been captured. An authorized
+// payment is claimed the way capture claims it, so the two cannot run at
+// once. A pending payment is what a capture in flight leaves behind too, so
+// it is only voided while the gateway still holds its authorization pending.
+func (s *paymentServices) Void(actor *model.Principal, id uint) (*response.TransactionResponse, error) {
+	t, err := s.findCardPayment(id)
+	if err != nil {
+		return nil, err
+	}
+	switch t.GatewayStatus {
+	case model.GatewayAuthorized:
+		claimed, err := s.transactionRepository.SwapGatewayStatus(t.Id, model.GatewayAuthorized, model.GatewayPending)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, errors.New("payment has changed in the meantime, please try again")
+		}
+	case model.GatewayPending:
+		if t.GatewayID == "" {
+			return nil, errors.New("payment is still being authorized, please try again")
+		}
+		current, err := s.gateway.Payment(t.GatewayID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status != model.GatewayPending {
+			return nil, fmt.Errorf("payment is %s at the gateway and cannot be voided", current.Status)
+		}
+	default:
+		return nil, fmt.Errorf("payment is %s and cannot be voided", t.GatewayStatus)
+	}
+	res, err := callGateway(func() (*GatewayPayment, error) {
+		return s.gateway.Void(t.GatewayID, fmt.Sprintf("void-%d", t.Id))
+	})
+	if err != nil {
+		if t.GatewayStatus == model.GatewayAuthorized {
+			if _, revertErr := s.transactionRepository.SwapGatewayStatus(t.Id, model.GatewayPending, model.GatewayAuthorized); revertErr != nil {
+				log.Printf("payment: releasing payment %d after a failed void failed: %v", t.Id, revertErr)
+			}
+		}
+		return nil, err
+	}
+	// A webhook may have settled a pending authorization meanwhile; the
+	// gateway has voided it either way.
+	voided := false
+	for _, from := range []model.GatewayStatus{model.GatewayPending, model.GatewayAuthorized} {
+		if voided, err = s.transactionRepository.SwapGatewayStatus(t.Id, from, res.Status); err != nil || voided {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !voided {
+		return nil, fmt.Errorf("payment %d was voided at the gateway but changed here in the meantime", t.Id)
+	}
+	before := *t
+	t.GatewayStatus = res.Status
+	s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditUpdate, &before, t)
+	return mapToTransactionResponse(t), nil
+}
+
+// HandleWebhook applies a result the gateway posted back for a payment or
+// refund. Gateways retry webhooks and may send them out of order, so results
+// that do not move the transaction forward are acknowledged and ignored.
+func (s *paymentServices) HandleWebhook(payload []byte, signature string) error {
+	event, err := s.gateway.ParseWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+	t, err := s.transactionRepository.FindByGatewayID(event.PaymentID)
+	if err != nil {
+		log.Printf("payment: webhook %s for unknown gateway payment %s", event.ID, event.PaymentID)
+		return nil
+	}
+	if !gatewayTransitionAllowed(t, event.Status) {
+		return nil
+	}
+	before := *t
+	t.GatewayStatus = event.Status
+	switch event.Status {
+	case model.GatewayCaptured:
+		if event.Amount > 0 {
+			t.Amount = roundAmount(event.Amount)
+		}
+		t.Paid = true
+	case model.GatewayRefunded:
+		t.Paid = true
+	}
+	if err := s.transactionRepository.Update(t); err != nil {
+		return err
+	}
+	s.auditServices.Record(model.JobPrincipal(webhookJob), model.EntityTransaction, t.Id, model.AuditUpdate, &before, t)
+	return nil
+}
+
+func (s *paymentServices) findCardPayment(id uint) (*model.Transaction, error) {
+	t, err := s.transactionRepository.GetByID(fmt.Sprint(id))
+	if err != nil {
+		return nil, errors.New("Transaction Not Found")
+	}
+	if !t.ViaGateway() || t.IsRefund() {
+		return nil, errors.New("transaction is not a card payment")
+	}
+	return &t, nil
+}
+
+// gatewayTransitionAllowed reports whether a gateway result moves the
+// transaction on from its current state.
+func gatewayTransitionAllowed(t *model.Transaction, to model.GatewayStatus) bool {
+	var allowed []model.GatewayStatus
+	switch {
+	case t.IsRefund() && t.GatewayStatus == model.GatewayPending:
+		allowed = []model.GatewayStatus{model.GatewayRefunded, model.GatewayFailed}
+	case t.IsRefund():
+	case t.GatewayStatus == model.GatewayPending:
+		allowed = []model.GatewayStatus{model.GatewayAuthorized, model.GatewayCaptured, model.GatewayVoided, model.GatewayFailed}
+	case t.GatewayStatus == model.GatewayAuthorized:
+		allowed = []model.GatewayStatus{model.GatewayCaptured, model.GatewayVoided}
+	}
+	for _, status := range allowed {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"strconv"
+	"testing"
+)
+
+// memoryTransactions keeps transactions in a map. Methods the payment flow
+// does not use are left to the embedded interface and panic if called.
+type memoryTransactions struct {
+	repository.TransactionRepository
+	rows map[uint]*model.Transaction
+	next uint
+}
+
+func newMemoryTransactions() *memoryTransactions {
+	return &memoryTransactions{rows: make(map[uint]*model.Transaction)}
+}
+
+func (r *memoryTransactions) Create(t *model.Transaction) error {
+	r.next++
+	t.Id = r.next
+	row := *t
+	r.rows[t.Id] = &row
+	return nil
+}
+
+func (r *memoryTransactions) GetByID(i string) (model.Transaction, error) {
+	id, _ := strconv.Atoi(i)
+	row, ok := r.rows[uint(id)]
+	if !ok {
+		return model.Transaction{}, errors.New("record not found")
+	}
+	return *row, nil
+}
+
+func (r *memoryTransactions) Update(t *model.Transaction) error {
+	row := *t
+	r.rows[t.Id] = &row
+	return nil
+}
+
+func (r *memoryTransactions) FindByGatewayID(gatewayID string) (*model.Transaction, error) {
+	for _, row := range r.rows {
+		if row.GatewayID == gatewayID {
+			t := *row
+			return &t, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memoryTransactions) FindByBookingID(bookingID string) ([]*model.Transaction, error) {
+	var ts []*model.Transaction
+	for id := uint(1); id <= r.next; id++ {
+		if row, ok := r.rows[id]; ok && row.BookingID == bookingID {
+			t := *row
+			ts = append(ts, &t)
+		}
+	}
+	return ts, nil
+}
+
+func (r *memoryTransactions) CreateRefund(refund *model.Transaction, check func(payment *model.Transaction, refunded float64) error) error {
+	payment := *r.rows[*refund.RefundOfID]
+	var refunded float64
+	for _, row := range r.rows {
+		if row.IsRefund() && *row.RefundOfID == payment.Id && row.GatewayStatus != model.GatewayFailed {
+			refunded += row.Amount
+		}
+	}
+	if err := check(&payment, refunded); err != nil {
+		return err
+	}
+	return r.Create(refund)
+}
+
+func (r *memoryTransactions) SwapGatewayStatus(id uint, from, to model.GatewayStatus) (bool, error) {
+	row := r.rows[id]
+	if row.GatewayStatus != from {
+		return false, nil
+	}
+	row.GatewayStatus = to
+	return true, nil
+}
+
+const testBookingID = "01J0000000000000000000TEST"
+
+type oneBooking struct {
+	repository.BookingRepository
+	booking *model.Booking
+}
+
+func (r *oneBooking) FindByReferenceID(ref string) (*model.Booking, error) {
+	if ref != r.booking.BookingReference {
+		return nil, errors.New("record not found")
+	}
+	return r.booking, nil
+}
+
+type oneProperty struct {
+	PropertyServices
+}
+
+func (oneProperty) GetPropertyModelByID(id uint) (*model.Property, error) {
+	return &model.Property{ID: id, Code: "HQ", Currency: "EUR"}, nil
+}
+
+type noAudit struct {
+	AuditServices
+}
+
+func (noAudit) Record(*model.Principal, string, any, model.AuditAction, any, any) {}
+
+type paymentTest struct {
+	gateway      *FakeGateway
+	transactions *memoryTransactions
+	payments     PaymentServices
+	refunds      TransactionServices
+}
+
+func newPaymentTest() *paymentTest {
+	gateway := newTestGateway()
+	transactions := newMemoryTransactions()
+	bookings := &oneBooking{booking: &model.Booking{ID: testBookingID, BookingReference: "BK1", Status: model.StatusConfirmed, PropertyID: 1}}
+	return &paymentTest{
+		gateway:      gateway,
+		transactions: transactions,
+		payments:     NewPaymentServices(transactions, bookings, oneProperty{}, gateway, noAudit{}),
+		refunds:      NewTransactionServices(transactions, bookings, gateway, noAudit{}),
+	}
+}
+
+var testActor = model.JobPrincipal("test")
+
+func (p *paymentTest) authorize(t *testing.T, card string, capture bool) (*model.Transaction, error) {
+	t.Helper()
+	token := tokenize(t, p.gateway, card)
+	res, err := p.payments.Authorize(testActor, &request.AuthorizePaymentRequest{BookingReference: "BK1", Amount: 100, CardToken: token, Capture: capture})
+	if err != nil {
+		return nil, err
+	}
+	row, _ := p.transactions.GetByID(fmt.Sprint(res.ID))
+	return &row, nil
+}
+
+func TestAuthorizeAndCapture(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardApproved, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.GatewayStatus != model.GatewayAuthorized || payment.Paid || payment.GatewayID == "" {
+		t.Fatalf("authorized payment = %+v", payment)
+	}
+	if _, err := p.payments.Capture(testActor, &request.CapturePaymentRequest{TransactionID: payment.Id, Amount: 70}); err != nil {
+		t.Fatal(err)
+	}
+	row, _ := p.transactions.GetByID(fmt.Sprint(payment.Id))
+	if row.GatewayStatus != model.GatewayCaptured || !row.Paid || row.Amount != 70 {
+		t.Errorf("captured payment = %s paid %v %.2f, want captured, paid, 70.00", row.GatewayStatus, row.Paid, row.Amount)
+	}
+	if _, err := p.payments.Capture(testActor, &request.CapturePaymentRequest{TransactionID: payment.Id}); err == nil {
+		t.Error("a captured payment was captured again")
+	}
+}
+
+func TestFailedCaptureReleasesThePayment(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardApproved, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Voided at the gateway behind our back, so the capture is refused.
+	if _, err := p.gateway.Void(payment.GatewayID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.payments.Capture(testActor, &request.CapturePaymentRequest{TransactionID: payment.Id}); err == nil {
+		t.Fatal("capture of a voided payment succeeded")
+	}
+	row, _ := p.transactions.GetByID(fmt.Sprint(payment.Id))
+	if row.GatewayStatus != model.GatewayAuthorized {
+		t.Errorf("payment left %s after a failed capture, want authorized", row.GatewayStatus)
+	}
+}
+
+func TestDeclinedCardIsRecordedAsFailed(t *testing.T) {
+	p := newPaymentTest()
+	if _, err := p.authorize(t, testCardDeclined, false); err == nil {
+		t.Fatal("a declined card was authorized")
+	}
+	row, err := p.transactions.GetByID("1")
+	if err != nil {
+		t.Fatal("no transaction recorded for the declined card")
+	}
+	if row.GatewayStatus != model.GatewayFailed || row.Paid {
+		t.Errorf("declined payment = %s paid %v, want failed and unpaid", row.GatewayStatus, row.Paid)
+	}
+}
+
+func TestVoid(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardApproved, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.payments.Void(testActor, payment.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GatewayStatus != model.GatewayVoided {
+		t.Errorf("Void = %s, want voided", res.GatewayStatus)
+	}
+	if _, err := p.payments.Void(testActor, payment.Id); err == nil {
+		t.Error("a voided payment was voided again")
+	}
+}
+
+func TestVoidPendingAuthorization(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardPending, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.payments.Void(testActor, payment.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GatewayStatus != model.GatewayVoided {
+		t.Errorf("Void = %s, want voided", res.GatewayStatus)
+	}
+}
+
+func TestVoidRefusesPaymentBeingCaptured(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardApproved, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A capture has claimed the payment and is waiting for the gateway.
+	if ok, _ := p.transactions.SwapGatewayStatus(payment.Id, model.GatewayAuthorized, model.GatewayPending); !ok {
+		t.Fatal("could not claim the payment")
+	}
+	if _, err := p.payments.Void(testActor, payment.Id); err == nil {
+		t.Fatal("a payment being captured was voided")
+	}
+	if at, _ := p.gateway.Payment(payment.GatewayID); at.Status != model.GatewayAuthorized {
+		t.Errorf("gateway payment is %s, want it still authorized", at.Status)
+	}
+	row, _ := p.transactions.GetByID(fmt.Sprint(payment.Id))
+	if row.GatewayStatus != model.GatewayPending {
+		t.Errorf("payment left %s, want it still claimed by the capture", row.GatewayStatus)
+	}
+}
+
+func TestCardRefund(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardApproved, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := p.refunds.Refund(testActor, &request.RefundRequest{TransactionID: payment.Id, Amount: 40, Reason: "Late check-out waived"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refund, _ := p.transactions.GetByID(fmt.Sprint(res.ID))
+	if refund.GatewayStatus != model.GatewayRefunded || !refund.Paid || refund.GatewayID == "" {
+		t.Errorf("refund = %+v, want refunded and paid out", refund)
+	}
+	if _, err := p.refunds.Refund(testActor, &request.RefundRequest{TransactionID: payment.Id, Amount: 70, Reason: "Too much"}); err == nil {
+		t.Error("refunds exceeded the payment")
+	}
+	if _, err := p.refunds.Refund(testActor, &request.RefundRequest{TransactionID: payment.Id, Reason: "The rest"}); err != nil {
+		t.Errorf("refunding the rest: %v", err)
+	}
+}
+
+func TestPendingPaymentSettledByWebhook(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardPending, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.GatewayStatus != model.GatewayPending {
+		t.Fatalf("payment = %s, want pending", payment.GatewayStatus)
+	}
+	payload, signature, err := p.gateway.Settle(payment.GatewayID, model.GatewayCaptured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.payments.HandleWebhook(payload, signature); err != nil {
+		t.Fatal(err)
+	}
+	row, _ := p.transactions.GetByID(fmt.Sprint(payment.Id))
+	if row.GatewayStatus != model.GatewayCaptured || !row.Paid {
+		t.Errorf("settled payment = %s paid %v, want captured and paid", row.GatewayStatus, row.Paid)
+	}
+}
+
+func TestWebhookRejectsBadSignature(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardPending, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, err := p.gateway.Settle(payment.GatewayID, model.GatewayCaptured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.payments.HandleWebhook(payload, "deadbeef"); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("err = %v, want ErrInvalidWebhookSignature", err)
+	}
+	row, _ := p.transactions.GetByID(fmt.Sprint(payment.Id))
+	if row.GatewayStatus != model.GatewayPending {
+		t.Errorf("unsigned webhook moved the payment to %s", row.GatewayStatus)
+	}
+}
+
+func TestWebhooksOutOfOrder(t *testing.T) {
+	p := newPaymentTest()
+	payment, err := p.authorize(t, testCardPending, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The gateway authorized the payment, then it was captured; the
+	// authorization webhook arrives last and again as a retry.
+	authorized, authorizedSig, err := p.gateway.Settle(payment.GatewayID, model.GatewayAuthorized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	captured := []byte(fmt.Sprintf(`{"id":"evt_late","payment_id":%q,"status":"captured","amount":100}`, payment.GatewayID))
+	if err := p.payments.HandleWebhook(captured, p.gateway.SignWebhook(captured)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := p.payments.HandleWebhook(authorized, authorizedSig); err != nil {
+			t.Fatalf("stale webhook not acknowledged: %v", err)
+		}
+	}
+	row, _ := p.transactions.GetByID(fmt.Sprint(payment.Id))
+	if row.GatewayStatus != model.GatewayCaptured || !row.Paid {
+		t.Errorf("payment = %s paid %v after a stale webhook, want captured and paid", row.GatewayStatus, row.Paid)
+	}
+
+	// Results for unknown payments are acknowledged so the gateway stops retrying.
+	unknown := []byte(`{"id":"evt_x","payment_id":"fake_pay_999999","status":"captured"}`)
+	if err := p.payments.HandleWebhook(unknown, p.gateway.SignWebhook(unknown)); err != nil {
+		t.Errorf("webhook for an unknown payment: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"strings"
+	"time"
+)
+
+type PropertyServices interface {
+	Create(input *request.PropertyRequest) (*response.PropertyResponse, error)
+	GetAll() ([]*response.PropertyResponse, error)
+	GetByID(id uint) (*response.PropertyResponse, error)
+	GetPropertyModelByID(id uint) (*model.Property, error)
+	Update(input *request.UpdatePropertyRequest) (*response.PropertyResponse, error)
+	GetBusinessDate(id uint) (*response.BusinessDateResponse, error)
+}
+
+type propertyServices struct {
+	propertyRepository repository.PropertyRepository
+	clock              Clock
+}
+
+func NewPropertyServices(repo repository.PropertyRepository, clock Clock) PropertyServices {
+	return &propertyServices{propertyRepository: repo, clock: clock}
+}
+
+func (s *propertyServices) Create(input *request.PropertyRequest) (*response.PropertyResponse, error) {
+	if err := validatePropertySettings(input); err != nil {
+		return nil, err
+	}
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if _, err := s.propertyRepository.FindByCode(code); err == nil {
+		return nil, errors.New("property with this code already exists")
+	}
+	property := model.Property{Code: code}
+	applyPropertySettings(&property, input)
+	created, err := s.propertyRepository.Create(&property)
+	if err != nil {
+		return nil, err
+	}
+	return mapToPropertyResponse(created), nil
+}
+
+func (s *propertyServices) GetAll() ([]*response.PropertyResponse, error) {
+	properties, err := s.propertyRepository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.PropertyResponse, len(properties))
+	for i, property := range properties {
+		resp[i] = mapToPropertyResponse(property)
+	}
+	return resp, nil
+}
+
+func (s *propertyServices) GetByID(id uint) (*response.PropertyResponse, error) {
+	property, err := s.propertyRepository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return mapToPropertyResponse(property), nil
+}
+
+func (s *propertyServices) GetPropertyModelByID(id uint) (*model.Property, error) {
+	return s.propertyRepository.FindByID(id)
+}
+
+func (s *propertyServices) Update(input *request.UpdatePropertyRequest) (*response.PropertyResponse, error) {
+	property, err := s.propertyRepository.FindByID(input.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePropertySettings(&input.PropertyRequest); err != nil {
+		return nil, err
+	}
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if existing, err := s.propertyRepository.FindByCode(code); err == nil && existing.ID != property.ID {
+		return nil, errors.New("property with this code already exists")
+	}
+	property.Code = code
+	applyPropertySettings(property, &input.PropertyRequest)
+	if err := s.propertyRepository.Update(property); err != nil {
+		return nil, err
+	}
+	return mapToPropertyResponse(property), nil
+}
+
+func (s *propertyServices) GetBusinessDate(id uint) (*response.BusinessDateResponse, error) {
+	property, err := s.propertyRepository.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &response.BusinessDateResponse{
+		PropertyID:   property.ID,
+		Timezone:     property.Timezone,
+		BusinessDate: businessDate(s.clock, property).Format(request.DateLayout),
+		LocalTime:    s.clock.Now().In(propertyLocation(property)).Format(time.RFC3339),
+	}, nil
+}
+
+func validatePropertySettings(input *request.PropertyRequest) error {
+	if _, err := time.LoadLocation(input.Timezone); err != nil {
+		return errors.New("invalid timezone, must be an IANA name such as 'Asia/Jakarta'")
+	}
+	if _, err := time.Parse("15:04", input.CheckInTime); err != nil {
+		return errors.New("invalid check_in_time, must use HH:MM format")
+	}
+	if _, err := time.Parse("15:04", input.CheckOutTime); err != nil {
+		return errors.New("invalid check_out_time, must use HH:MM format")
+	}
+	return nil
+}
+
+func applyPropertySettings(property *model.Property, input *request.PropertyRequest) {
+	property.Name = input.Name
+	property.Address = input.Address
+	property.Timezone = input.Timezone
+	property.Currency = strings.ToUpper(input.Currency)
+	property.CheckInTime = input.CheckInTime
+	property.CheckOutTime = input.CheckOutTime
+	property.TaxName = input.TaxName
+	if property.TaxName == "" {
+		property.TaxName = "Tax"
+	}
+	property.TaxRate = input.TaxRate
+	property.TaxNumber = input.TaxNumber
+}
+
+func mapToPropertyResponse(p *model.Property) *response.PropertyResponse {
+	return &response.PropertyResponse{
+		ID:           p.ID,
+		Code:         p.Code,
+		Name:         p.Name,
+		Address:      p.Address,
+		Timezone:     p.Timezone,
+		Currency:     p.Currency,
+		CheckInTime:  p.CheckInTime,
+		CheckOutTime: p.CheckOutTime,
+		TaxName:      p.TaxName,
+		TaxRate:      p.TaxRate,
+		TaxNumber:    p.TaxNumber,
+	}
+}
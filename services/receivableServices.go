@@ -0,0 +1,378 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"math"
+	"time"
+)
+
+type ReceivableServices interface {
+	InvoiceTransaction(actor *model.Principal, req *request.InvoiceTransactionRequest) (*response.ReceivableEntryResponse, error)
+	PostInvoice(actor *model.Principal, t *model.Transaction) (*model.ReceivableEntry, error)
+	RecordPayment(actor *model.Principal, req *request.ReceivablePaymentRequest) (*response.ReceivableEntryResponse, error)
+	IssueCreditNote(actor *model.Principal, req *request.CreditNoteRequest) (*response.ReceivableEntryResponse, error)
+	GetInvoices(accountID uint, openOnly bool) ([]*response.ReceivableEntryResponse, error)
+	GetStatement(accountID uint, params *request.StatementParams) (*response.StatementResponse, error)
+	GetAging() (*response.AgingResponse, error)
+}
+
+type receivableServices struct {
+	receivableRepository  repository.ReceivableRepository
+	corporateRepository   repository.CorporateRepository
+	transactionRepository repository.TransactionRepository
+	auditServices         AuditServices
+	clock                 Clock
+}
+
+func NewReceivableServices(repo repository.ReceivableRepository, corporate repository.CorporateRepository, transaction repository.TransactionRepository, audit AuditServices, clock Clock) ReceivableServices {
+	return &receivableServices{
+		receivableRepository:  repo,
+		corporateRepository:   corporate,
+		transactionRepository: transaction,
+		auditServices:         audit,
+		clock:                 clock,
+	}
+}
+
+// InvoiceTransaction moves an unpaid booking transaction to the city ledger
+// of a billing account, e.g. a stay a travel agent pays for.
+func (s *receivableServices) InvoiceTransaction(actor *model.Principal, req *request.InvoiceTransactionRequest) (*response.ReceivableEntryResponse, error) {
+	t, err := s.transactionRepository.GetByID(fmt.Sprint(req.TransactionID))
+	if err != nil {
+		return nil, errors.New("Transaction Not Found")
+	}
+	if t.Paid {
+		return nil, errors.New("transaction is already paid")
+	}
+	if t.ViaGateway() {
+		return nil, errors.New("card payments are settled through the payment gateway")
+	}
+	account, err := s.corporateRepository.FindByID(req.AccountID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	if t.CorporateAccountID != nil && *t.CorporateAccountID != account.ID {
+		return nil, fmt.Errorf("transaction is already billed to account %d", *t.CorporateAccountID)
+	}
+	if t.CorporateAccountID == nil {
+		before := t
+		t.CorporateAccountID = &account.ID
+		if err := s.transactionRepository.Update(&t); err != nil {
+			return nil, err
+		}
+		s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditUpdate, &before, &t)
+	}
+	invoice, err := s.PostInvoice(actor, &t)
+	if err != nil {
+		return nil, err
+	}
+	return mapToReceivableResponse(invoice), nil
+}
+
+// PostInvoice raises an invoice on the transaction's billing account.
+func (s *receivableServices) PostInvoice(actor *model.Principal, t *model.Transaction) (*model.ReceivableEntry, error) {
+	if t.CorporateAccountID == nil {
+		return nil, errors.New("transaction is not billed to an account")
+	}
+	if _, err := s.receivableRepository.FindByTransactionID(t.Id); err == nil {
+		return nil, errors.New("transaction is already invoiced")
+	}
+	invoice := model.ReceivableEntry{
+		AccountID:     *t.CorporateAccountID,
+		Type:          model.ReceivableInvoice,
+		Amount:        t.Amount,
+		PostedOn:      businessDate(s.clock, nil),
+		TransactionID: &t.Id,
+		BookingID:     &t.BookingID,
+		CreatedBy:     actor.Name(),
+		CreatedAt:     s.clock.Now(),
+	}
+	if t.Booking != nil {
+		invoice.Reference = t.Booking.BookingReference
+	}
+	if err := s.receivableRepository.Post(&invoice); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityReceivable, invoice.ID, model.AuditCreate, nil, &invoice)
+	return &invoice, nil
+}
+
+func (s *receivableServices) RecordPayment(actor *model.Principal, req *request.ReceivablePaymentRequest) (*response.ReceivableEntryResponse, error) {
+	account, err := s.corporateRepository.FindByID(req.AccountID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	postedOn := businessDate(s.clock, nil)
+	if req.ReceivedOn != "" {
+		if postedOn, err = request.ParseDate(req.ReceivedOn); err != nil {
+			return nil, errors.New("invalid received_on, must be YYYY-MM-DD")
+		}
+	}
+	invoices, err := s.receivableRepository.FindInvoices(account.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	amount := roundAmount(req.Amount)
+	var allocations []model.ReceivableAllocation
+	if len(req.Allocations) > 0 {
+		open := make(map[uint]*model.ReceivableEntry, len(invoices))
+		for _, invoice := range invoices {
+			open[invoice.ID] = invoice
+		}
+		var total float64
+		for _, a := range req.Allocations {
+			invoice, ok := open[a.InvoiceID]
+			if !ok {
+				return nil, fmt.Errorf("invoice %d is not an open invoice of this account", a.InvoiceID)
+			}
+			if roundAmount(a.Amount) > roundAmount(invoice.Open()) {
+				return nil, fmt.Errorf("allocation of %.2f exceeds the %.2f open on invoice %d", a.Amount, invoice.Open(), invoice.ID)
+			}
+			invoice.Settled += a.Amount
+			total += a.Amount
+			allocations = append(allocations, model.ReceivableAllocation{InvoiceID: invoice.ID, Amount: a.Amount, CreatedAt: s.clock.Now()})
+		}
+		if roundAmount(total) != amount {
+			return nil, fmt.Errorf("allocations add up to %.2f, not the payment amount of %.2f", total, amount)
+		}
+	} else {
+		var open float64
+		for _, invoice := range invoices {
+			open += invoice.Open()
+		}
+		if amount > roundAmount(open) {
+			return nil, fmt.Errorf("payment of %.2f exceeds the %.2f the account owes", amount, open)
+		}
+		left := amount
+		for _, invoice := range invoices {
+			if left <= 0 {
+				break
+			}
+			take := math.Min(left, roundAmount(invoice.Open()))
+			left = roundAmount(left - take)
+			allocations = append(allocations, model.ReceivableAllocation{InvoiceID: invoice.ID, Amount: take, CreatedAt: s.clock.Now()})
+		}
+	}
+	payment := model.ReceivableEntry{
+		AccountID:     account.ID,
+		Type:          model.ReceivablePayment,
+		Amount:        amount,
+		PostedOn:      postedOn,
+		PaymentMethod: req.PaymentMethod,
+		Reference:     req.Reference,
+		Allocations:   allocations,
+		CreatedBy:     actor.Name(),
+		CreatedAt:     s.clock.Now(),
+	}
+	if err := s.receivableRepository.Post(&payment); err != nil {
+		return nil, receivablePostError(err)
+	}
+	s.auditServices.Record(actor, model.EntityReceivable, payment.ID, model.AuditCreate, nil, &payment)
+	return mapToReceivableResponse(&payment), nil
+}
+
+// IssueCreditNote reduces what is owed on an invoice, e.g. after a billing
+// dispute. It cannot credit more than is still open.
+func (s *receivableServices) IssueCreditNote(actor *model.Principal, req *request.CreditNoteRequest) (*response.ReceivableEntryResponse, error) {
+	invoice, err := s.receivableRepository.FindByID(req.InvoiceID)
+	if err != nil || invoice.Type != model.ReceivableInvoice {
+		return nil, errors.New("Invoice Not Found")
+	}
+	amount := roundAmount(req.Amount)
+	if amount > roundAmount(invoice.Open()) {
+		return nil, fmt.Errorf("credit of %.2f exceeds the %.2f open on invoice %d", amount, invoice.Open(), invoice.ID)
+	}
+	note := model.ReceivableEntry{
+		AccountID: invoice.AccountID,
+		Type:      model.ReceivableCreditNote,
+		Amount:    amount,
+		PostedOn:  businessDate(s.clock, nil),
+		BookingID: invoice.BookingID,
+		Reference: invoice.Reference,
+		Notes:     req.Reason,
+		Allocations: []model.ReceivableAllocation{
+			{InvoiceID: invoice.ID, Amount: amount, CreatedAt: s.clock.Now()},
+		},
+		CreatedBy: actor.Name(),
+		CreatedAt: s.clock.Now(),
+	}
+	if err := s.receivableRepository.Post(&note); err != nil {
+		return nil, receivablePostError(err)
+	}
+	s.auditServices.Record(actor, model.EntityReceivable, note.ID, model.AuditCreate, nil, &note)
+	return mapToReceivableResponse(&note), nil
+}
+
+// receivablePostError reports an allocation that lost a race with another
+// payment or credit note on the same invoice.
+func receivablePostError(err error) error {
+	if errors.Is(err, repository.ErrOverSettled) {
+		return errors.New("an invoice was settled in the meantime, please try again")
+	}
+	return err
+}
+
+func (s *receivableServices) GetInvoices(accountID uint, openOnly bool) ([]*response.ReceivableEntryResponse, error) {
+	invoices, err := s.receivableRepository.FindInvoices(accountID, openOnly)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.ReceivableEntryResponse, len(invoices))
+	for i, invoice := range invoices {
+		resp[i] = mapToReceivableResponse(invoice)
+	}
+	return resp, nil
+}
+
+func (s *receivableServices) GetStatement(accountID uint, params *request.StatementParams) (*response.StatementResponse, error) {
+	account, err := s.corporateRepository.FindByID(accountID)
+	if err != nil {
+		return nil, errors.New("Corporate Account Not Found")
+	}
+	to := businessDate(s.clock, nil)
+	from := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if params.From != "" {
+		if from, err = request.ParseDate(params.From); err != nil {
+			return nil, errors.New("invalid from date, must be YYYY-MM-DD")
+		}
+	}
+	if params.To != "" {
+		if to, err = request.ParseDate(params.To); err != nil {
+			return nil, errors.New("invalid to date, must be YYYY-MM-DD")
+		}
+	}
+	if to.Before(from) {
+		return nil, errors.New("to date must not be before from date")
+	}
+	opening, err := s.receivableRepository.BalanceBefore(account.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.receivableRepository.FindEntries(account.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	statement := &response.StatementResponse{
+		Account:        *mapToCorporateResponse(account),
+		From:           from.Format(request.DateLayout),
+		To:             to.Format(request.DateLayout),
+		OpeningBalance: roundAmount(opening),
+		Lines:          make([]response.StatementLine, len(entries)),
+	}
+	balance := opening
+	for i, e := range entries {
+		line := response.StatementLine{
+			Date:        e.PostedOn.Format(request.DateLayout),
+			EntryID:     e.ID,
+			Type:        e.Type,
+			Description: describeReceivable(e),
+		}
+		if e.Type == model.ReceivableInvoice {
+			line.Debit = e.Amount
+			balance += e.Amount
+		} else {
+			line.Credit = e.Amount
+			balance -= e.Amount
+		}
+		line.Balance = roundAmount(balance)
+		statement.Lines[i] = line
+	}
+	statement.ClosingBalance = roundAmount(balance)
+	return statement, nil
+}
+
+// GetAging buckets every open invoice by how long ago it was posted.
+func (s *receivableServices) GetAging() (*response.AgingResponse, error) {
+	invoices, err := s.receivableRepository.FindAllOpenInvoices()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.corporateRepository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*model.CorporateAccount, len(accounts))
+	for _, a := range accounts {
+		byID[a.ID] = a
+	}
+	today := businessDate(s.clock, nil)
+	aging := &response.AgingResponse{AsOf: today.Format(request.DateLayout)}
+	index := make(map[uint]int)
+	for _, invoice := range invoices {
+		i, ok := index[invoice.AccountID]
+		if !ok {
+			row := response.AgingAccount{AccountID: invoice.AccountID}
+			if a, ok := byID[invoice.AccountID]; ok {
+				row.Code, row.Name, row.Type = a.Code, a.Name, a.Type
+			}
+			aging.Accounts = append(aging.Accounts, row)
+			i = len(aging.Accounts) - 1
+			index[invoice.AccountID] = i
+		}
+		days := int(today.Sub(invoice.PostedOn).Hours() / 24)
+		addToBucket(&aging.Accounts[i].AgingBuckets, model.AgingBucket(days), invoice.Open())
+		addToBucket(&aging.Totals, model.AgingBucket(days), invoice.Open())
+	}
+	return aging, nil
+}
+
+func addToBucket(b *response.AgingBuckets, bucket string, amount float64) {
+	switch bucket {
+	case model.AgingCurrent:
+		b.Current = roundAmount(b.Current + amount)
+	case model.Aging30:
+		b.Days30 = roundAmount(b.Days30 + amount)
+	case model.Aging60:
+		b.Days60 = roundAmount(b.Days60 + amount)
+	default:
+		b.Days90Plus = roundAmount(b.Days90Plus + amount)
+	}
+	b.Total = roundAmount(b.Total + amount)
+}
+
+func describeReceivable(e *model.ReceivableEntry) string {
+	switch e.Type {
+	case model.ReceivableInvoice:
+		if e.Reference != "" {
+			return fmt.Sprintf("Invoice %d for booking %s", e.ID, e.Reference)
+		}
+		return fmt.Sprintf("Invoice %d", e.ID)
+	case model.ReceivablePayment:
+		if e.Reference != "" {
+			return fmt.Sprintf("Payment by %s, ref %s", e.PaymentMethod, e.Reference)
+		}
+		return fmt.Sprintf("Payment by %s", e.PaymentMethod)
+	default:
+		return fmt.Sprintf("Credit note: %s", e.Notes)
+	}
+}
+
+func mapToReceivableResponse(e *model.ReceivableEntry) *response.ReceivableEntryResponse {
+	resp := &response.ReceivableEntryResponse{
+		ID:            e.ID,
+		AccountID:     e.AccountID,
+		Type:          e.Type,
+		Amount:        e.Amount,
+		PostedOn:      e.PostedOn.Format(request.DateLayout),
+		TransactionID: e.TransactionID,
+		BookingID:     e.BookingID,
+		PaymentMethod: e.PaymentMethod,
+		Reference:     e.Reference,
+		Notes:         e.Notes,
+		CreatedBy:     e.CreatedBy,
+		CreatedAt:     e.CreatedAt,
+	}
+	if e.Type == model.ReceivableInvoice {
+		open := roundAmount(e.Open())
+		resp.Open = &open
+	}
+	for _, a := range e.Allocations {
+		resp.Allocations = append(resp.Allocations, response.ReceivableAllocationResponse{InvoiceID: a.InvoiceID, Amount: a.Amount})
+	}
+	return resp
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"sort"
+	"testing"
+	"time"
+)
+
+// memoryReceivables keeps the city ledger in a map. Post settles invoices
+// and marks their transactions paid the way the SQL repository does.
+type memoryReceivables struct {
+	repository.ReceivableRepository
+	rows         map[uint]*model.ReceivableEntry
+	next         uint
+	transactions *memoryTransactions
+}
+
+func (r *memoryReceivables) FindByID(id uint) (*model.ReceivableEntry, error) {
+	row, ok := r.rows[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	e := *row
+	return &e, nil
+}
+
+func (r *memoryReceivables) FindByTransactionID(transactionID uint) (*model.ReceivableEntry, error) {
+	for _, row := range r.rows {
+		if row.TransactionID != nil && *row.TransactionID == transactionID {
+			e := *row
+			return &e, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memoryReceivables) FindInvoices(accountID uint, openOnly bool) ([]*model.ReceivableEntry, error) {
+	var invoices []*model.ReceivableEntry
+	for _, e := range r.openInvoices(!openOnly) {
+		if e.AccountID == accountID {
+			invoices = append(invoices, e)
+		}
+	}
+	return invoices, nil
+}
+
+func (r *memoryReceivables) FindAllOpenInvoices() ([]*model.ReceivableEntry, error) {
+	invoices := r.openInvoices(false)
+	sort.SliceStable(invoices, func(i, j int) bool { return invoices[i].AccountID < invoices[j].AccountID })
+	return invoices, nil
+}
+
+// openInvoices returns copies of the invoices, oldest first.
+func (r *memoryReceivables) openInvoices(settledToo bool) []*model.ReceivableEntry {
+	var invoices []*model.ReceivableEntry
+	for id := uint(1); id <= r.next; id++ {
+		row, ok := r.rows[id]
+		if !ok || row.Type != model.ReceivableInvoice || (!settledToo && row.IsSettled()) {
+			continue
+		}
+		e := *row
+		invoices = append(invoices, &e)
+	}
+	sort.SliceStable(invoices, func(i, j int) bool { return invoices[i].PostedOn.Before(invoices[j].PostedOn) })
+	return invoices
+}
+
+func (r *memoryReceivables) Post(entry *model.ReceivableEntry) error {
+	for _, a := range entry.Allocations {
+		invoice, ok := r.rows[a.InvoiceID]
+		if !ok || invoice.Settled+a.Amount > invoice.Amount+0.005 {
+			return repository.ErrOverSettled
+		}
+	}
+	r.next++
+	entry.ID = r.next
+	row := *entry
+	r.rows[entry.ID] = &row
+	for _, a := range entry.Allocations {
+		invoice := r.rows[a.InvoiceID]
+		invoice.Settled += a.Amount
+		if entry.Type == model.ReceivablePayment && r.receivedOn(invoice.ID) >= invoice.Amount-0.005 {
+			r.transactions.rows[*invoice.TransactionID].Paid = true
+		}
+	}
+	return nil
+}
+
+// receivedOn sums the payments, not credit notes, allocated to an invoice.
+func (r *memoryReceivables) receivedOn(invoiceID uint) float64 {
+	var received float64
+	for _, e := range r.rows {
+		if e.Type != model.ReceivablePayment {
+			continue
+		}
+		for _, a := range e.Allocations {
+			if a.InvoiceID == invoiceID {
+				received += a.Amount
+			}
+		}
+	}
+	return received
+}
+
+type oneCorporate struct {
+	repository.CorporateRepository
+	account *model.CorporateAccount
+}
+
+func (r *oneCorporate) FindByID(id uint) (*model.CorporateAccount, error) {
+	if id != r.account.ID {
+		return nil, errors.New("record not found")
+	}
+	return r.account, nil
+}
+
+func (r *oneCorporate) FindAll() ([]*model.CorporateAccount, error) {
+	return []*model.CorporateAccount{r.account}, nil
+}
+
+// settableClock is a clock the test moves by hand.
+type settableClock struct {
+	now time.Time
+}
+
+func (c *settableClock) Now() time.Time {
+	return c.now
+}
+
+type receivableTest struct {
+	clock        *settableClock
+	transactions *memoryTransactions
+	ledger       *memoryReceivables
+	receivables  ReceivableServices
+	refunds      TransactionServices
+}
+
+func newReceivableTest() *receivableTest {
+	clock := &settableClock{now: time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)}
+	transactions := newMemoryTransactions()
+	ledger := &memoryReceivables{rows: make(map[uint]*model.ReceivableEntry), transactions: transactions}
+	corporate := &oneCorporate{account: &model.CorporateAccount{ID: 1, Code: "ACME", Name: "Acme Ltd", CreditLimit: 10000}}
+	bookings := &oneBooking{booking: &model.Booking{ID: testBookingID, BookingReference: "BK1", Status: model.StatusCheckedOut, PropertyID: 1}}
+	return &receivableTest{
+		clock:        clock,
+		transactions: transactions,
+		ledger:       ledger,
+		receivables:  NewReceivableServices(ledger, corporate, transactions, noAudit{}, clock),
+		refunds:      NewTransactionServices(transactions, bookings, newTestGateway(), noAudit{}),
+	}
+}
+
+// invoice bills an unpaid transaction of amount to the account on date.
+func (r *receivableTest) invoice(t *testing.T, amount float64, date string) (transactionID, invoiceID uint) {
+	t.Helper()
+	posted, _ := request.ParseDate(date)
+	r.clock.now = posted.Add(12 * time.Hour)
+	tr := model.Transaction{BookingID: testBookingID, Type: model.TransactionPayment, Amount: amount, PaymentMethod: "bank_transfer"}
+	r.transactions.Create(&tr)
+	res, err := r.receivables.InvoiceTransaction(testActor, &request.InvoiceTransactionRequest{TransactionID: tr.Id, AccountID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr.Id, res.ID
+}
+
+func (r *receivableTest) open(invoiceID uint) float64 {
+	e, _ := r.ledger.FindByID(invoiceID)
+	return roundAmount(e.Open())
+}
+
+func (r *receivableTest) paid(transactionID uint) bool {
+	t, _ := r.transactions.GetByID(fmt.Sprint(transactionID))
+	return t.Paid
+}
+
+func TestRecordPaymentSettlesOldestInvoicesFirst(t *testing.T) {
+	r := newReceivableTest()
+	firstTr, first := r.invoice(t, 100, "2026-04-01")
+	secondTr, second := r.invoice(t, 50, "2026-05-01")
+
+	res, err := r.receivables.RecordPayment(testActor, &request.ReceivablePaymentRequest{AccountID: 1, Amount: 120, PaymentMethod: "bank_transfer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Allocations) != 2 || res.Allocations[0].InvoiceID != first || res.Allocations[0].Amount != 100 ||
+		res.Allocations[1].InvoiceID != second || res.Allocations[1].Amount != 20 {
+		t.Errorf("allocations = %+v, want 100.00 to invoice %d and 20.00 to invoice %d", res.Allocations, first, second)
+	}
+	if r.open(first) != 0 || r.open(second) != 30 {
+		t.Errorf("open = %.2f and %.2f, want 0.00 and 30.00", r.open(first), r.open(second))
+	}
+	if !r.paid(firstTr) || r.paid(secondTr) {
+		t.Errorf("transactions paid = %v and %v, want only the settled one paid", r.paid(firstTr), r.paid(secondTr))
+	}
+}
+
+func TestRecordPaymentAllocations(t *testing.T) {
+	r := newReceivableTest()
+	_, first := r.invoice(t, 100, "2026-04-01")
+	_, second := r.invoice(t, 50, "2026-05-01")
+
+	tests := []struct {
+		name        string
+		amount      float64
+		allocations []request.ReceivableAllocationRequest
+	}{
+		{"more than the account owes", 150.01, nil},
+		{"more than is open on the invoice", 60, []request.ReceivableAllocationRequest{{InvoiceID: second, Amount: 60}}},
+		{"allocations short of the amount", 80, []request.ReceivableAllocationRequest{{InvoiceID: first, Amount: 50}}},
+		{"an invoice of no open balance", 10, []request.ReceivableAllocationRequest{{InvoiceID: 99, Amount: 10}}},
+	}
+	for _, tt := range tests {
+		req := &request.ReceivablePaymentRequest{AccountID: 1, Amount: tt.amount, PaymentMethod: "cheque", Allocations: tt.allocations}
+		if _, err := r.receivables.RecordPayment(testActor, req); err == nil {
+			t.Errorf("payment of %s was accepted", tt.name)
+		}
+	}
+
+	req := &request.ReceivablePaymentRequest{AccountID: 1, Amount: 70, PaymentMethod: "cheque", Allocations: []request.ReceivableAllocationRequest{
+		{InvoiceID: second, Amount: 50}, {InvoiceID: first, Amount: 20},
+	}}
+	if _, err := r.receivables.RecordPayment(testActor, req); err != nil {
+		t.Fatal(err)
+	}
+	if r.open(first) != 80 || r.open(second) != 0 {
+		t.Errorf("open = %.2f and %.2f, want 80.00 and 0.00", r.open(first), r.open(second))
+	}
+}
+
+func TestCreditNoteSettlesWithoutPaying(t *testing.T) {
+	r := newReceivableTest()
+	tr, invoice := r.invoice(t, 100, "2026-05-01")
+
+	if _, err := r.receivables.IssueCreditNote(testActor, &request.CreditNoteRequest{InvoiceID: invoice, Amount: 100.01, Reason: "Dispute"}); err == nil {
+		t.Error("a credit note exceeded the invoice")
+	}
+	if _, err := r.receivables.IssueCreditNote(testActor, &request.CreditNoteRequest{InvoiceID: invoice, Amount: 100, Reason: "Dispute"}); err != nil {
+		t.Fatal(err)
+	}
+	if r.open(invoice) != 0 {
+		t.Errorf("open after the credit note = %.2f, want 0.00", r.open(invoice))
+	}
+	if r.paid(tr) {
+		t.Error("an invoice settled by a credit note marked its transaction paid")
+	}
+	if _, err := r.refunds.Refund(testActor, &request.RefundRequest{TransactionID: tr, Reason: "Refund"}); err == nil {
+		t.Error("a credited company charge was refunded")
+	}
+	if _, err := r.receivables.RecordPayment(testActor, &request.ReceivablePaymentRequest{AccountID: 1, Amount: 1, PaymentMethod: "cheque"}); err == nil {
+		t.Error("a payment was taken on a settled account")
+	}
+}
+
+func TestPaidCompanyChargeIsNotRefundable(t *testing.T) {
+	r := newReceivableTest()
+	tr, invoice := r.invoice(t, 100, "2026-05-01")
+	if _, err := r.receivables.IssueCreditNote(testActor, &request.CreditNoteRequest{InvoiceID: invoice, Amount: 30, Reason: "Discount"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.receivables.RecordPayment(testActor, &request.ReceivablePaymentRequest{AccountID: 1, Amount: 70, PaymentMethod: "bank_transfer"}); err != nil {
+		t.Fatal(err)
+	}
+	if r.paid(tr) {
+		t.Error("payments of 70.00 paid a charge of 100.00")
+	}
+	if _, err := r.refunds.Refund(testActor, &request.RefundRequest{TransactionID: tr, Reason: "Refund"}); err == nil {
+		t.Error("a company charge was refunded")
+	}
+}
+
+func TestAgingBuckets(t *testing.T) {
+	r := newReceivableTest()
+	_, current := r.invoice(t, 100, "2026-05-02")
+	r.invoice(t, 200, "2026-04-02")
+	r.invoice(t, 300, "2026-03-03")
+	r.invoice(t, 400, "2026-03-02")
+	r.clock.now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := r.receivables.IssueCreditNote(testActor, &request.CreditNoteRequest{InvoiceID: current, Amount: 40, Reason: "Discount"}); err != nil {
+		t.Fatal(err)
+	}
+
+	aging, err := r.receivables.GetAging()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 30, 60, 90 and 91 days old on 2026-06-01.
+	want := struct{ current, days30, days60, days90Plus, total float64 }{60, 200, 300, 400, 960}
+	got := aging.Totals
+	if got.Current != want.current || got.Days30 != want.days30 || got.Days60 != want.days60 || got.Days90Plus != want.days90Plus || got.Total != want.total {
+		t.Errorf("aging totals = %+v, want %+v", got, want)
+	}
+	if len(aging.Accounts) != 1 || aging.Accounts[0].Code != "ACME" || aging.Accounts[0].Total != 960 {
+		t.Errorf("aging accounts = %+v, want ACME owing 960.00", aging.Accounts)
+	}
+	if aging.AsOf != "2026-06-01" {
+		t.Errorf("aging as of %s, want 2026-06-01", aging.AsOf)
+	}
+}
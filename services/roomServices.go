@@ -7,30 +7,42 @@ import (
 	"hms-backend/repository"
 	"hms-backend/request"
 	"hms-backend/response"
+	"sort"
+	"strings"
 )
 
 type RoomServices interface {
-	GetAll() ([]*response.RoomResponse, error)
+	GetAll(propertyID uint) ([]*response.RoomResponse, error)
 	GetByID(id uint) (*response.RoomResponse, error)
-	Create(input *request.CreateRoomRequest) (*response.RoomResponse, error)
-	Update(input request.UpdateRoomRequest) (*response.RoomResponse, error)
-	Delete(roomNumber string) error
-	ChangeStatus(id uint, status string) error
+	Create(actor *model.Principal, input *request.CreateRoomRequest) (*response.RoomResponse, error)
+	Update(actor *model.Principal, input request.UpdateRoomRequest) (*response.RoomResponse, error)
+	Delete(actor *model.Principal, propertyID uint, roomNumber string) error
+	ChangeStatus(actor *model.Principal, id uint, status string) error
 	FindAvailable(params request.RoomFilterParams) ([]*response.RoomResponse, error)
-	CreateRoomType(input *request.CreateRoomTypeRequest) (*response.RoomTypeDetail, error)
+	CreateRoomType(actor *model.Principal, input *request.CreateRoomTypeRequest) (*response.RoomTypeDetail, error)
+	GetAllRoomTypes(propertyID uint, includeRetired bool) ([]*response.RoomTypeDetail, error)
+	GetRoomTypeByID(id uint) (*response.RoomTypeDetail, error)
+	UpdateRoomType(actor *model.Principal, input *request.UpdateRoomTypeRequest) (*response.RoomTypeDetail, error)
+	RetireRoomType(actor *model.Principal, id uint) (*response.RoomTypeDetail, error)
+	ReactivateRoomType(actor *model.Principal, id uint) (*response.RoomTypeDetail, error)
+	DeleteRoomType(actor *model.Principal, id uint) error
 	GetRoomModelByID(id uint) (*model.Room, error)
 }
 
 type roomServices struct {
-	roomRepository repository.RoomRepository
+	roomRepository   repository.RoomRepository
+	guestRepository  repository.GuestRepository
+	propertyServices PropertyServices
+	auditServices    AuditServices
+	clock            Clock
 }
 
-func NewRoomServices(roomRepo repository.RoomRepository) RoomServices {
-	return &roomServices{roomRepo}
+func NewRoomServices(roomRepo repository.RoomRepository, guestRepo repository.GuestRepository, property PropertyServices, audit AuditServices, clock Clock) RoomServices {
+	return &roomServices{roomRepo, guestRepo, property, audit, clock}
 }
 
-func (s *roomServices) GetAll() ([]*response.RoomResponse, error) {
-	rooms, err := s.roomRepository.FindAll()
+func (s *roomServices) GetAll(propertyID uint) ([]*response.RoomResponse, error) {
+	rooms, err := s.roomRepository.FindAll(propertyID)
 	if err != nil {
 		return nil, err
 	}
@@ -49,23 +61,46 @@ func (s *roomServices) GetRoomModelByID(id uint) (*model.Room, error) {
 	return s.roomRepository.FindByID(id)
 }
 
-func (s *roomServices) Create(input *request.CreateRoomRequest) (*response.RoomResponse, error) {
+func (s *roomServices) Create(actor *model.Principal, input *request.CreateRoomRequest) (*response.RoomResponse, error) {
 	if !isValidRoomStatus(input.Status) {
 		return nil, errors.New("invalid room status provided. must be 'available' or 'maintenance'")
 	}
+	if !isValidBedConfiguration(input.BedConfiguration) {
+		return nil, errors.New("invalid bed configuration provided. must be 'king', 'queen', 'double', 'twin' or 'single'")
+	}
+	if _, err := s.propertyServices.GetPropertyModelByID(input.PropertyID); err != nil {
+		return nil, fmt.Errorf("property not found: %w", err)
+	}
+	if err := s.ensureRoomTypeAssignable(input.PropertyID, uint(input.RoomTypeID)); err != nil {
+		return nil, err
+	}
+	connectingIDs, err := s.connectingRoomIDs(0, input.PropertyID, input.RoomAttributes.ConnectingRoomIDs)
+	if err != nil {
+		return nil, err
+	}
 	room := model.Room{
+		PropertyID: input.PropertyID,
 		Status:     model.RoomStatus(input.Status),
 		Number:     input.Number,
 		RoomTypeID: uint(input.RoomTypeID),
 	}
+	applyRoomAttributes(&room, input.RoomAttributes)
 	createdRoom, err := s.roomRepository.Create(&room)
 	if err != nil {
 		return nil, err
 	}
+	s.auditServices.Record(actor, model.EntityRoom, createdRoom.ID, model.AuditCreate, nil, createdRoom)
+	if err := s.saveRoomRelations(createdRoom, input.RoomAttributes.Amenities, connectingIDs); err != nil {
+		return nil, err
+	}
+	createdRoom, err = s.roomRepository.FindByID(createdRoom.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch room after create: %w", err)
+	}
 	return mapToRoomResponse(createdRoom), err
 }
 
-func (s *roomServices) Update(update request.UpdateRoomRequest) (*response.RoomResponse, error) {
+func (s *roomServices) Update(actor *model.Principal, update request.UpdateRoomRequest) (*response.RoomResponse, error) {
 	room, err := s.roomRepository.FindByID(update.ID)
 	if err != nil {
 		return nil, err
@@ -73,61 +108,404 @@ func (s *roomServices) Update(update request.UpdateRoomRequest) (*response.RoomR
 	if room == nil {
 		return nil, errors.New("room not found")
 	}
+	before := *room
 	if !isValidRoomStatus(update.Status) {
 		return nil, errors.New("invalid room status provided. must be 'available' or 'maintenance'")
 	}
+	if !isValidBedConfiguration(update.BedConfiguration) {
+		return nil, errors.New("invalid bed configuration provided. must be 'king', 'queen', 'double', 'twin' or 'single'")
+	}
+	// Existing rooms may keep a retired type, but cannot be moved onto one.
+	if update.RoomTypeID != room.RoomTypeID {
+		if err := s.ensureRoomTypeAssignable(room.PropertyID, update.RoomTypeID); err != nil {
+			return nil, err
+		}
+	}
+	connectingIDs, err := s.connectingRoomIDs(room.ID, room.PropertyID, update.RoomAttributes.ConnectingRoomIDs)
+	if err != nil {
+		return nil, err
+	}
 	room.Number = update.Number
 	room.Status = model.RoomStatus(update.Status)
 	room.RoomTypeID = uint(update.RoomTypeID)
 	room.RoomType = model.RoomType{}
+	applyRoomAttributes(room, update.RoomAttributes)
+	// Relations are replaced explicitly below; Save would only append to them.
+	room.Amenities = nil
+	room.ConnectingRooms = nil
 	err = s.roomRepository.Update(room)
 	if err != nil {
 		return nil, err
 	}
+	s.auditServices.Record(actor, model.EntityRoom, room.ID, model.AuditUpdate, &before, room)
+	if err := s.saveRoomRelations(room, update.RoomAttributes.Amenities, connectingIDs); err != nil {
+		return nil, err
+	}
 	updatedRoom, err := s.roomRepository.FindByID(update.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch room after update: %w", err)
 	}
 	return mapToRoomResponse(updatedRoom), nil
 }
-func (s *roomServices) Delete(roomNumber string) error {
-	room, err := s.roomRepository.FindByNumber(roomNumber)
+func (s *roomServices) Delete(actor *model.Principal, propertyID uint, roomNumber string) error {
+	room, err := s.roomRepository.FindByNumber(propertyID, roomNumber)
 	if err != nil {
 		return err
 	}
-	return s.roomRepository.Delete(room.ID)
+	if err := s.roomRepository.Delete(room.ID); err != nil {
+		return err
+	}
+	s.auditServices.Record(actor, model.EntityRoom, room.ID, model.AuditDelete, room, nil)
+	return nil
 }
 
-func (s *roomServices) ChangeStatus(id uint, status string) error {
+func (s *roomServices) ChangeStatus(actor *model.Principal, id uint, status string) error {
 	if !isValidRoomStatus(status) {
 		return errors.New("invalid room status provided. must be 'available' or 'maintenance'")
 	}
-	return s.roomRepository.ChangeStatus(id, status)
+	room, err := s.roomRepository.FindByID(id)
+	if err != nil {
+		return err
+	}
+	before := *room
+	if err := s.roomRepository.ChangeStatus(id, status); err != nil {
+		return err
+	}
+	room.Status = model.RoomStatus(status)
+	s.auditServices.Record(actor, model.EntityRoom, id, model.AuditUpdate, &before, room)
+	return nil
 }
 
 func (s *roomServices) FindAvailable(params request.RoomFilterParams) ([]*response.RoomResponse, error) {
+	if !isValidBedConfiguration(params.BedConfiguration) {
+		return nil, errors.New("invalid bed configuration provided. must be 'king', 'queen', 'double', 'twin' or 'single'")
+	}
+	params.View = strings.ToLower(strings.TrimSpace(params.View))
+	params.Amenities = normalizeAmenities(params.Amenities)
+	var preferences *model.GuestPreferences
+	if params.GuestID != 0 {
+		guest, err := s.guestRepository.FindByID(params.GuestID)
+		if err != nil {
+			return nil, errors.New("Guest Not Found")
+		}
+		preferences = &guest.Preferences
+		// Guests who need an accessible room are never offered another one.
+		if preferences.NeedsAccessibleRoom() && params.Accessible == nil {
+			accessible := true
+			params.Accessible = &accessible
+		}
+	}
 	rooms, err := s.roomRepository.FindAvailable(params)
 	if err != nil {
 		return nil, err
 	}
+	if preferences == nil {
+		return mapToRoomResponseSlice(rooms), nil
+	}
+	return rankRoomsByPreferences(rooms, preferences), nil
+}
 
-	return mapToRoomResponseSlice(rooms), nil
+// rankRoomsByPreferences puts the rooms matching most of the guest's
+// preferences first and lists what matched on each room.
+func rankRoomsByPreferences(rooms []*model.Room, p *model.GuestPreferences) []*response.RoomResponse {
+	resp := mapToRoomResponseSlice(rooms)
+	scores := make([]int, len(rooms))
+	for i, room := range rooms {
+		if len(p.Accessibility) > 0 && room.Accessible {
+			scores[i] += 2
+			resp[i].PreferenceMatches = append(resp[i].PreferenceMatches, "accessible")
+		}
+	}
+	// Floors are only comparable between rooms, so the best floor gets the point.
+	if p.FloorPreference != "" && len(rooms) > 0 {
+		best := rooms[0].Floor
+		for _, room := range rooms {
+			if (p.FloorPreference == model.FloorLow && room.Floor < best) ||
+				(p.FloorPreference == model.FloorHigh && room.Floor > best) {
+				best = room.Floor
+			}
+		}
+		for i, room := range rooms {
+			if room.Floor == best {
+				scores[i]++
+				resp[i].PreferenceMatches = append(resp[i].PreferenceMatches, string(p.FloorPreference)+"_floor")
+			}
+		}
+	}
+	order := make([]int, len(rooms))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if scores[i] != scores[j] {
+			return scores[i] > scores[j]
+		}
+		if p.FloorPreference == model.FloorHigh {
+			return rooms[i].Floor > rooms[j].Floor
+		}
+		if p.FloorPreference == model.FloorLow {
+			return rooms[i].Floor < rooms[j].Floor
+		}
+		return false
+	})
+	ranked := make([]*response.RoomResponse, len(order))
+	for k, i := range order {
+		ranked[k] = resp[i]
+	}
+	return ranked
 }
 
-func (s *roomServices) CreateRoomType(input *request.CreateRoomTypeRequest) (*response.RoomTypeDetail, error) {
+func (s *roomServices) CreateRoomType(actor *model.Principal, input *request.CreateRoomTypeRequest) (*response.RoomTypeDetail, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.New("room type name is required")
+	}
+	if _, err := s.propertyServices.GetPropertyModelByID(input.PropertyID); err != nil {
+		return nil, fmt.Errorf("property not found: %w", err)
+	}
+	if _, err := s.roomRepository.FindRoomTypeByName(input.PropertyID, name); err == nil {
+		return nil, errors.New("room type with this name already exists")
+	}
+	if err := validateOccupancyRules(input.Capacity, input.OccupancyRules); err != nil {
+		return nil, err
+	}
 	roomType := model.RoomType{
+		PropertyID:  input.PropertyID,
 		Price:       input.Price,
 		Capacity:    input.Capacity,
 		Description: input.Description,
-		Name:        input.Name,
+		Name:        name,
 	}
+	applyOccupancyRules(&roomType, input.OccupancyRules)
+	applyDepositPolicy(&roomType, input.DepositPolicy)
 	createdRoomType, err := s.roomRepository.CreateRoomType(&roomType)
 	if err != nil {
 		return nil, err
 	}
+	s.auditServices.Record(actor, model.EntityRoomType, createdRoomType.ID, model.AuditCreate, nil, createdRoomType)
 	return mapToRoomTypeResponse(createdRoomType), nil
 }
 
+func (s *roomServices) GetAllRoomTypes(propertyID uint, includeRetired bool) ([]*response.RoomTypeDetail, error) {
+	roomTypes, err := s.roomRepository.FindAllRoomTypes(propertyID, includeRetired)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.RoomTypeDetail, len(roomTypes))
+	for i, roomType := range roomTypes {
+		resp[i] = mapToRoomTypeResponse(roomType)
+	}
+	return resp, nil
+}
+
+func (s *roomServices) GetRoomTypeByID(id uint) (*response.RoomTypeDetail, error) {
+	roomType, err := s.roomRepository.FindRoomTypeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return mapToRoomTypeResponse(roomType), nil
+}
+
+func (s *roomServices) UpdateRoomType(actor *model.Principal, input *request.UpdateRoomTypeRequest) (*response.RoomTypeDetail, error) {
+	roomType, err := s.roomRepository.FindRoomTypeByID(input.ID)
+	if err != nil {
+		return nil, err
+	}
+	before := *roomType
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.New("room type name is required")
+	}
+	if existing, err := s.roomRepository.FindRoomTypeByName(roomType.PropertyID, name); err == nil && existing.ID != roomType.ID {
+		return nil, errors.New("room type with this name already exists")
+	}
+	if err := validateOccupancyRules(input.Capacity, input.OccupancyRules); err != nil {
+		return nil, err
+	}
+	roomType.Name = name
+	roomType.Description = input.Description
+	roomType.Price = input.Price
+	roomType.Capacity = input.Capacity
+	applyOccupancyRules(roomType, input.OccupancyRules)
+	applyDepositPolicy(roomType, input.DepositPolicy)
+	if err := s.roomRepository.UpdateRoomType(roomType); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityRoomType, roomType.ID, model.AuditUpdate, &before, roomType)
+	return mapToRoomTypeResponse(roomType), nil
+}
+
+func (s *roomServices) RetireRoomType(actor *model.Principal, id uint) (*response.RoomTypeDetail, error) {
+	roomType, err := s.roomRepository.FindRoomTypeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *roomType
+	if roomType.Retired {
+		return nil, errors.New("room type already retired")
+	}
+	now := s.clock.Now()
+	roomType.Retired = true
+	roomType.RetiredAt = &now
+	if err := s.roomRepository.UpdateRoomType(roomType); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityRoomType, roomType.ID, model.AuditUpdate, &before, roomType)
+	return mapToRoomTypeResponse(roomType), nil
+}
+
+func (s *roomServices) ReactivateRoomType(actor *model.Principal, id uint) (*response.RoomTypeDetail, error) {
+	roomType, err := s.roomRepository.FindRoomTypeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := *roomType
+	if !roomType.Retired {
+		return nil, errors.New("room type is not retired")
+	}
+	roomType.Retired = false
+	roomType.RetiredAt = nil
+	if err := s.roomRepository.UpdateRoomType(roomType); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityRoomType, roomType.ID, model.AuditUpdate, &before, roomType)
+	return mapToRoomTypeResponse(roomType), nil
+}
+
+func (s *roomServices) DeleteRoomType(actor *model.Principal, id uint) error {
+	roomType, err := s.roomRepository.FindRoomTypeByID(id)
+	if err != nil {
+		return err
+	}
+	rooms, err := s.roomRepository.CountRoomsByType(id)
+	if err != nil {
+		return err
+	}
+	if rooms > 0 {
+		return fmt.Errorf("room type is still used by %d room(s), retire it instead", rooms)
+	}
+	bookings, err := s.roomRepository.CountBookingsByRoomType(id)
+	if err != nil {
+		return err
+	}
+	if bookings > 0 {
+		return fmt.Errorf("room type is referenced by %d booking(s), retire it instead", bookings)
+	}
+	if err := s.roomRepository.DeleteRoomType(id); err != nil {
+		return err
+	}
+	s.auditServices.Record(actor, model.EntityRoomType, id, model.AuditDelete, roomType, nil)
+	return nil
+}
+
+func validateOccupancyRules(capacity uint, rules request.OccupancyRules) error {
+	if rules.BaseOccupancy > capacity {
+		return errors.New("base occupancy cannot exceed capacity")
+	}
+	return nil
+}
+
+func applyOccupancyRules(roomType *model.RoomType, rules request.OccupancyRules) {
+	roomType.BaseOccupancy = rules.BaseOccupancy
+	roomType.MaxExtraBeds = rules.MaxExtraBeds
+	roomType.ExtraBedPrice = rules.ExtraBedPrice
+	roomType.ExtraAdultPrice = rules.ExtraAdultPrice
+	roomType.ChildPrice = rules.ChildPrice
+}
+
+func applyDepositPolicy(roomType *model.RoomType, policy request.DepositPolicy) {
+	roomType.DepositPercent = policy.DepositPercent
+	roomType.DepositDueDays = policy.DepositDueDays
+	roomType.BalanceDueDays = policy.BalanceDueDays
+}
+
+func (s *roomServices) ensureRoomTypeAssignable(propertyID, id uint) error {
+	roomType, err := s.roomRepository.FindRoomTypeByID(id)
+	if err != nil {
+		return fmt.Errorf("room type not found: %w", err)
+	}
+	if roomType.PropertyID != propertyID {
+		return errors.New("room type belongs to another property")
+	}
+	if roomType.Retired {
+		return errors.New("room type is retired and cannot be assigned")
+	}
+	return nil
+}
+
+// connectingRoomIDs checks that the connecting rooms requested for a room
+// exist in the same property and returns their IDs without duplicates. It
+// runs before the room is saved, so invalid input leaves nothing behind.
+func (s *roomServices) connectingRoomIDs(roomID, propertyID uint, ids []uint) ([]uint, error) {
+	seen := make(map[uint]bool)
+	connectingIDs := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if roomID != 0 && id == roomID {
+			return nil, errors.New("a room cannot connect to itself")
+		}
+		if seen[id] {
+			continue
+		}
+		connecting, err := s.roomRepository.FindByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("connecting room %d not found: %w", id, err)
+		}
+		if connecting.PropertyID != propertyID {
+			return nil, fmt.Errorf("connecting room %d belongs to another property", id)
+		}
+		seen[id] = true
+		connectingIDs = append(connectingIDs, id)
+	}
+	return connectingIDs, nil
+}
+
+// saveRoomRelations replaces the amenities and connecting rooms of a persisted room.
+func (s *roomServices) saveRoomRelations(room *model.Room, amenityNames []string, connectingIDs []uint) error {
+	amenities, err := s.roomRepository.FindOrCreateAmenities(normalizeAmenities(amenityNames))
+	if err != nil {
+		return err
+	}
+	if err := s.roomRepository.ReplaceAmenities(room, amenities); err != nil {
+		return err
+	}
+	return s.roomRepository.ReplaceConnectingRooms(room.ID, connectingIDs)
+}
+
+func applyRoomAttributes(room *model.Room, attrs request.RoomAttributes) {
+	room.Floor = attrs.Floor
+	room.Building = strings.TrimSpace(attrs.Building)
+	room.View = strings.ToLower(strings.TrimSpace(attrs.View))
+	room.BedConfiguration = model.BedConfiguration(attrs.BedConfiguration)
+	room.Smoking = attrs.Smoking
+	room.Accessible = attrs.Accessible
+}
+
+// normalizeAmenities lower-cases names and drops blanks and duplicates.
+func normalizeAmenities(names []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+func isValidBedConfiguration(bed string) bool {
+	// An empty value means the bed configuration is not specified.
+	switch model.BedConfiguration(bed) {
+	case "", model.BedKing, model.BedQueen, model.BedDouble, model.BedTwin, model.BedSingle:
+		return true
+	default:
+		return false
+	}
+}
+
 func isValidRoomStatus(status string) bool {
 	// Cast the string to a RoomStatus to compare against the constants
 	s := model.RoomStatus(status)
@@ -144,31 +522,60 @@ func mapToRoomResponse(room *model.Room) *response.RoomResponse {
 		return nil
 	}
 	resp := &response.RoomResponse{
-		ID:     room.ID,
-		Number: room.Number,
-		Status: room.Status, // Correct type cast
+		ID:                room.ID,
+		PropertyID:        room.PropertyID,
+		Number:            room.Number,
+		Status:            room.Status, // Correct type cast
+		Floor:             room.Floor,
+		Building:          room.Building,
+		View:              room.View,
+		BedConfiguration:  room.BedConfiguration,
+		Smoking:           room.Smoking,
+		Accessible:        room.Accessible,
+		ConnectingRoomIDs: mapToConnectingRoomIDs(room.ConnectingRooms),
+		Amenities:         mapToAmenityNames(room.Amenities),
 	}
 	// FIX: Nil check before accessing nested struct fields
 	if &room.RoomType != nil {
-		resp.RoomType = response.RoomTypeDetail{
-			ID:          room.RoomType.ID,
-			Name:        room.RoomType.Name,
-			Description: room.RoomType.Description,
-			Price:       room.RoomType.Price,
-			Capacity:    room.RoomType.Capacity,
-		}
+		resp.RoomType = *mapToRoomTypeResponse(&room.RoomType)
 	}
 	return resp
 }
 
 func mapToRoomTypeResponse(room *model.RoomType) *response.RoomTypeDetail {
 	return &response.RoomTypeDetail{
-		ID:          room.ID,
-		Name:        room.Name,
-		Description: room.Description,
-		Price:       room.Price,
-		Capacity:    room.Capacity,
+		ID:              room.ID,
+		PropertyID:      room.PropertyID,
+		Name:            room.Name,
+		Description:     room.Description,
+		Price:           room.Price,
+		Capacity:        room.Capacity,
+		BaseOccupancy:   room.BaseOccupancy,
+		MaxExtraBeds:    room.MaxExtraBeds,
+		ExtraBedPrice:   room.ExtraBedPrice,
+		ExtraAdultPrice: room.ExtraAdultPrice,
+		ChildPrice:      room.ChildPrice,
+		DepositPercent:  room.DepositPercent,
+		DepositDueDays:  room.DepositDueDays,
+		BalanceDueDays:  room.BalanceDueDays,
+		Retired:         room.Retired,
+	}
+}
+
+func mapToConnectingRoomIDs(rooms []*model.Room) []uint {
+	ids := make([]uint, len(rooms))
+	for i, room := range rooms {
+		ids[i] = room.ID
+	}
+	return ids
+}
+
+func mapToAmenityNames(amenities []model.Amenity) []string {
+	names := make([]string, len(amenities))
+	for i, amenity := range amenities {
+		names[i] = amenity.Name
 	}
+	return names
 }
 
 func mapToRoomResponseSlice(rooms []*model.Room) []*response.RoomResponse {
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/repository"
+	"hms-backend/request"
+	"hms-backend/response"
+	"math"
+)
+
+type TransactionServices interface {
+	Create(actor *model.Principal, req *request.CreateTransactionRequest) (*response.TransactionResponse, error)
+	MarkPaid(actor *model.Principal, id uint) (*response.TransactionResponse, error)
+	GetForBooking(ref string) ([]*response.TransactionResponse, error)
+	Refund(actor *model.Principal, req *request.RefundRequest) (*response.TransactionResponse, error)
+	RefundableFor(bookingID string) (float64, error)
+	RefundBooking(actor *model.Principal, bookingID string, amount float64, reason string) (float64, error)
+	RecordPayment(actor *model.Principal, bookingID string, amount float64, method, reason string) (*model.Transaction, error)
+	PaidFor(bookingID string) (float64, error)
+}
+
+type transactionServices struct {
+	transactionRepository repository.TransactionRepository
+	bookingRepository     repository.BookingRepository
+	gateway               PaymentGateway
+	auditServices         AuditServices
+}
+
+func NewTransactionServices(repo repository.TransactionRepository, booking repository.BookingRepository, gateway PaymentGateway, audit AuditServices) TransactionServices {
+	return &transactionServices{transactionRepository: repo, bookingRepository: booking, gateway: gateway, auditServices: audit}
+}
+
+func (s *transactionServices) Create(actor *model.Principal, req *request.CreateTransactionRequest) (*response.TransactionResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(req.BookingReference)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	if booking.Status == model.StatusCancelled {
+		return nil, errors.New("Booking Is Cancelled")
+	}
+	if req.PaymentMethod == model.PaymentMethodLoyalty {
+		return nil, errors.New("loyalty points are redeemed through the loyalty program")
+	}
+	t := model.Transaction{
+		BookingID:     booking.ID,
+		Type:          model.TransactionPayment,
+		Amount:        req.Amount,
+		PaymentMethod: req.PaymentMethod,
+	}
+	if err := s.transactionRepository.Create(&t); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditCreate, nil, &t)
+	return mapToTransactionResponse(&t), nil
+}
+
+func (s *transactionServices) MarkPaid(actor *model.Principal, id uint) (*response.TransactionResponse, error) {
+	t, err := s.transactionRepository.GetByID(fmt.Sprint(id))
+	if err != nil {
+		return nil, errors.New("Transaction Not Found")
+	}
+	if t.Paid {
+		return nil, errors.New("Transaction Already Paid")
+	}
+	if t.IsRefund() {
+		return nil, errors.New("refunds are paid out when they are recorded")
+	}
+	if t.ViaGateway() {
+		return nil, errors.New("card payments are settled through the payment gateway")
+	}
+	if t.CorporateAccountID != nil {
+		return nil, errors.New("transaction is billed to a company, record the payment on its city ledger")
+	}
+	before := t
+	t.Paid = true
+	if err := s.transactionRepository.Update(&t); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditUpdate, &before, &t)
+	return mapToTransactionResponse(&t), nil
+}
+
+func (s *transactionServices) GetForBooking(ref string) ([]*response.TransactionResponse, error) {
+	booking, err := s.bookingRepository.FindByReferenceID(ref)
+	if err != nil {
+		return nil, errors.New("Booking Not Found")
+	}
+	ts, err := s.transactionRepository.FindByBookingID(booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*response.TransactionResponse, len(ts))
+	for i, t := range ts {
+		resp[i] = mapToTransactionResponse(t)
+	}
+	return resp, nil
+}
+
+// RecordPayment stores money received for a booking, e.g. a deposit.
+func (s *transactionServices) RecordPayment(actor *model.Principal, bookingID string, amount float64, method, reason string) (*model.Transaction, error) {
+	if method == model.PaymentMethodLoyalty || method == model.PaymentMethodDirectBill {
+		return nil, fmt.Errorf("%s cannot be recorded as a payment", method)
+	}
+	t := model.Transaction{
+		BookingID:     bookingID,
+		Type:          model.TransactionPayment,
+		Amount:        roundAmount(amount),
+		PaymentMethod: method,
+		Paid:          true,
+		Reason:        reason,
+	}
+	if err := s.transactionRepository.Create(&t); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, t.Id, model.AuditCreate, nil, &t)
+	return &t, nil
+}
+
+// PaidFor is the money held for a booking: paid payments less refunds.
+func (s *transactionServices) PaidFor(bookingID string) (float64, error) {
+	ts, err := s.transactionRepository.FindByBookingID(bookingID)
+	if err != nil {
+		return 0, err
+	}
+	var paid float64
+	for _, t := range ts {
+		if t.PaymentMethod != model.PaymentMethodDirectBill {
+			paid += t.Received()
+		}
+	}
+	return roundAmount(paid), nil
+}
+
+// Refund gives back part or all of a paid payment. Without an amount, what
+// is left of the payment is refunded.
+func (s *transactionServices) Refund(actor *model.Principal, req *request.RefundRequest) (*response.TransactionResponse, error) {
+	if _, err := s.transactionRepository.GetByID(fmt.Sprint(req.TransactionID)); err != nil {
+		return nil, errors.New("Transaction Not Found")
+	}
+	refund, err := s.refund(actor, req.TransactionID, req.Amount, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return mapToTransactionResponse(refund), nil
+}
+
+// RefundableFor is what could still be refunded from the booking's payments.
+func (s *transactionServices) RefundableFor(bookingID string) (float64, error) {
+	ts, err := s.transactionRepository.FindByBookingID(bookingID)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, available := range refundablePayments(ts) {
+		total += available
+	}
+	return roundAmount(total), nil
+}
+
+// RefundBooking refunds up to amount from the booking's payments, starting
+// with the latest one, and returns what was refunded.
+func (s *transactionServices) RefundBooking(actor *model.Principal, bookingID string, amount float64, reason string) (float64, error) {
+	ts, err := s.transactionRepository.FindByBookingID(bookingID)
+	if err != nil {
+		return 0, err
+	}
+	available := refundablePayments(ts)
+	var refunded float64
+	for i := len(ts) - 1; i >= 0 && roundAmount(amount-refunded) > 0; i-- {
+		left, ok := available[ts[i].Id]
+		if !ok {
+			continue
+		}
+		refund, err := s.refund(actor, ts[i].Id, math.Min(left, roundAmount(amount-refunded)), reason)
+		if err != nil {
+			return refunded, err
+		}
+		refunded = roundAmount(refunded + refund.Amount)
+	}
+	return refunded, nil
+}
+
+// refund records a refund of amount from a payment, or of all that is left
+// of it when amount is zero. Refunds go back through the payment method of
+// the payment and count as paid out straight away, except card refunds the
+// gateway has yet to confirm. A card refund is recorded as pending before
+// the gateway is called, which holds its amount against the payment, and
+// fails if the gateway refuses it.
+func (s *transactionServices) refund(actor *model.Principal, paymentID uint, amount float64, reason string) (*model.Transaction, error) {
+	refund := model.Transaction{
+		Type:       model.TransactionRefund,
+		Paid:       true,
+		RefundOfID: &paymentID,
+		Reason:     reason,
+	}
+	var gatewayPaymentID string
+	err := s.transactionRepository.CreateRefund(&refund, func(payment *model.Transaction, refunded float64) error {
+		if err := checkRefundable(payment); err != nil {
+			return err
+		}
+		left := roundAmount(payment.Amount - refunded)
+		if left <= 0 {
+			return errors.New("payment has already been refunded in full")
+		}
+		if amount == 0 {
+			amount = left
+		}
+		if roundAmount(amount) > left {
+			return fmt.Errorf("refund of %.2f exceeds the %.2f left to refund on this payment", amount, left)
+		}
+		refund.BookingID = payment.BookingID
+		refund.PaymentMethod = payment.PaymentMethod
+		refund.Amount = roundAmount(amount)
+		if !payment.ViaGateway() {
+			return nil
+		}
+		gatewayPaymentID = payment.GatewayID
+		refund.GatewayStatus = model.GatewayPending
+		refund.CardBrand = payment.CardBrand
+		refund.CardLast4 = payment.CardLast4
+		refund.Paid = false
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if gatewayPaymentID == "" {
+		s.auditServices.Record(actor, model.EntityTransaction, refund.Id, model.AuditCreate, nil, &refund)
+		return &refund, nil
+	}
+	res, err := callGateway(func() (*GatewayPayment, error) {
+		return s.gateway.Refund(gatewayPaymentID, refund.Amount, fmt.Sprintf("refund-%d", refund.Id))
+	})
+	if err != nil {
+		res = &GatewayPayment{Status: model.GatewayFailed, Message: err.Error()}
+	}
+	refund.GatewayID = res.ID
+	refund.GatewayStatus = res.Status
+	// A pending refund is paid out once the gateway confirms it.
+	refund.Paid = res.Status == model.GatewayRefunded
+	if err := s.transactionRepository.Update(&refund); err != nil {
+		return nil, err
+	}
+	s.auditServices.Record(actor, model.EntityTransaction, refund.Id, model.AuditCreate, nil, &refund)
+	if res.Status == model.GatewayFailed {
+		return nil, fmt.Errorf("card refund failed: %s", res.Message)
+	}
+	return &refund, nil
+}
+
+// checkRefundable rejects transactions that do not hold money the hotel can
+// give back.
+func checkRefundable(t *model.Transaction) error {
+	switch {
+	case t.IsRefund():
+		return errors.New("a refund cannot be refunded")
+	case t.GatewayStatus == model.GatewayAuthorized:
+		return errors.New("authorized card payments are voided, not refunded")
+	case !t.Paid:
+		return errors.New("only paid transactions can be refunded")
+	case t.PaymentMethod == model.PaymentMethodDirectBill || t.CorporateAccountID != nil:
+		return errors.New("company charges are reduced with a credit note on the city ledger")
+	case t.PaymentMethod == model.PaymentMethodLoyalty:
+		return errors.New("loyalty point redemptions cannot be refunded")
+	}
+	return nil
+}
+
+// refundablePayments maps each refundable payment among ts to what is left
+// to refund on it.
+func refundablePayments(ts []*model.Transaction) map[uint]float64 {
+	refunded := make(map[uint]float64)
+	for _, t := range ts {
+		if t.IsRefund() && t.RefundOfID != nil && t.GatewayStatus != model.GatewayFailed {
+			refunded[*t.RefundOfID] += t.Amount
+		}
+	}
+	available := make(map[uint]float64)
+	for _, t := range ts {
+		if checkRefundable(t) != nil {
+			continue
+		}
+		if left := roundAmount(t.Amount - refunded[t.Id]); left > 0 {
+			available[t.Id] = left
+		}
+	}
+	return available
+}
+
+func mapToTransactionResponse(t *model.Transaction) *response.TransactionResponse {
+	return &response.TransactionResponse{
+		ID:                 t.Id,
+		BookingID:          t.BookingID,
+		Type:               t.Type,
+		Amount:             t.Amount,
+		PaymentMethod:      t.PaymentMethod,
+		Paid:               t.Paid,
+		CorporateAccountID: t.CorporateAccountID,
+		RefundOfID:         t.RefundOfID,
+		Reason:             t.Reason,
+		GatewayID:          t.GatewayID,
+		GatewayStatus:      t.GatewayStatus,
+		CardBrand:          t.CardBrand,
+		CardLast4:          t.CardLast4,
+		CreatedAt:          t.CreatedAt,
+	}
+}
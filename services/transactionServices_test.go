@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"hms-backend/model"
+	"hms-backend/request"
+	"testing"
+)
+
+func TestRefundablePayments(t *testing.T) {
+	account := uint(1)
+	cash := &model.Transaction{Id: 1, Type: model.TransactionPayment, Amount: 100, PaymentMethod: "cash", Paid: true}
+	ts := []*model.Transaction{
+		cash,
+		{Id: 2, Type: model.TransactionRefund, Amount: 30, PaymentMethod: "cash", Paid: true, RefundOfID: &cash.Id},
+		// A failed card refund gave nothing back.
+		{Id: 3, Type: model.TransactionRefund, Amount: 20, PaymentMethod: "cash", GatewayStatus: model.GatewayFailed, RefundOfID: &cash.Id},
+		{Id: 4, Type: model.TransactionPayment, Amount: 40, PaymentMethod: "bank_transfer"},
+		{Id: 5, Type: model.TransactionPayment, Amount: 25, PaymentMethod: model.PaymentMethodLoyalty, Paid: true},
+		{Id: 6, Type: model.TransactionPayment, Amount: 200, PaymentMethod: model.PaymentMethodDirectBill, CorporateAccountID: &account},
+		// Settled on the city ledger, still not the hotel's money to give back.
+		{Id: 7, Type: model.TransactionPayment, Amount: 80, PaymentMethod: "bank_transfer", Paid: true, CorporateAccountID: &account},
+		{Id: 8, Type: model.TransactionPayment, Amount: 60, PaymentMethod: model.PaymentMethodCard, GatewayID: "fake_pay_1", GatewayStatus: model.GatewayAuthorized},
+		{Id: 9, Type: model.TransactionPayment, Amount: 50, PaymentMethod: "cash", Paid: true},
+	}
+	got := refundablePayments(ts)
+	want := map[uint]float64{1: 70, 9: 50}
+	if len(got) != len(want) {
+		t.Fatalf("refundablePayments = %v, want %v", got, want)
+	}
+	for id, amount := range want {
+		if got[id] != amount {
+			t.Errorf("refundable on payment %d = %.2f, want %.2f", id, got[id], amount)
+		}
+	}
+}
+
+func newRefundTest(t *testing.T, payments ...float64) (*memoryTransactions, TransactionServices) {
+	t.Helper()
+	transactions := newMemoryTransactions()
+	for _, amount := range payments {
+		transactions.Create(&model.Transaction{BookingID: testBookingID, Type: model.TransactionPayment, Amount: amount, PaymentMethod: "cash", Paid: true})
+	}
+	bookings := &oneBooking{booking: &model.Booking{ID: testBookingID, BookingReference: "BK1", Status: model.StatusConfirmed, PropertyID: 1}}
+	return transactions, NewTransactionServices(transactions, bookings, newTestGateway(), noAudit{})
+}
+
+func TestRefundBookingStartsWithTheLatestPayment(t *testing.T) {
+	transactions, refunds := newRefundTest(t, 100, 60)
+	refunded, err := refunds.RefundBooking(testActor, testBookingID, 130, "Cancelled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refunded != 130 {
+		t.Errorf("RefundBooking refunded %.2f, want 130.00", refunded)
+	}
+	ts, _ := transactions.FindByBookingID(testBookingID)
+	var fromFirst, fromSecond float64
+	for _, tr := range ts {
+		if tr.IsRefund() && *tr.RefundOfID == 1 {
+			fromFirst += tr.Amount
+		}
+		if tr.IsRefund() && *tr.RefundOfID == 2 {
+			fromSecond += tr.Amount
+		}
+	}
+	if fromSecond != 60 || fromFirst != 70 {
+		t.Errorf("refunded %.2f from the latest and %.2f from the first payment, want 60.00 and 70.00", fromSecond, fromFirst)
+	}
+	if left, _ := refunds.RefundableFor(testBookingID); left != 30 {
+		t.Errorf("RefundableFor = %.2f, want 30.00", left)
+	}
+	if paid, _ := refunds.PaidFor(testBookingID); paid != 30 {
+		t.Errorf("PaidFor = %.2f, want 30.00", paid)
+	}
+}
+
+func TestRefundBookingStopsAtWhatWasPaid(t *testing.T) {
+	_, refunds := newRefundTest(t, 50)
+	refunded, err := refunds.RefundBooking(testActor, testBookingID, 80, "Cancelled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refunded != 50 {
+		t.Errorf("RefundBooking refunded %.2f, want the 50.00 paid", refunded)
+	}
+	if again, err := refunds.RefundBooking(testActor, testBookingID, 10, "Again"); err != nil || again != 0 {
+		t.Errorf("second RefundBooking = %.2f, %v, want nothing left to refund", again, err)
+	}
+}
+
+func TestRefundLimits(t *testing.T) {
+	transactions, refunds := newRefundTest(t, 100)
+	if _, err := refunds.Refund(testActor, &request.RefundRequest{TransactionID: 1, Amount: 100.01, Reason: "Too much"}); err == nil {
+		t.Error("a refund exceeded the payment")
+	}
+	if _, err := refunds.Refund(testActor, &request.RefundRequest{TransactionID: 1, Amount: 40, Reason: "Part"}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := refunds.Refund(testActor, &request.RefundRequest{TransactionID: 1, Reason: "The rest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Amount != 60 {
+		t.Errorf("refund of the rest = %.2f, want 60.00", res.Amount)
+	}
+	if _, err := refunds.Refund(testActor, &request.RefundRequest{TransactionID: 1, Reason: "Nothing left"}); err == nil {
+		t.Error("a payment refunded in full was refunded again")
+	}
+	refund, _ := transactions.GetByID(fmt.Sprint(res.ID))
+	if _, err := refunds.Refund(testActor, &request.RefundRequest{TransactionID: refund.Id, Reason: "Refund of a refund"}); err == nil {
+		t.Error("a refund was refunded")
+	}
+}